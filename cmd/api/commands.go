@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	rtdebug "runtime/debug"
+	"text/tabwriter"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/db"
+	"github.com/riyadhalnur/godi/v2/pkg/k8s"
+	"github.com/riyadhalnur/godi/v2/pkg/openapi"
+	"github.com/riyadhalnur/godi/v2/pkg/server"
+)
+
+// set at build time e.g.
+// go build -ldflags "-X main.version=v1.2.0 -X main.commit=abc1234 -X main.buildDate=2021-06-01T00:00:00Z"
+var (
+	version   = "dev"
+	commit    string
+	buildDate string
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{name: "serve", description: "start the server. Default when no command is given", run: runServe},
+	{name: "routes", description: "list the routes and their middlewares", run: runRoutes},
+	{name: "config", description: "print the effective configuration as JSON", run: runConfig},
+	{name: "healthcheck", description: "probe the health endpoint of a running instance", run: runHealthcheck},
+	{name: "openapi", description: "export the OpenAPI document of the routes", run: runOpenAPI},
+	{name: "manifests", description: "render the Kubernetes manifests of an environment", run: runManifests},
+	{name: "migrate", description: "apply or revert the database migrations", run: runMigrate},
+	{name: "replay", description: "replay recorded traffic against an instance and diff the responses", run: runReplay},
+	{name: "version", description: "print the version and build information", run: runVersion},
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprint(w, "usage: api <command> [flags]\n\ncommands:\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.description)
+	}
+	tw.Flush()
+	fmt.Fprint(w, "\nrun api <command> -h for the flags of a command\n")
+}
+
+// newFlagSet returns the flags of a command, which
+// prints the usage and exits on invalid flags
+func newFlagSet(name, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: api %s\n\nflags:\n", usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// runServe starts the server and blocks until it shuts down
+func runServe(args []string) error {
+	newFlagSet("serve", "serve").Parse(args)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	srv := newServer(cfg)
+	if configFile != "" {
+		srv.WatchConfig(loadConfig, configFile)
+	}
+
+	if database.DSN != "" {
+		pool, err := db.Open(database)
+		if err != nil {
+			return err
+		}
+		defer pool.Close()
+
+		// handlers get the pool injected as a *db.DB
+		if err := srv.Container().Supply(pool); err != nil {
+			return err
+		}
+		srv.AddHealthChecks(pool)
+	}
+
+	return srv.Listen()
+}
+
+// runRoutes prints a table of the routes of the server
+func runRoutes(args []string) error {
+	newFlagSet("routes", "routes").Parse(args)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tMETHOD\tPATH\tMIDDLEWARES")
+	for _, route := range newServer(cfg).Routes() {
+		middlewares := "-"
+		if len(route.Middlewares) > 0 {
+			middlewares = fmt.Sprint(route.Middlewares)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", route.Name, route.Method, route.Path, middlewares)
+	}
+	return tw.Flush()
+}
+
+// runConfig prints the configuration read from the environment
+// and the config file, with the defaults applied
+func runConfig(args []string) error {
+	newFlagSet("config", "config").Parse(args)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cfg.Effective())
+}
+
+// runHealthcheck exits with a non-zero status unless the health
+// endpoint responds with 200 e.g. for the HEALTHCHECK of Docker
+func runHealthcheck(args []string) error {
+	flags := newFlagSet("healthcheck", "healthcheck [-url url] [-timeout duration]")
+	url := flags.String("url", "", "URL of the health endpoint. Defaults to http://localhost:<port>/health")
+	timeout := flags.Duration("timeout", 5*time.Second, "maximum duration to wait for the response")
+	flags.Parse(args)
+
+	if *url == "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		*url = "http://localhost:" + cfg.Port + "/health"
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	res, err := client.Get(*url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unhealthy: %s %s", res.Status, body)
+	}
+	return nil
+}
+
+// runOpenAPI writes the OpenAPI document of the routes
+func runOpenAPI(args []string) error {
+	flags := newFlagSet("openapi", "openapi [-o file] [-title title]")
+	output := flags.String("o", "", "file to write the document to. Defaults to stdout")
+	title := flags.String("title", "api", "title of the API")
+	flags.Parse(args)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	doc := newServer(cfg).OpenAPI(openapi.Info{
+		Title:   *title,
+		Version: buildVersion(),
+	})
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if *output == "" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(*output, b, 0644)
+}
+
+// runManifests renders the manifests of the effective config, using the
+// options of base.yml and of the environment file of the directory
+func runManifests(args []string) error {
+	flags := newFlagSet("manifests", "manifests [-env name] [-environments directory] [-o directory]")
+	env := flags.String("env", "", "environment to render e.g. dev. Only base.yml applies when not set")
+	dir := flags.String("environments", environmentsDir, "directory of the environment files")
+	output := flags.String("o", "", "directory to write the manifests and their kustomization.yml to. Defaults to stdout")
+	flags.Parse(args)
+
+	opts := k8s.Options{}
+	files := []string{filepath.Join(*dir, "base.yml")}
+	if *env != "" {
+		opts.Environment = *env
+		files = append(files, filepath.Join(*dir, *env+".yml"))
+	}
+	for _, name := range files {
+		var err error
+		if opts, err = k8s.LoadEnvironment(name, opts); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if opts.ConfigFile != "" {
+		if cfg, err = server.LoadConfigFile(opts.ConfigFile, cfg); err != nil {
+			return err
+		}
+	}
+
+	manifests, err := k8s.Generate(cfg, newServer(cfg).Routes(), opts)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return k8s.Write(os.Stdout, manifests)
+	}
+	written, err := k8s.WriteDir(*output, manifests)
+	for _, name := range written {
+		fmt.Println("wrote " + name)
+	}
+	return err
+}
+
+// runVersion prints the build information
+func runVersion(args []string) error {
+	newFlagSet("version", "version").Parse(args)
+
+	fmt.Printf("api %s\n", buildVersion())
+	if commit != "" {
+		fmt.Printf("commit: %s\n", commit)
+	}
+	if buildDate != "" {
+		fmt.Printf("built: %s\n", buildDate)
+	}
+	fmt.Printf("go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return nil
+}
+
+// buildVersion returns the version set at build time or the
+// version of the module when installed using go install
+func buildVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := rtdebug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
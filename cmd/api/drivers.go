@@ -0,0 +1,11 @@
+//go:build cgo
+// +build cgo
+
+package main
+
+// database/sql drivers DATABASE_DRIVER can name. SQLite needs cgo,
+// so it is left out of static builds e.g. the Docker image. Import
+// the driver of your database here e.g. _ "github.com/lib/pq"
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
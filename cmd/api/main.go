@@ -1,17 +1,50 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/riyadhalnur/godi/v2/locales"
+	"github.com/riyadhalnur/godi/v2/pkg/db"
+	"github.com/riyadhalnur/godi/v2/pkg/middleware"
 	"github.com/riyadhalnur/godi/v2/pkg/server"
+	"github.com/riyadhalnur/godi/v2/static"
 )
 
 var (
-	port      = "3001"
-	timeout   = 30
-	staticDir = "./../../static"
+	port            = "3001"
+	timeout         = 30
+	staticDir       string
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	idleTimeout     time.Duration
+	shutdownTimeout time.Duration
+	handlerTimeout  time.Duration
+	configFile      string
+	compress        bool
+	maxBodyBytes    int64
+	templateDir     string
+	localeDir       string
+	debugNetworks   []string
+	idempotency     bool
+	cache           bool
+	cacheTTL        time.Duration
+	cacheStore      middleware.CacheStore
+	debug           bool
+	contract        bool
+	recordFile      string
+	recordHeaders   []string
+	recordFields    []string
+	database        db.Config
+	migrationsDir   = "migrations"
+	environmentsDir = "deploy/environments"
+
+	// variables that failed to parse, reported before running any command
+	envErrors []string
 )
 
 func init() {
@@ -20,24 +53,189 @@ func init() {
 	}
 
 	if os.Getenv("TIMEOUT") != "" {
-		timeoutStr := os.Getenv("TIMEOUT")
-		timeout, _ = strconv.Atoi(timeoutStr)
+		timeout = envInt("TIMEOUT")
 	}
 
 	if os.Getenv("STATIC_DIR") != "" {
 		staticDir = os.Getenv("STATIC_DIR")
 	}
+
+	configFile = os.Getenv("CONFIG_FILE")
+	templateDir = os.Getenv("TEMPLATE_DIR")
+	localeDir = os.Getenv("LOCALE_DIR")
+	debug = envBool("DEBUG")
+	if os.Getenv("DEBUG_NETWORKS") != "" {
+		debugNetworks = strings.Split(os.Getenv("DEBUG_NETWORKS"), ",")
+	}
+
+	readTimeout = envDuration("READ_TIMEOUT")
+	writeTimeout = envDuration("WRITE_TIMEOUT")
+	idleTimeout = envDuration("IDLE_TIMEOUT")
+	shutdownTimeout = envDuration("SHUTDOWN_TIMEOUT")
+	handlerTimeout = envDuration("HANDLER_TIMEOUT")
+	compress = envBool("COMPRESS")
+	maxBodyBytes = envInt64("MAX_BODY_BYTES")
+	idempotency = envBool("IDEMPOTENCY")
+	cache = envBool("CACHE")
+	cacheTTL = envDuration("CACHE_TTL")
+	contract = envBool("CONTRACT_VALIDATION")
+	recordFile = os.Getenv("RECORD_FILE")
+	recordHeaders = splitList(os.Getenv("RECORD_REDACT_HEADERS"))
+	recordFields = splitList(os.Getenv("RECORD_REDACT_FIELDS"))
+
+	// import the driver of the database e.g. _ "github.com/lib/pq"
+	database.Driver = os.Getenv("DATABASE_DRIVER")
+	database.DSN = os.Getenv("DATABASE_URL")
+	database.MaxOpenConns = envInt("DATABASE_MAX_OPEN_CONNS")
+	database.MaxIdleConns = envInt("DATABASE_MAX_IDLE_CONNS")
+	database.ConnMaxLifetime = envDuration("DATABASE_CONN_MAX_LIFETIME")
+	database.SlowQueryThreshold = envDuration("DATABASE_SLOW_QUERY")
+	database.LogQueries = debug
+	if os.Getenv("MIGRATIONS_DIR") != "" {
+		migrationsDir = os.Getenv("MIGRATIONS_DIR")
+	}
+
+	// created once so reloading the config keeps the same store
+	if os.Getenv("REDIS_ADDR") != "" {
+		cacheStore = middleware.NewRedisCacheStore(middleware.RedisOptions{
+			Addr:     os.Getenv("REDIS_ADDR"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+		})
+	}
 }
 
 func main() {
-	cfg := &server.Config{
-		Port:      port,
-		Timeout:   timeout,
-		StaticDir: staticDir,
+	name, args := "serve", []string{}
+	if len(os.Args) > 1 {
+		name, args = os.Args[1], os.Args[2:]
 	}
 
-	srv := server.NewServer(cfg)
-	if err := srv.Listen(); err != nil {
+	switch name {
+	case "help", "-h", "-help", "--help":
+		printUsage(os.Stdout)
+		return
+	}
+
+	if len(envErrors) > 0 {
+		log.Fatalln("invalid environment variables: " + strings.Join(envErrors, "; "))
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+
+	if err := cmd.run(args); err != nil {
 		log.Fatalln(err)
 	}
 }
+
+// newServer creates the server of every command, so routes
+// added here are served, listed and documented alike
+func newServer(cfg *server.Config) *server.Server {
+	srv := server.NewServer(cfg)
+	// e.g. srv.AddRoutes(users.Routes()...)
+	return srv
+}
+
+// loadConfig reads the configuration from the environment
+// and applies the config file on top, if any
+func loadConfig() (*server.Config, error) {
+	cfg := &server.Config{
+		Port:                port,
+		Timeout:             timeout,
+		ReadTimeout:         readTimeout,
+		WriteTimeout:        writeTimeout,
+		IdleTimeout:         idleTimeout,
+		ShutdownTimeout:     shutdownTimeout,
+		HandlerTimeout:      handlerTimeout,
+		StaticDir:           staticDir,
+		Compress:            compress,
+		MaxBodyBytes:        maxBodyBytes,
+		TemplateDir:         templateDir,
+		TemplateReload:      debug,
+		LocaleDir:           localeDir,
+		DebugErrors:         debug,
+		DebugNetworks:       debugNetworks,
+		Idempotency:         idempotency,
+		Cache:               cache,
+		CacheTTL:            cacheTTL,
+		CacheStore:          cacheStore,
+		ContractValidation:  contract,
+		RecordFile:          recordFile,
+		RecordRedactHeaders: recordHeaders,
+		RecordRedactFields:  recordFields,
+	}
+
+	if configFile != "" {
+		var err error
+		cfg, err = server.LoadConfigFile(configFile, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// serve the files embedded in the binary unless a directory is set
+	if cfg.StaticDir == "" {
+		cfg.StaticFS = static.Files
+	}
+	if cfg.LocaleDir == "" {
+		cfg.Locales = locales.Files
+	}
+
+	return cfg, nil
+}
+
+// envInt returns the integer value of the environment variable, or 0 when not set
+func envInt(name string) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		envErrors = append(envErrors, fmt.Sprintf("%s=%q is not an integer", name, value))
+	}
+	return i
+}
+
+// envInt64 returns the 64-bit integer value of the environment variable, or 0 when not set
+func envInt64(name string) int64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		envErrors = append(envErrors, fmt.Sprintf("%s=%q is not an integer", name, value))
+	}
+	return i
+}
+
+// envBool returns the boolean value of the environment variable, or false when not set
+func envBool(name string) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		envErrors = append(envErrors, fmt.Sprintf("%s=%q is not a boolean e.g. true", name, value))
+	}
+	return b
+}
+
+// envDuration returns the duration value of the environment variable, or 0 when not set
+func envDuration(name string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		envErrors = append(envErrors, fmt.Sprintf("%s=%q is not a duration e.g. 10s", name, value))
+	}
+	return d
+}
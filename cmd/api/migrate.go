@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/riyadhalnur/godi/v2/pkg/db"
+	"github.com/riyadhalnur/godi/v2/pkg/db/migrate"
+)
+
+const migrateUsage = `usage: api migrate [-dir directory] [-dry-run] <command>
+
+commands:
+  up [version]   apply the pending migrations, up to the version if set
+  down [steps]   revert the last applied migrations. Defaults to 1
+  status         list the migrations and whether they are applied
+
+flags:
+`
+
+// runMigrate applies the migrations of the directory
+// to the database configured using DATABASE_DRIVER and DATABASE_URL
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+	dir := flags.String("dir", migrationsDir, "directory of the migrations")
+	dryRun := flags.Bool("dry-run", false, "print the SQL of the migrations instead of running it")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if database.DSN == "" {
+		return errors.New("DATABASE_URL is not set")
+	}
+
+	pool, err := db.Open(database)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	migrations, err := migrate.Load(os.DirFS(*dir))
+	if err != nil {
+		return err
+	}
+
+	opts := migrate.Options{Driver: database.Driver}
+	if *dryRun {
+		opts.DryRun = os.Stdout
+	}
+	m, err := migrate.New(pool.DB, migrations, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch flags.Arg(0) {
+	case "up":
+		var version int64
+		if flags.Arg(1) != "" {
+			if version, err = strconv.ParseInt(flags.Arg(1), 10, 64); err != nil {
+				return fmt.Errorf("invalid version %q", flags.Arg(1))
+			}
+		}
+
+		applied, err := m.Up(ctx, version)
+		printMigrations("applied", applied, *dryRun)
+		return err
+	case "down":
+		steps := 1
+		if flags.Arg(1) != "" {
+			if steps, err = strconv.Atoi(flags.Arg(1)); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", flags.Arg(1))
+			}
+		}
+
+		reverted, err := m.Down(ctx, steps)
+		printMigrations("reverted", reverted, *dryRun)
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		return nil
+	}
+
+	flags.Usage()
+	os.Exit(2)
+	return nil
+}
+
+// printMigrations lists the migrations run, unless
+// their SQL was printed instead
+func printMigrations(action string, migrations []migrate.Migration, dryRun bool) {
+	if dryRun {
+		return
+	}
+	if len(migrations) == 0 {
+		fmt.Println("no migrations " + action)
+	}
+	for _, m := range migrations {
+		fmt.Printf("%s %d_%s\n", action, m.Version, m.Name)
+	}
+}
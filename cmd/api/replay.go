@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/riyadhalnur/godi/v2/pkg/traffic"
+)
+
+const replayUsage = `usage: api replay [-target url] [-header 'Name: value'] [-ignore-headers list] [-ignore-fields list] [-routes list] <file>
+
+re-sends the requests recorded to the file, in order, and prints
+the differences between the responses and the recorded ones
+
+flags:
+`
+
+// headerFlags collects the -header flags
+type headerFlags http.Header
+
+func (h headerFlags) String() string {
+	return fmt.Sprint(http.Header(h))
+}
+
+func (h headerFlags) Set(value string) error {
+	idx := strings.Index(value, ":")
+	if idx < 1 {
+		return fmt.Errorf("invalid header %q, expected 'Name: value'", value)
+	}
+	http.Header(h).Add(strings.TrimSpace(value[:idx]), strings.TrimSpace(value[idx+1:]))
+	return nil
+}
+
+// runReplay replays recorded traffic against a running instance,
+// failing when any response differs from the recorded one
+func runReplay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), replayUsage)
+		flags.PrintDefaults()
+	}
+	target := flags.String("target", "", "base URL of the server to replay against. Defaults to http://localhost:<port>")
+	header := headerFlags{}
+	flags.Var(header, "header", "header set on every request e.g. to replace redacted credentials. Can be repeated")
+	ignoreHeaders := flags.String("ignore-headers", "", "comma-separated response headers not compared")
+	ignoreFields := flags.String("ignore-fields", "", "comma-separated JSON fields not compared e.g. id,createdAt")
+	routes := flags.String("routes", "", "comma-separated names of the routes to replay. Defaults to all")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *target == "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		*target = "http://localhost:" + cfg.Port
+	}
+
+	replayer, err := traffic.NewReplayer(traffic.ReplayOptions{
+		Target:        *target,
+		Header:        http.Header(header),
+		IgnoreHeaders: splitList(*ignoreHeaders),
+		IgnoreFields:  splitList(*ignoreFields),
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	only := map[string]bool{}
+	for _, name := range splitList(*routes) {
+		only[name] = true
+	}
+
+	var replayed, differed, skipped int
+	reader := traffic.NewReader(f)
+	ctx := context.Background()
+	for {
+		exchange, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(only) > 0 && !only[exchange.Route] {
+			continue
+		}
+
+		request := exchange.Request.Method + " " + exchange.Request.URL
+		if exchange.Route != "" {
+			request += " (" + exchange.Route + ")"
+		}
+
+		result, err := replayer.Replay(ctx, exchange)
+		if errors.Is(err, traffic.ErrBodyOmitted) {
+			skipped++
+			fmt.Printf("SKIP %s: %v\n", request, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		replayed++
+		if len(result.Diffs) == 0 {
+			continue
+		}
+		differed++
+		fmt.Printf("DIFF %s\n", request)
+		for _, diff := range result.Diffs {
+			fmt.Printf("  %s\n", diff)
+		}
+	}
+
+	fmt.Printf("replayed %d exchanges: %d matched, %d differed, %d skipped\n", replayed, replayed-differed, differed, skipped)
+	if differed > 0 {
+		return fmt.Errorf("%d responses differ from the recorded ones", differed)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
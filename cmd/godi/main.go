@@ -0,0 +1,132 @@
+// Command godi creates new services built on godi and
+// generates the handlers of existing ones
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+
+	"github.com/riyadhalnur/godi/v2/pkg/scaffold"
+)
+
+const usage = `usage: godi <command>
+
+commands:
+  new <module> [flags]         create a service e.g. godi new github.com/acme/orders
+  gen handler <name> [flags]   add a handler, its test and its route e.g. godi gen handler get-user
+
+run godi <command> -h for the flags of a command
+`
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var (
+		files []string
+		err   error
+	)
+	switch os.Args[1] {
+	case "new":
+		files, err = runNew(os.Args[2:])
+	case "gen":
+		files, err = runGen(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	for _, name := range files {
+		fmt.Println("wrote " + name)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// runNew creates a service
+func runNew(args []string) ([]string, error) {
+	flags := flag.NewFlagSet("new", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), "usage: godi new <module> [flags]\n\nflags:\n")
+		flags.PrintDefaults()
+	}
+	project := scaffold.Project{GodiVersion: godiVersion()}
+	flags.StringVar(&project.Dir, "dir", "", "directory to create the service in. Defaults to the name of the service")
+	flags.StringVar(&project.Name, "name", "", "name of the binary, image and Kubernetes resources. Defaults to the last element of the module path")
+	flags.StringVar(&project.Image, "image", "", "Docker image of the service. Defaults to <name>:latest")
+	flags.StringVar(&project.Port, "port", scaffold.DefaultPort, "port the service listens on")
+	// flags may follow the module path
+	module := ""
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		module, args = args[0], args[1:]
+	}
+	flags.Parse(args)
+	if module == "" {
+		module = flags.Arg(0)
+	}
+	if module == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	project.Module = module
+
+	files, err := scaffold.New(project)
+	if err == nil {
+		fmt.Fprintln(os.Stderr, "run go mod tidy in the directory of the service to download its dependencies")
+	}
+	return files, err
+}
+
+// runGen generates code in an existing service
+func runGen(args []string) ([]string, error) {
+	if len(args) == 0 || args[0] != "handler" {
+		fmt.Fprint(os.Stderr, "usage: godi gen handler <name> [flags]\n")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("gen handler", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), "usage: godi gen handler <name> [flags]\n\nflags:\n")
+		flags.PrintDefaults()
+	}
+	handler := scaffold.Handler{}
+	flags.StringVar(&handler.Dir, "dir", scaffold.DefaultHandlerDir, "package directory of the handler")
+	flags.StringVar(&handler.Method, "method", "GET", "HTTP method of the route")
+	flags.StringVar(&handler.Path, "path", "", "path of the route. Defaults to the name in kebab case")
+	flags.StringVar(&handler.RoutesFile, "routes", "routes.go", "file of the package holding the "+scaffold.RoutesMarker+" marker")
+	args = args[1:]
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		handler.Name, args = args[0], args[1:]
+	}
+	flags.Parse(args)
+	if handler.Name == "" {
+		handler.Name = flags.Arg(0)
+	}
+	if handler.Name == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	return scaffold.GenerateHandler(handler)
+}
+
+// godiVersion returns the version of godi the command was
+// installed from, required by the services it creates
+func godiVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" || info.Main.Version == "(devel)" {
+		return ""
+	}
+	return info.Main.Version
+}
@@ -1,10 +1,18 @@
 module github.com/riyadhalnur/godi/v2
 
-go 1.14
+go 1.16
 
 require (
+	github.com/andybalholm/brotli v1.0.1
+	github.com/fxamacker/cbor/v2 v2.2.0
 	github.com/gofrs/uuid v3.3.0+incompatible
 	github.com/gorilla/mux v1.7.4
+	github.com/gorilla/websocket v1.4.2
+	github.com/klauspost/compress v1.11.2
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/stretchr/testify v1.6.1
+	github.com/vmihailenco/msgpack/v5 v5.0.0
 	go.uber.org/zap v1.15.0
+	google.golang.org/protobuf v1.25.0
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
@@ -0,0 +1,10 @@
+// Package locales contains the message catalogs
+// of the boilerplate, embedded in the binary
+package locales
+
+import "embed"
+
+// Files holds the English and Bengali catalogs
+//
+//go:embed *.json
+var Files embed.FS
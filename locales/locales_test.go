@@ -0,0 +1,24 @@
+package locales
+
+import (
+	"testing"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/i18n"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogs(t *testing.T) {
+	catalogs, err := i18n.Load(Files, i18n.DefaultLanguage)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bn", "en"}, catalogs.Languages())
+
+	// every built-in error type can be translated in every language
+	for _, lang := range catalogs.Languages() {
+		for _, def := range godierr.Definitions() {
+			_, ok := catalogs.Translate(lang, def.Type, nil)
+			assert.True(t, ok, "%s is missing %s", lang, def.Type)
+		}
+	}
+}
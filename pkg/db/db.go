@@ -0,0 +1,239 @@
+// Package db wraps database/sql with pool tuning, query logging
+// and metrics, health checks and per request transactions.
+// Import the driver of the database in the application e.g.
+// _ "github.com/lib/pq"
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+const (
+	defaultHealthTimeout time.Duration = 2 * time.Second
+)
+
+// driverImports are the packages registering the common drivers,
+// named when the driver of the config is not imported
+var driverImports = map[string]string{
+	"mysql":    "github.com/go-sql-driver/mysql",
+	"pgx":      "github.com/jackc/pgx/v4/stdlib",
+	"postgres": "github.com/lib/pq",
+	"sqlite3":  "github.com/mattn/go-sqlite3",
+}
+
+// Config specifies the parameters
+// of a pool of database connections
+//
+// Driver (required) - name of the database/sql driver e.g. postgres, sqlite3
+// DSN (required) - data source name passed to the driver
+// MaxOpenConns - maximum number of open connections. Not limited when not set
+// MaxIdleConns - maximum number of idle connections kept. Uses the database/sql default when not set
+// ConnMaxLifetime - maximum duration a connection is reused for. Not limited when not set
+// ConnMaxIdleTime - maximum duration a connection stays idle. Not limited when not set
+// LogQueries - log every query at debug level, with the ID of the request
+// SlowQueryThreshold - queries taking longer are logged as warnings
+// and counted as slow. Disabled when not set
+// HealthTimeout - maximum duration of the ping of health checks. Defaults to 2s
+type Config struct {
+	Driver             string
+	DSN                string
+	MaxOpenConns       int
+	MaxIdleConns       int
+	ConnMaxLifetime    time.Duration
+	ConnMaxIdleTime    time.Duration
+	LogQueries         bool
+	SlowQueryThreshold time.Duration
+	HealthTimeout      time.Duration
+}
+
+// QueryStats are the metrics of the queries run through a DB
+type QueryStats struct {
+	Queries     int64
+	SlowQueries int64
+	Errors      int64
+	QueryTime   time.Duration
+}
+
+// DB is a pool of database connections logging and measuring the
+// queries run through ExecContext, QueryContext and QueryRowContext.
+// The methods of sql.DB are available as they are
+type DB struct {
+	// accessed atomically, kept first for 64-bit alignment
+	queries     int64
+	slowQueries int64
+	errors      int64
+	queryTime   int64
+
+	*sql.DB
+	config Config
+}
+
+// Open opens a pool of connections using the config.
+// Connections are opened when needed, use CheckHealth
+// to verify the database can be reached. Fails naming
+// the package to import when the driver is not registered
+func Open(cfg Config) (*DB, error) {
+	if cfg.Driver == "" {
+		return nil, godierr.RequiredArgsError("database driver")
+	}
+
+	if cfg.DSN == "" {
+		return nil, godierr.RequiredArgsError("database dsn")
+	}
+
+	if !registered(cfg.Driver) {
+		pkg, ok := driverImports[cfg.Driver]
+		if !ok {
+			pkg = "<driver package>"
+		}
+		return nil, fmt.Errorf("db: the %s driver is not registered, import it in main.go e.g. _ %q", cfg.Driver, pkg)
+	}
+
+	pool, err := sql.Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(pool, cfg), nil
+}
+
+// registered reports whether a driver is registered under the name
+func registered(name string) bool {
+	for _, driver := range sql.Drivers() {
+		if driver == name {
+			return true
+		}
+	}
+	return false
+}
+
+// New wraps a pool opened beforehand, tuning it using the config
+func New(pool *sql.DB, cfg Config) *DB {
+	if cfg.MaxOpenConns != 0 {
+		pool.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns != 0 {
+		pool.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime != 0 {
+		pool.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime != 0 {
+		pool.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+
+	return &DB{
+		DB:     pool,
+		config: cfg,
+	}
+}
+
+// ExecContext runs a query without returning any rows
+func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := d.DB.ExecContext(ctx, query, args...)
+	d.observe(ctx, query, start, err)
+	return res, err
+}
+
+// QueryContext runs a query returning rows
+func (d *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := d.DB.QueryContext(ctx, query, args...)
+	d.observe(ctx, query, start, err)
+	return rows, err
+}
+
+// QueryRowContext runs a query returning at most one row
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := d.DB.QueryRowContext(ctx, query, args...)
+	d.observe(ctx, query, start, row.Err())
+	return row
+}
+
+// BeginTx starts a transaction. Its queries are logged and
+// measured along with the ones of the pool
+func (d *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{
+		Tx: tx,
+		db: d,
+	}, nil
+}
+
+// CheckHealth pings the database. Fails with an UNAVAILABLE
+// error when the database cannot be reached
+func (d *DB) CheckHealth(ctx context.Context) error {
+	timeout := d.config.HealthTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := d.PingContext(ctx); err != nil {
+		return godierr.UnavailableError(err)
+	}
+	return nil
+}
+
+// QueryStats returns the metrics of the queries run so far
+func (d *DB) QueryStats() QueryStats {
+	return QueryStats{
+		Queries:     atomic.LoadInt64(&d.queries),
+		SlowQueries: atomic.LoadInt64(&d.slowQueries),
+		Errors:      atomic.LoadInt64(&d.errors),
+		QueryTime:   time.Duration(atomic.LoadInt64(&d.queryTime)),
+	}
+}
+
+// observe logs the query and updates the metrics
+func (d *DB) observe(ctx context.Context, query string, start time.Time, err error) {
+	latency := time.Since(start)
+	atomic.AddInt64(&d.queries, 1)
+	atomic.AddInt64(&d.queryTime, int64(latency))
+
+	args := []interface{}{
+		"query",
+		query,
+		"requestId",
+		requestID(ctx),
+		"latency",
+		latency.String(),
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		atomic.AddInt64(&d.errors, 1)
+		args = append(args, "error", err.Error())
+	}
+
+	if d.config.SlowQueryThreshold > 0 && latency >= d.config.SlowQueryThreshold {
+		atomic.AddInt64(&d.slowQueries, 1)
+		logger.Warn("Slow database query", args...)
+		return
+	}
+
+	if d.config.LogQueries {
+		logger.Debug("Database query", args...)
+	}
+}
+
+// requestID returns the ID of the request the context belongs to, if any
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(util.RequestIDKey).(string)
+	return id
+}
@@ -0,0 +1,148 @@
+//go:build cgo
+// +build cgo
+
+package db
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestDB(t *testing.T, cfg Config) *DB {
+	cfg.Driver = "sqlite3"
+	cfg.DSN = filepath.Join(t.TempDir(), "test.db")
+
+	database, err := Open(cfg)
+	assert.Nil(t, err)
+	t.Cleanup(func() { database.Close() })
+
+	_, err = database.ExecContext(context.Background(), "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	assert.Nil(t, err)
+	return database
+}
+
+func countUsers(t *testing.T, database *DB) int {
+	var count int
+	err := database.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM users").Scan(&count)
+	assert.Nil(t, err)
+	return count
+}
+
+func TestOpen(t *testing.T) {
+	_, err := Open(Config{DSN: "test.db"})
+	assert.EqualError(t, err, "missing required argument(s): database driver")
+
+	_, err = Open(Config{Driver: "sqlite3"})
+	assert.EqualError(t, err, "missing required argument(s): database dsn")
+
+	_, err = Open(Config{Driver: "postgres", DSN: "postgres://localhost/test"})
+	assert.EqualError(t, err, `db: the postgres driver is not registered, import it in main.go e.g. _ "github.com/lib/pq"`)
+
+	_, err = Open(Config{Driver: "oracle", DSN: "oracle://localhost/test"})
+	assert.EqualError(t, err, `db: the oracle driver is not registered, import it in main.go e.g. _ "<driver package>"`)
+
+	database := openTestDB(t, Config{MaxOpenConns: 3})
+	assert.Equal(t, 3, database.Stats().MaxOpenConnections)
+	assert.Nil(t, database.CheckHealth(context.Background()))
+
+	database.Close()
+	err = database.CheckHealth(context.Background())
+	var godiErr *godierr.Error
+	assert.True(t, errors.As(err, &godiErr))
+	assert.Equal(t, http.StatusServiceUnavailable, godiErr.Code())
+}
+
+func TestQueryStats(t *testing.T) {
+	ctx := context.WithValue(context.Background(), util.RequestIDKey, "request-1")
+	database := openTestDB(t, Config{LogQueries: true, SlowQueryThreshold: time.Nanosecond})
+
+	_, err := database.ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", "ada")
+	assert.Nil(t, err)
+
+	rows, err := database.QueryContext(ctx, "SELECT name FROM users")
+	assert.Nil(t, err)
+	rows.Close()
+
+	_, err = database.ExecContext(ctx, "INSERT INTO missing (name) VALUES (?)", "ada")
+	assert.NotNil(t, err)
+
+	stats := database.QueryStats()
+	assert.Equal(t, int64(4), stats.Queries)
+	assert.Equal(t, int64(4), stats.SlowQueries)
+	assert.Equal(t, int64(1), stats.Errors)
+	assert.True(t, stats.QueryTime > 0)
+}
+
+func TestTransactional(t *testing.T) {
+	newHandler := func(database *DB, fail error) util.APIHandlerFunc {
+		return database.Transactional(func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			tx, ok := TxFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, tx, database.Querier(ctx))
+
+			if _, err := database.Querier(ctx).ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", "ada"); err != nil {
+				return nil, err
+			}
+			if fail != nil {
+				return nil, fail
+			}
+			return &util.Response{StatusCode: http.StatusCreated}, nil
+		})
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		database := openTestDB(t, Config{})
+
+		res, err := newHandler(database, nil)(context.Background(), &util.Request{})
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, 1, countUsers(t, database))
+		assert.Equal(t, database, database.Querier(context.Background()))
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		database := openTestDB(t, Config{})
+
+		conflict := godierr.ConflictError("user")
+		_, err := newHandler(database, conflict)(context.Background(), &util.Request{})
+		assert.Equal(t, conflict, err)
+		assert.Equal(t, 0, countUsers(t, database))
+	})
+
+	t.Run("rollback on panic", func(t *testing.T) {
+		database := openTestDB(t, Config{})
+		handler := database.Transactional(func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			database.Querier(ctx).ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", "ada")
+			panic("boom")
+		})
+
+		assert.Panics(t, func() { handler(context.Background(), &util.Request{}) })
+		assert.Equal(t, 0, countUsers(t, database))
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		database := openTestDB(t, Config{})
+		invalid := godierr.InvalidArgsError("name")
+		outer := database.Transactional(func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			if _, err := newHandler(database, nil)(ctx, req); err != nil {
+				return nil, err
+			}
+			return nil, invalid
+		})
+
+		_, err := outer(context.Background(), &util.Request{})
+		assert.Equal(t, invalid, err)
+		assert.Equal(t, 0, countUsers(t, database))
+	})
+}
@@ -0,0 +1,189 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	lockPollInterval time.Duration = 100 * time.Millisecond
+)
+
+// dialect holds the SQL that differs between databases
+type dialect interface {
+	placeholder(n int) string
+	tableExists(ctx context.Context, db *sql.DB, table string) (bool, error)
+	// lock blocks until the lock for the table is acquired
+	// or the context is done, returning the function releasing it
+	lock(ctx context.Context, db *sql.DB, table string) (func() error, error)
+}
+
+// dialects are the dialects of the supported drivers
+var dialects = map[string]dialect{
+	"cloudsqlpostgres": postgres{},
+	"mysql":            mysql{},
+	"pgx":              postgres{},
+	"postgres":         postgres{},
+	"sqlite":           sqlite{},
+	"sqlite3":          sqlite{},
+}
+
+// dialectOf returns the dialect of the driver
+func dialectOf(driver string) (dialect, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		drivers := make([]string, 0, len(dialects))
+		for name := range dialects {
+			drivers = append(drivers, name)
+		}
+		sort.Strings(drivers)
+		return nil, fmt.Errorf("migrate: unsupported driver %q, use one of %s", driver, strings.Join(drivers, ", "))
+	}
+	return d, nil
+}
+
+// postgres serializes runs using a session level advisory lock
+type postgres struct{}
+
+func (postgres) placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgres) tableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1)",
+		table,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (postgres) lock(ctx context.Context, db *sql.DB, table string) (func() error, error) {
+	// advisory locks belong to the connection that took them
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := lockKey(table)
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+		return err
+	}, nil
+}
+
+// mysql serializes runs using a named lock
+type mysql struct{}
+
+func (mysql) placeholder(n int) string {
+	return "?"
+}
+
+func (mysql) tableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+		table,
+	).Scan(&count)
+	return count > 0, err
+}
+
+func (mysql) lock(ctx context.Context, db *sql.DB, table string) (func() error, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := 0
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = int(time.Until(deadline).Seconds())
+	}
+
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", table, timeout).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if acquired.Int64 != 1 {
+		conn.Close()
+		return nil, fmt.Errorf("timed out waiting for the lock of %s", table)
+	}
+
+	return func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", table)
+		return err
+	}, nil
+}
+
+// sqlite serializes runs using a row in a lock table,
+// as SQLite has no advisory locks. The row of a run
+// that crashed has to be deleted by hand
+type sqlite struct{}
+
+func (sqlite) placeholder(n int) string {
+	return "?"
+}
+
+func (sqlite) tableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+		table,
+	).Scan(&count)
+	return count > 0, err
+}
+
+func (sqlite) lock(ctx context.Context, db *sql.DB, table string) (func() error, error) {
+	lockTable := table + "_lock"
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY, owner VARCHAR(64) NOT NULL)",
+		lockTable,
+	)); err != nil {
+		return nil, err
+	}
+
+	owner, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		_, err := db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (id, owner) VALUES (1, ?)", lockTable), owner.String())
+		if err == nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for the lock of %s, delete its row if no migration is running: %w", lockTable, err)
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	return func() error {
+		_, err := db.ExecContext(context.Background(), fmt.Sprintf("DELETE FROM %s WHERE id = 1 AND owner = ?", lockTable), owner.String())
+		return err
+	}, nil
+}
+
+// lockKey derives the key of the advisory lock from the table name
+func lockKey(table string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(table))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,326 @@
+// Package migrate applies versioned SQL migrations. Migrations are
+// pairs of files named <version>_<name>.up.sql and <version>_<name>.down.sql
+// e.g. 0001_create_users.up.sql, loaded from any fs.FS such as an
+// embed.FS or os.DirFS. Applied versions are recorded in a table and
+// runs are serialized using a lock, so every pod can migrate on start
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+)
+
+const (
+	// DefaultTable records the applied versions when not configured
+	DefaultTable string = "schema_migrations"
+	// DefaultLockTimeout is how long to wait for another
+	// run to finish when not configured
+	DefaultLockTimeout time.Duration = time.Minute
+)
+
+var (
+	fileName   = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Migration is a version of the schema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and whether it is applied
+type Status struct {
+	Migration
+	Applied bool
+}
+
+// Options configures a Migrator
+//
+// Driver (required) - name of the database/sql driver, picks the SQL
+// dialect and the lock. One of postgres, pgx, cloudsqlpostgres, mysql,
+// sqlite3 or sqlite
+// Table - records the applied versions. Defaults to schema_migrations
+// LockTimeout - how long to wait for another run to finish. Defaults to 1m
+// DryRun - writes the SQL of the migrations instead of running it
+type Options struct {
+	Driver      string
+	Table       string
+	LockTimeout time.Duration
+	DryRun      io.Writer
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	opts       Options
+	dialect    dialect
+}
+
+// Load reads the migrations in the root of fsys, sorted by version.
+// Down migrations are optional
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migrate: %s is not named <version>_<name>.up.sql or <version>_<name>.down.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: invalid version of %s: %w", entry.Name(), err)
+		}
+
+		b, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d is used by %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migrate: version %d (%s) has no up migration", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// New returns a new instance of Migrator
+func New(db *sql.DB, migrations []Migration, opts Options) (*Migrator, error) {
+	if opts.Table == "" {
+		opts.Table = DefaultTable
+	}
+	if !identifier.MatchString(opts.Table) {
+		return nil, fmt.Errorf("migrate: invalid table name %q", opts.Table)
+	}
+	if opts.LockTimeout <= 0 {
+		opts.LockTimeout = DefaultLockTimeout
+	}
+	dialect, err := dialectOf(opts.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		opts:       opts,
+		dialect:    dialect,
+	}, nil
+}
+
+// Up applies the pending migrations up to the version, or all
+// of them when the version is 0. Returns the applied migrations
+func (m *Migrator) Up(ctx context.Context, version int64) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(versions map[int64]bool) error {
+		for _, migration := range m.migrations {
+			if versions[migration.Version] {
+				continue
+			}
+			if version != 0 && migration.Version > version {
+				break
+			}
+
+			insert := fmt.Sprintf("INSERT INTO %s (version, name) VALUES (%s, %s)",
+				m.opts.Table, m.dialect.placeholder(1), m.dialect.placeholder(2))
+			if err := m.run(ctx, migration, "up", migration.Up, insert, migration.Version, migration.Name); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, in reverse
+// order. Returns the reverted migrations
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(versions map[int64]bool) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if !versions[migration.Version] {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migrate: version %d (%s) has no down migration", migration.Version, migration.Name)
+			}
+
+			remove := fmt.Sprintf("DELETE FROM %s WHERE version = %s", m.opts.Table, m.dialect.placeholder(1))
+			if err := m.run(ctx, migration, "down", migration.Down, remove, migration.Version); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status returns every migration and whether it is applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	versions, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = Status{
+			Migration: migration,
+			Applied:   versions[migration.Version],
+		}
+	}
+	return statuses, nil
+}
+
+// locked runs fn with the applied versions while holding the lock.
+// Dry runs neither lock nor create the table
+func (m *Migrator) locked(ctx context.Context, fn func(versions map[int64]bool) error) error {
+	if m.opts.DryRun != nil {
+		versions, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+		return fn(versions)
+	}
+
+	lockCtx, cancel := context.WithTimeout(ctx, m.opts.LockTimeout)
+	defer cancel()
+
+	unlock, err := m.dialect.lock(lockCtx, m.db, m.opts.Table)
+	if err != nil {
+		return fmt.Errorf("migrate: acquiring lock: %w", err)
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			logger.Error("Unable to release migration lock", "error", err.Error())
+		}
+	}()
+
+	// created once locked, so concurrent runs do not race to create it
+	if _, err := m.db.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+		m.opts.Table,
+	)); err != nil {
+		return fmt.Errorf("migrate: creating table %s: %w", m.opts.Table, err)
+	}
+
+	// read once locked, another run may have applied migrations meanwhile
+	versions, err := m.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+	return fn(versions)
+}
+
+// run applies a migration and records it in a transaction
+func (m *Migrator) run(ctx context.Context, migration Migration, direction, query, record string, args ...interface{}) error {
+	if m.opts.DryRun != nil {
+		_, err := fmt.Fprintf(m.opts.DryRun, "-- %d_%s.%s.sql\n%s\n", migration.Version, migration.Name, direction, strings.TrimSpace(query))
+		return err
+	}
+
+	start := time.Now()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migrate: %d_%s.%s.sql: %w", migration.Version, migration.Name, direction, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migrate: recording version %d: %w", migration.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logger.Info("Migrated database",
+		"version",
+		migration.Version,
+		"name",
+		migration.Name,
+		"direction",
+		direction,
+		"latency",
+		time.Since(start).String(),
+	)
+	return nil
+}
+
+// appliedVersions returns the versions recorded in the table.
+// None are applied when the table does not exist yet
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]bool, error) {
+	versions := make(map[int64]bool)
+
+	exists, err := m.dialect.tableExists(ctx, m.db, m.opts.Table)
+	if err != nil || !exists {
+		return versions, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, fmt.Sprintf("SELECT version FROM %s", m.opts.Table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions[version] = true
+	}
+	return versions, rows.Err()
+}
@@ -0,0 +1,216 @@
+//go:build cgo
+// +build cgo
+
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFiles = fstest.MapFS{
+	"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);")},
+	"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	"0002_index_names.up.sql":    {Data: []byte("CREATE INDEX users_name ON users (name);\nCREATE TABLE sessions (id TEXT PRIMARY KEY);")},
+	"0002_index_names.down.sql":  {Data: []byte("DROP TABLE sessions;\nDROP INDEX users_name;")},
+	"0003_create_orders.up.sql":  {Data: []byte("CREATE TABLE orders (id INTEGER PRIMARY KEY);")},
+	"README.md":                  {Data: []byte("not a migration")},
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=5000")
+	assert.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newMigrator(t *testing.T, db *sql.DB, opts Options) *Migrator {
+	migrations, err := Load(testFiles)
+	assert.Nil(t, err)
+
+	m, err := New(db, migrations, opts)
+	assert.Nil(t, err)
+	return m
+}
+
+func versions(migrations []Migration) []int64 {
+	v := []int64{}
+	for _, m := range migrations {
+		v = append(v, m.Version)
+	}
+	return v
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testFiles)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, versions(migrations))
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.Equal(t, "DROP TABLE users;", migrations[0].Down)
+	assert.Empty(t, migrations[2].Down)
+
+	cases := []struct {
+		name  string
+		files fstest.MapFS
+		err   string
+	}{
+		{
+			name:  "invalid name",
+			files: fstest.MapFS{"create_users.sql": {}},
+			err:   "migrate: create_users.sql is not named <version>_<name>.up.sql or <version>_<name>.down.sql",
+		},
+		{
+			name:  "missing up",
+			files: fstest.MapFS{"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")}},
+			err:   "migrate: version 1 (create_users) has no up migration",
+		},
+		{
+			name: "duplicate version",
+			files: fstest.MapFS{
+				"0001_create_users.up.sql":  {Data: []byte("SELECT 1;")},
+				"0001_create_orders.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			err: "migrate: version 1 is used by create_orders and create_users",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Load(c.files)
+			assert.EqualError(t, err, c.err)
+		})
+	}
+
+	_, err = New(nil, nil, Options{Driver: "sqlite3", Table: "migrations; DROP TABLE users"})
+	assert.EqualError(t, err, `migrate: invalid table name "migrations; DROP TABLE users"`)
+
+	_, err = New(nil, nil, Options{Driver: "oracle"})
+	assert.EqualError(t, err, `migrate: unsupported driver "oracle", use one of cloudsqlpostgres, mysql, pgx, postgres, sqlite, sqlite3`)
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("up and down", func(t *testing.T) {
+		db := openTestDB(t)
+		m := newMigrator(t, db, Options{Driver: "sqlite3"})
+
+		applied, err := m.Up(ctx, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, []int64{1, 2}, versions(applied))
+
+		_, err = db.ExecContext(ctx, "INSERT INTO sessions (id) VALUES ('session-1')")
+		assert.Nil(t, err)
+
+		applied, err = m.Up(ctx, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, []int64{3}, versions(applied))
+
+		applied, err = m.Up(ctx, 0)
+		assert.Nil(t, err)
+		assert.Empty(t, applied)
+
+		statuses, err := m.Status(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, statuses, 3)
+		assert.True(t, statuses[2].Applied)
+
+		// 0003 has no down migration
+		_, err = m.Down(ctx, 1)
+		assert.EqualError(t, err, "migrate: version 3 (create_orders) has no down migration")
+
+		_, err = db.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = 3")
+		assert.Nil(t, err)
+		reverted, err := m.Down(ctx, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, []int64{2, 1}, versions(reverted))
+
+		statuses, err = m.Status(ctx)
+		assert.Nil(t, err)
+		assert.False(t, statuses[0].Applied)
+		assert.False(t, statuses[1].Applied)
+	})
+
+	t.Run("failed migrations are not recorded", func(t *testing.T) {
+		db := openTestDB(t)
+		m, err := New(db, []Migration{
+			{Version: 1, Name: "create_users", Up: "CREATE TABLE users (id INTEGER PRIMARY KEY);"},
+			{Version: 2, Name: "broken", Up: "ALTER TABLE missing ADD COLUMN name TEXT;"},
+		}, Options{Driver: "sqlite3", Table: "versions"})
+		assert.Nil(t, err)
+
+		applied, err := m.Up(ctx, 0)
+		assert.Contains(t, err.Error(), "migrate: 2_broken.up.sql: no such table: missing")
+		assert.Equal(t, []int64{1}, versions(applied))
+
+		statuses, err := m.Status(ctx)
+		assert.Nil(t, err)
+		assert.True(t, statuses[0].Applied)
+		assert.False(t, statuses[1].Applied)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		db := openTestDB(t)
+		var out bytes.Buffer
+		m := newMigrator(t, db, Options{Driver: "sqlite3", DryRun: &out})
+
+		applied, err := m.Up(ctx, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, []int64{1}, versions(applied))
+		assert.Equal(t, "-- 1_create_users.up.sql\nCREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);\n", out.String())
+
+		var count int
+		assert.Nil(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(&count))
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("concurrent runs", func(t *testing.T) {
+		db := openTestDB(t)
+
+		var wg sync.WaitGroup
+		results := make([][]Migration, 3)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				applied, err := newMigrator(t, db, Options{Driver: "sqlite3"}).Up(ctx, 0)
+				assert.Nil(t, err)
+				results[i] = applied
+			}(i)
+		}
+		wg.Wait()
+
+		total := 0
+		for _, applied := range results {
+			total += len(applied)
+		}
+		assert.Equal(t, 3, total)
+	})
+
+	t.Run("lock timeout", func(t *testing.T) {
+		db := openTestDB(t)
+		_, err := newMigrator(t, db, Options{Driver: "sqlite3"}).Up(ctx, 1)
+		assert.Nil(t, err)
+
+		unlock, err := sqlite{}.lock(ctx, db, DefaultTable)
+		assert.Nil(t, err)
+
+		_, err = newMigrator(t, db, Options{Driver: "sqlite3", LockTimeout: 200 * time.Millisecond}).Up(ctx, 0)
+		assert.Contains(t, err.Error(), "migrate: acquiring lock: timed out waiting for the lock of schema_migrations_lock")
+
+		assert.Nil(t, unlock())
+		applied, err := newMigrator(t, db, Options{Driver: "sqlite3"}).Up(ctx, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, []int64{2, 3}, versions(applied))
+	})
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+type txKey struct{}
+
+// Querier runs queries on the pool or in a transaction
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Tx is a transaction logging and measuring its queries like the
+// DB it was started from. The methods of sql.Tx are available as they are
+type Tx struct {
+	*sql.Tx
+	db *DB
+}
+
+// ExecContext runs a query without returning any rows
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := t.Tx.ExecContext(ctx, query, args...)
+	t.db.observe(ctx, query, start, err)
+	return res, err
+}
+
+// QueryContext runs a query returning rows
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := t.Tx.QueryContext(ctx, query, args...)
+	t.db.observe(ctx, query, start, err)
+	return rows, err
+}
+
+// QueryRowContext runs a query returning at most one row
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := t.Tx.QueryRowContext(ctx, query, args...)
+	t.db.observe(ctx, query, start, row.Err())
+	return row
+}
+
+// TxFromContext returns the transaction of the request, if any
+func TxFromContext(ctx context.Context) (*Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	return tx, ok
+}
+
+// Querier returns the transaction of the request started from the
+// DB, if any, so handlers and the code they call share it. Returns
+// the pool otherwise
+func (d *DB) Querier(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok && tx.db == d {
+		return tx
+	}
+	return d
+}
+
+// Transactional runs the handler in a transaction, reachable using
+// Querier or TxFromContext. The transaction is committed once the
+// handler returns a response, and rolled back when it returns an
+// error e.g. a godierr error, or panics. Handlers already running in
+// a transaction of the DB reuse it. Streamed responses are written
+// after the transaction ends
+func (d *DB) Transactional(handler util.APIHandlerFunc) util.APIHandlerFunc {
+	return func(ctx context.Context, req *util.Request) (res *util.Response, err error) {
+		if tx, ok := TxFromContext(ctx); ok && tx.db == d {
+			return handler(ctx, req)
+		}
+
+		tx, err := d.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		defer func() {
+			if p := recover(); p != nil {
+				tx.rollback(ctx)
+				panic(p)
+			}
+		}()
+
+		res, err = handler(context.WithValue(ctx, txKey{}, tx), req)
+		if err != nil {
+			tx.rollback(ctx)
+			return nil, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+}
+
+// rollback rolls the transaction back, logging failures
+// as the error of the handler takes precedence
+func (t *Tx) rollback(ctx context.Context) {
+	if err := t.Rollback(); err != nil && err != sql.ErrTxDone {
+		logger.Error("Unable to roll back transaction",
+			"requestId",
+			requestID(ctx),
+			"error",
+			err.Error(),
+		)
+	}
+}
@@ -0,0 +1,437 @@
+// Package di provides a dependency injection container to hand
+// DB pools, clients, config etc. to handlers. Providers are registered
+// with a scope, the dependency graph is checked and singletons are
+// created at startup, and handler structs get their dependencies
+// injected for every request
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+// Scope is the lifetime of the values of a provider
+type Scope int
+
+const (
+	// Singleton values are created once, at startup,
+	// and shared by every request
+	Singleton Scope = iota
+	// PerRequest values are created at most once per request
+	// and disposed once the request is handled
+	PerRequest
+)
+
+// String returns the name of the scope
+func (s Scope) String() string {
+	if s == PerRequest {
+		return "request"
+	}
+	return "singleton"
+}
+
+const (
+	// injectTag marks the fields of handler structs to inject.
+	// Use `inject:"optional"` for dependencies that might not be provided
+	injectTag string = "inject"
+)
+
+var (
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	contextType  = reflect.TypeOf((*context.Context)(nil)).Elem()
+	requestType  = reflect.TypeOf((*util.Request)(nil))
+	handlerType  = reflect.TypeOf((util.APIHandlerFunc)(nil))
+	requestTypes = map[reflect.Type]bool{contextType: true, requestType: true}
+)
+
+type provider struct {
+	scope Scope
+	out   reflect.Type
+	fn    reflect.Value
+	in    []reflect.Type
+	// value is set for values supplied as they are
+	value *reflect.Value
+}
+
+// Container holds the providers of the dependencies and the
+// singleton values. Values created by the container implementing
+// io.Closer are closed when they are disposed
+type Container struct {
+	mu         sync.Mutex
+	providers  map[reflect.Type]*provider
+	handlers   []reflect.Type
+	errs       []error
+	built      bool
+	singletons map[reflect.Type]reflect.Value
+	closers    []io.Closer
+}
+
+// New returns a new instance of Container
+func New() *Container {
+	return &Container{
+		providers:  make(map[reflect.Type]*provider),
+		singletons: make(map[reflect.Type]reflect.Value),
+	}
+}
+
+// Provide registers the constructor of a dependency. The constructor is
+// a function taking its own dependencies as arguments and returning the
+// dependency, optionally followed by an error e.g.
+// func(cfg *Config) (*sql.DB, error). Constructors of per request values
+// can also take the context.Context and *util.Request of the request
+func (c *Container) Provide(scope Scope, constructor interface{}) error {
+	fn := reflect.ValueOf(constructor)
+	if fn.Kind() != reflect.Func || fn.IsNil() {
+		return fmt.Errorf("di: constructor must be a function, got %T", constructor)
+	}
+
+	t := fn.Type()
+	if t.IsVariadic() {
+		return fmt.Errorf("di: constructor %s cannot be variadic", t)
+	}
+	if t.NumOut() == 0 || t.NumOut() > 2 || t.Out(0) == errorType || (t.NumOut() == 2 && t.Out(1) != errorType) {
+		return fmt.Errorf("di: constructor %s must return a value, optionally followed by an error", t)
+	}
+
+	p := &provider{
+		scope: scope,
+		out:   t.Out(0),
+		fn:    fn,
+	}
+	for i := 0; i < t.NumIn(); i++ {
+		p.in = append(p.in, t.In(i))
+	}
+
+	return c.register(p)
+}
+
+// Supply registers a value created beforehand as a singleton, e.g. the
+// server config. It is injected as its dynamic type. Use Provide with a
+// constructor returning an interface to inject a value as an interface
+func (c *Container) Supply(value interface{}) error {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return errors.New("di: cannot supply nil")
+	}
+
+	return c.register(&provider{
+		scope: Singleton,
+		out:   v.Type(),
+		value: &v,
+	})
+}
+
+func (c *Container) register(p *provider) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.built {
+		return fmt.Errorf("di: cannot provide %s once the container is built", p.out)
+	}
+	if requestTypes[p.out] {
+		return fmt.Errorf("di: %s is provided by the container", p.out)
+	}
+	if _, ok := c.providers[p.out]; ok {
+		return fmt.Errorf("di: %s is already provided", p.out)
+	}
+
+	c.providers[p.out] = p
+	return nil
+}
+
+// Handler returns a handler injecting the dependencies of a handler
+// struct for every request. Takes a function receiving a pointer to the
+// struct and returning the method handling the request e.g.
+//
+//	c.Handler(func(h *UserHandler) util.APIHandlerFunc { return h.Get })
+//
+// Exported fields tagged `inject` are set. Mistakes, e.g. a dependency
+// that is not provided, are reported by Build
+func (c *Container) Handler(fn interface{}) util.APIHandlerFunc {
+	v := reflect.ValueOf(fn)
+	if !isHandlerFactory(v) {
+		err := fmt.Errorf("di: handler %T must be a func(*Struct) util.APIHandlerFunc", fn)
+		c.mu.Lock()
+		c.errs = append(c.errs, err)
+		c.mu.Unlock()
+
+		return func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			return nil, err
+		}
+	}
+
+	structType := v.Type().In(0).Elem()
+	c.mu.Lock()
+	c.handlers = append(c.handlers, structType)
+	c.mu.Unlock()
+
+	return func(ctx context.Context, req *util.Request) (*util.Response, error) {
+		if err := c.Build(); err != nil {
+			return nil, err
+		}
+
+		scope := ScopeFrom(ctx)
+		if scope == nil || scope.container != c {
+			// used outside of the server, the values live as long as the call
+			scope = c.NewScope(ctx, req)
+			defer scope.Dispose()
+		}
+
+		h := reflect.New(structType)
+		if err := scope.inject(h.Elem()); err != nil {
+			return nil, err
+		}
+
+		handler := v.Call([]reflect.Value{h})[0].Interface().(util.APIHandlerFunc)
+		return handler(ctx, req)
+	}
+}
+
+// Build checks every dependency can be resolved, reporting missing
+// providers, cycles and singletons depending on per request values,
+// then creates the singletons. Calling it again does nothing
+func (c *Container) Build() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.built {
+		return nil
+	}
+	if len(c.errs) != 0 {
+		return c.errs[0]
+	}
+
+	types := make([]reflect.Type, 0, len(c.providers))
+	for t := range c.providers {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].String() < types[j].String()
+	})
+
+	visited := make(map[reflect.Type]bool)
+	for _, t := range types {
+		if err := c.check(t, nil, visited); err != nil {
+			return err
+		}
+	}
+
+	for _, structType := range c.handlers {
+		for _, field := range injectedFields(structType) {
+			if field.err != nil {
+				return field.err
+			}
+			if _, ok := c.providers[field.t]; !ok && !requestTypes[field.t] && !field.optional {
+				return fmt.Errorf("di: no provider for %s (required by field %s of %s)", field.t, field.name, structType)
+			}
+		}
+	}
+
+	for _, t := range types {
+		if c.providers[t].scope != Singleton {
+			continue
+		}
+		if _, err := c.singleton(t); err != nil {
+			return err
+		}
+	}
+
+	c.built = true
+	return nil
+}
+
+// check walks the dependencies of the type depth first.
+// Path is the chain of types depending on it
+func (c *Container) check(t reflect.Type, path []reflect.Type, visited map[reflect.Type]bool) error {
+	for i, dependent := range path {
+		if dependent == t {
+			return fmt.Errorf("di: dependency cycle: %s", formatPath(append(path[i:], t)))
+		}
+	}
+	if visited[t] {
+		return nil
+	}
+
+	p := c.providers[t]
+	for _, in := range p.in {
+		if requestTypes[in] {
+			if p.scope == Singleton {
+				return fmt.Errorf("di: singleton %s cannot depend on %s, which only exists during a request", t, in)
+			}
+			continue
+		}
+
+		dep, ok := c.providers[in]
+		if !ok {
+			return fmt.Errorf("di: no provider for %s (required by %s)", in, formatPath(append(path, t)))
+		}
+		if p.scope == Singleton && dep.scope == PerRequest {
+			return fmt.Errorf("di: singleton %s cannot depend on %s, which is provided per request", t, in)
+		}
+		if err := c.check(in, append(path, t), visited); err != nil {
+			return err
+		}
+	}
+
+	visited[t] = true
+	return nil
+}
+
+// singleton returns the singleton value of the type, creating it and
+// its dependencies if needed. Must be called with the lock held, once
+// the graph is checked
+func (c *Container) singleton(t reflect.Type) (reflect.Value, error) {
+	if v, ok := c.singletons[t]; ok {
+		return v, nil
+	}
+
+	p := c.providers[t]
+	if p.value != nil {
+		c.singletons[t] = *p.value
+		return *p.value, nil
+	}
+
+	args := make([]reflect.Value, len(p.in))
+	for i, in := range p.in {
+		arg, err := c.singleton(in)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		args[i] = arg
+	}
+
+	v, err := call(p, args)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	c.singletons[t] = v
+	if closer, ok := v.Interface().(io.Closer); ok {
+		c.closers = append(c.closers, closer)
+	}
+	return v, nil
+}
+
+// Resolve sets target, a pointer, to the singleton value of its type e.g.
+//
+//	var db *sql.DB
+//	err := c.Resolve(&db)
+func (c *Container) Resolve(target interface{}) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("di: target must be a pointer, got %T", target)
+	}
+	if err := c.Build(); err != nil {
+		return err
+	}
+	t := ptr.Elem().Type()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.providers[t]
+	if !ok {
+		return fmt.Errorf("di: no provider for %s", t)
+	}
+	if p.scope != Singleton {
+		return fmt.Errorf("di: %s is provided per request, resolve it from the request scope", t)
+	}
+
+	v, err := c.singleton(t)
+	if err != nil {
+		return err
+	}
+	ptr.Elem().Set(v)
+	return nil
+}
+
+// Close closes the singletons implementing io.Closer,
+// in the reverse order they were created
+func (c *Container) Close() error {
+	c.mu.Lock()
+	closers := c.closers
+	c.closers = nil
+	c.mu.Unlock()
+
+	return closeAll(closers)
+}
+
+// call calls the constructor of the provider
+func call(p *provider, args []reflect.Value) (reflect.Value, error) {
+	out := p.fn.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("di: providing %s: %w", p.out, out[1].Interface().(error))
+	}
+	return out[0], nil
+}
+
+// isHandlerFactory reports whether v is a func(*Struct) util.APIHandlerFunc
+func isHandlerFactory(v reflect.Value) bool {
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return false
+	}
+
+	t := v.Type()
+	return t.NumIn() == 1 && t.NumOut() == 1 && t.Out(0) == handlerType &&
+		t.In(0).Kind() == reflect.Ptr && t.In(0).Elem().Kind() == reflect.Struct
+}
+
+// closeAll closes the closers in reverse order,
+// returning the first error
+func closeAll(closers []io.Closer) error {
+	var first error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+type injectedField struct {
+	index    int
+	name     string
+	t        reflect.Type
+	optional bool
+	err      error
+}
+
+// injectedFields returns the fields of the struct tagged inject
+func injectedFields(structType reflect.Type) []injectedField {
+	var fields []injectedField
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag, ok := field.Tag.Lookup(injectTag)
+		if !ok {
+			continue
+		}
+
+		f := injectedField{
+			index:    i,
+			name:     field.Name,
+			t:        field.Type,
+			optional: tag == "optional",
+		}
+		if field.PkgPath != "" {
+			f.err = fmt.Errorf("di: field %s of %s must be exported to be injected", field.Name, structType)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func formatPath(path []reflect.Type) string {
+	names := make([]string, len(path))
+	for i, t := range path {
+		names[i] = t.String()
+	}
+	return strings.Join(names, " -> ")
+}
@@ -0,0 +1,230 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type config struct {
+	DSN string
+}
+
+type pool struct {
+	dsn    string
+	closed bool
+}
+
+func (p *pool) Close() error {
+	p.closed = true
+	return nil
+}
+
+type tx struct {
+	pool   *pool
+	path   string
+	closed bool
+}
+
+func (t *tx) Close() error {
+	t.closed = true
+	return nil
+}
+
+type repo struct {
+	tx *tx
+}
+
+type userHandler struct {
+	Pool  *pool           `inject:""`
+	Repo  *repo           `inject:""`
+	Ctx   context.Context `inject:""`
+	Clock func() int64    `inject:"optional"`
+}
+
+func (h *userHandler) Get(ctx context.Context, req *util.Request) (*util.Response, error) {
+	return &util.Response{
+		StatusCode: http.StatusOK,
+		Body:       h.Repo.tx.path + " " + h.Pool.dsn,
+	}, nil
+}
+
+func newContainer(t *testing.T) *Container {
+	c := New()
+	assert.Nil(t, c.Supply(&config{DSN: "postgres://db"}))
+	assert.Nil(t, c.Provide(Singleton, func(cfg *config) (*pool, error) {
+		return &pool{dsn: cfg.DSN}, nil
+	}))
+	assert.Nil(t, c.Provide(PerRequest, func(p *pool, req *util.Request) *tx {
+		return &tx{pool: p, path: req.URL.Path}
+	}))
+	assert.Nil(t, c.Provide(PerRequest, func(t *tx) *repo {
+		return &repo{tx: t}
+	}))
+	return c
+}
+
+func TestContainer(t *testing.T) {
+	t.Run("singletons", func(t *testing.T) {
+		c := newContainer(t)
+		assert.Nil(t, c.Build())
+
+		var p1, p2 *pool
+		assert.Nil(t, c.Resolve(&p1))
+		assert.Nil(t, c.Resolve(&p2))
+		assert.Equal(t, "postgres://db", p1.dsn)
+		assert.Same(t, p1, p2)
+
+		var r *repo
+		assert.EqualError(t, c.Resolve(&r), "di: *di.repo is provided per request, resolve it from the request scope")
+
+		assert.Nil(t, c.Close())
+		assert.True(t, p1.closed)
+	})
+
+	t.Run("request scope", func(t *testing.T) {
+		c := newContainer(t)
+		r, err := http.NewRequest(http.MethodGet, "/users", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+
+		scope := c.NewScope(context.Background(), &util.Request{Request: r})
+		var first, second *tx
+		var rp *repo
+		assert.Nil(t, scope.Resolve(&first))
+		assert.Nil(t, scope.Resolve(&second))
+		assert.Nil(t, scope.Resolve(&rp))
+		assert.Same(t, first, second)
+		assert.Same(t, first, rp.tx)
+		assert.Equal(t, "/users", first.path)
+
+		other := c.NewScope(context.Background(), &util.Request{Request: r})
+		var third *tx
+		assert.Nil(t, other.Resolve(&third))
+		assert.NotSame(t, first, third)
+
+		assert.Nil(t, scope.Dispose())
+		assert.True(t, first.closed)
+		assert.False(t, first.pool.closed)
+		assert.False(t, third.closed)
+		assert.EqualError(t, scope.Resolve(&first), "di: cannot resolve *di.tx, the request scope is disposed")
+	})
+
+	t.Run("handlers", func(t *testing.T) {
+		c := newContainer(t)
+		handler := c.Handler(func(h *userHandler) util.APIHandlerFunc { return h.Get })
+		assert.Nil(t, c.Build())
+
+		r, err := http.NewRequest(http.MethodGet, "/users/1", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+
+		scope := c.NewScope(context.Background(), &util.Request{Request: r})
+		ctx := WithScope(context.Background(), scope)
+		res, err := handler(ctx, &util.Request{Request: r})
+		assert.Nil(t, err)
+		assert.Equal(t, "/users/1 postgres://db", res.Body)
+
+		var used *tx
+		assert.Nil(t, scope.Resolve(&used))
+		assert.False(t, used.closed)
+		assert.Nil(t, scope.Dispose())
+		assert.True(t, used.closed)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		type a struct{}
+		type b struct{}
+		type c struct{}
+
+		cases := []struct {
+			name  string
+			setup func(*Container) error
+			err   string
+		}{
+			{
+				name: "missing provider",
+				setup: func(ctr *Container) error {
+					return ctr.Provide(Singleton, func(*b) *a { return nil })
+				},
+				err: "di: no provider for *di.b (required by *di.a)",
+			},
+			{
+				name: "cycle",
+				setup: func(ctr *Container) error {
+					ctr.Provide(Singleton, func(*b) *a { return nil })
+					ctr.Provide(Singleton, func(*c) *b { return nil })
+					return ctr.Provide(Singleton, func(*a) *c { return nil })
+				},
+				err: "di: dependency cycle: *di.a -> *di.b -> *di.c -> *di.a",
+			},
+			{
+				name: "singleton depending on a per request value",
+				setup: func(ctr *Container) error {
+					ctr.Provide(Singleton, func(*b) *a { return nil })
+					return ctr.Provide(PerRequest, func() *b { return nil })
+				},
+				err: "di: singleton *di.a cannot depend on *di.b, which is provided per request",
+			},
+			{
+				name: "singleton depending on the request",
+				setup: func(ctr *Container) error {
+					return ctr.Provide(Singleton, func(context.Context) *a { return nil })
+				},
+				err: "di: singleton *di.a cannot depend on context.Context, which only exists during a request",
+			},
+			{
+				name: "failing constructor",
+				setup: func(ctr *Container) error {
+					return ctr.Provide(Singleton, func() (*a, error) { return nil, errors.New("connection refused") })
+				},
+				err: "di: providing *di.a: connection refused",
+			},
+			{
+				name: "handler field not provided",
+				setup: func(ctr *Container) error {
+					ctr.Handler(func(h *userHandler) util.APIHandlerFunc { return h.Get })
+					return nil
+				},
+				err: "di: no provider for *di.pool (required by field Pool of di.userHandler)",
+			},
+			{
+				name: "invalid handler",
+				setup: func(ctr *Container) error {
+					ctr.Handler(func(h userHandler) util.APIHandlerFunc { return h.Get })
+					return nil
+				},
+				err: "di: handler func(di.userHandler) util.APIHandlerFunc must be a func(*Struct) util.APIHandlerFunc",
+			},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				ctr := New()
+				assert.Nil(t, tc.setup(ctr))
+				assert.EqualError(t, ctr.Build(), tc.err)
+			})
+		}
+	})
+
+	t.Run("invalid providers", func(t *testing.T) {
+		ctr := New()
+		assert.EqualError(t, ctr.Provide(Singleton, "pool"), "di: constructor must be a function, got string")
+		assert.EqualError(t, ctr.Provide(Singleton, func() error { return nil }), "di: constructor func() error must return a value, optionally followed by an error")
+		assert.EqualError(t, ctr.Provide(PerRequest, func() context.Context { return nil }), "di: context.Context is provided by the container")
+		assert.EqualError(t, ctr.Supply(nil), "di: cannot supply nil")
+
+		assert.Nil(t, ctr.Supply(&config{}))
+		assert.EqualError(t, ctr.Supply(&config{}), "di: *di.config is already provided")
+
+		assert.Nil(t, ctr.Build())
+		assert.EqualError(t, ctr.Provide(Singleton, func() *pool { return nil }), "di: cannot provide *di.pool once the container is built")
+	})
+}
@@ -0,0 +1,180 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+type scopeKey struct{}
+
+// RequestScope holds the per request values of a request
+type RequestScope struct {
+	container *Container
+	ctx       context.Context
+	req       *util.Request
+
+	mu       sync.Mutex
+	values   map[reflect.Type]reflect.Value
+	closers  []io.Closer
+	disposed bool
+}
+
+// NewScope returns a new scope for the request. Dispose it
+// once the request is handled
+func (c *Container) NewScope(ctx context.Context, req *util.Request) *RequestScope {
+	return &RequestScope{
+		container: c,
+		ctx:       ctx,
+		req:       req,
+		values:    make(map[reflect.Type]reflect.Value),
+	}
+}
+
+// WithScope returns a copy of the context carrying the scope
+func WithScope(ctx context.Context, scope *RequestScope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFrom returns the scope carried by the context, if any
+func ScopeFrom(ctx context.Context) *RequestScope {
+	scope, _ := ctx.Value(scopeKey{}).(*RequestScope)
+	return scope
+}
+
+// Resolve sets target, a pointer, to the value of its type
+// for the request, creating it if needed
+func (s *RequestScope) Resolve(target interface{}) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("di: target must be a pointer, got %T", target)
+	}
+	if err := s.container.Build(); err != nil {
+		return err
+	}
+
+	v, err := s.resolve(ptr.Elem().Type())
+	if err != nil {
+		return err
+	}
+	ptr.Elem().Set(v)
+	return nil
+}
+
+func (s *RequestScope) resolve(t reflect.Type) (reflect.Value, error) {
+	if v, ok, err := s.shared(t); ok {
+		return v, err
+	}
+
+	p := s.container.provider(t)
+	if p == nil {
+		return reflect.Value{}, fmt.Errorf("di: no provider for %s", t)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.perRequest(p)
+}
+
+// shared returns the values of the type not owned by the scope: the
+// context and request, and singletons. Reports false for other types
+func (s *RequestScope) shared(t reflect.Type) (reflect.Value, bool, error) {
+	switch t {
+	case contextType:
+		return reflect.ValueOf(&s.ctx).Elem(), true, nil
+	case requestType:
+		return reflect.ValueOf(s.req), true, nil
+	}
+
+	c := s.container
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.providers[t]; !ok || p.scope != Singleton {
+		return reflect.Value{}, false, nil
+	}
+	v, err := c.singleton(t)
+	return v, true, err
+}
+
+// perRequest returns the value of the provider for the request, creating
+// it and its dependencies if needed. Must be called with the lock held
+func (s *RequestScope) perRequest(p *provider) (reflect.Value, error) {
+	if s.disposed {
+		return reflect.Value{}, fmt.Errorf("di: cannot resolve %s, the request scope is disposed", p.out)
+	}
+	if v, ok := s.values[p.out]; ok {
+		return v, nil
+	}
+
+	args := make([]reflect.Value, len(p.in))
+	for i, in := range p.in {
+		arg, ok, err := s.shared(in)
+		if !ok {
+			dep := s.container.provider(in)
+			if dep == nil {
+				return reflect.Value{}, fmt.Errorf("di: no provider for %s (required by %s)", in, p.out)
+			}
+			arg, err = s.perRequest(dep)
+		}
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		args[i] = arg
+	}
+
+	v, err := call(p, args)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	s.values[p.out] = v
+	if closer, ok := v.Interface().(io.Closer); ok {
+		s.closers = append(s.closers, closer)
+	}
+	return v, nil
+}
+
+// inject sets the fields of the struct tagged inject
+func (s *RequestScope) inject(v reflect.Value) error {
+	for _, field := range injectedFields(v.Type()) {
+		if field.err != nil {
+			return field.err
+		}
+		if field.optional && !requestTypes[field.t] && s.container.provider(field.t) == nil {
+			continue
+		}
+
+		value, err := s.resolve(field.t)
+		if err != nil {
+			return err
+		}
+		v.Field(field.index).Set(value)
+	}
+	return nil
+}
+
+// Dispose closes the per request values implementing io.Closer,
+// in the reverse order they were created
+func (s *RequestScope) Dispose() error {
+	s.mu.Lock()
+	closers := s.closers
+	s.closers = nil
+	s.values = nil
+	s.disposed = true
+	s.mu.Unlock()
+
+	return closeAll(closers)
+}
+
+func (c *Container) provider(t reflect.Type) *provider {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.providers[t]
+}
@@ -1,6 +1,16 @@
 package godierr
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+const (
+	// maximum number of frames captured in the stack of an error
+	maxStackDepth int = 32
+)
 
 // Error wraps a Go error
 // with more human friendly information.
@@ -13,12 +23,26 @@ type Error struct {
 	code    int
 	t       string
 	message string
+
+	// key and args identify the message in catalogs
+	// to send it to clients in their language
+	key  string
+	args map[string]interface{}
+
+	// context is the annotation added by Wrap
+	context string
+	fields  map[string]interface{}
+	stack   []uintptr
 }
 
 // Error returns a formatted version
 // of the error message and the original
 // error passed in
 func (e *Error) Error() string {
+	if e.context != "" {
+		return fmt.Sprintf("%s: %s", e.context, e.error.Error())
+	}
+
 	msg := fmt.Sprintf("%s", e.message)
 	if e.error != nil {
 		msg = fmt.Sprintf("%s due to %s", msg, e.error.Error())
@@ -26,6 +50,12 @@ func (e *Error) Error() string {
 	return msg
 }
 
+// Unwrap returns the original error, if any,
+// so the error works with errors.Is and errors.As
+func (e *Error) Unwrap() error {
+	return e.error
+}
+
 // Code returns the error code
 func (e *Error) Code() int {
 	return e.code
@@ -41,13 +71,156 @@ func (e *Error) Message() string {
 	return e.message
 }
 
+// WithMessageKey sets the key of the message in message catalogs
+// and the arguments to fill in its placeholders e.g. {resources}
+func (e *Error) WithMessageKey(key string, args map[string]interface{}) *Error {
+	e.key = key
+	e.args = args
+	return e
+}
+
+// MessageKey returns the key of the message in message
+// catalogs. Empty when the message cannot be translated
+func (e *Error) MessageKey() string {
+	return e.key
+}
+
+// MessageArgs returns the arguments of the message
+func (e *Error) MessageArgs() map[string]interface{} {
+	return e.args
+}
+
+// WithField attaches a structured field to the error.
+// Fields are logged but never sent to clients
+func (e *Error) WithField(key string, value interface{}) *Error {
+	if e.fields == nil {
+		e.fields = make(map[string]interface{})
+	}
+	e.fields[key] = value
+	return e
+}
+
+// WithFields attaches the structured fields to the error.
+// Fields are logged but never sent to clients
+func (e *Error) WithFields(fields map[string]interface{}) *Error {
+	for key, value := range fields {
+		e.WithField(key, value)
+	}
+	return e
+}
+
+// Fields returns the fields attached to the error and to
+// any error it wraps. Fields of outer errors take precedence
+func (e *Error) Fields() map[string]interface{} {
+	var chain []*Error
+	for err := error(e); err != nil; err = errors.Unwrap(err) {
+		if godiErr, ok := err.(*Error); ok {
+			chain = append(chain, godiErr)
+		}
+	}
+
+	fields := make(map[string]interface{})
+	for i := len(chain) - 1; i >= 0; i-- {
+		for key, value := range chain[i].fields {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// Stack returns the stack trace of where the error was
+// created. For wrapped errors, it is the stack of the
+// innermost Error, where the error originated
+func (e *Error) Stack() string {
+	pcs := e.stack
+	for err := errors.Unwrap(e); err != nil; err = errors.Unwrap(err) {
+		if godiErr, ok := err.(*Error); ok && len(godiErr.stack) != 0 {
+			pcs = godiErr.stack
+		}
+	}
+
+	if len(pcs) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
 // New returns an Error object. Takes the error code,
 // type, message, and the original error, if any
 func New(code int, t, msg string, err error) *Error {
+	return newSkip(1, code, t, msg, err)
+}
+
+// newSkip returns an Error whose stack starts at the caller of
+// the skip frames above it e.g. of the sentinel constructors
+func newSkip(skip, code int, t, msg string, err error) *Error {
 	return &Error{
 		code:    code,
 		t:       t,
 		message: msg,
 		error:   err,
+		stack:   callers(3 + skip),
+	}
+}
+
+// Wrap annotates the error with msg. The result keeps the code,
+// type and message of the Error it wraps, if any, so clients get
+// the same response. Any other error is treated as an internal error.
+// Returns nil when err is nil
+func Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return wrap(err, msg)
+}
+
+// Wrapf annotates the error with the formatted message.
+// Behaves the same as Wrap
+func Wrapf(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return wrap(err, fmt.Sprintf(format, args...))
+}
+
+func wrap(err error, msg string) *Error {
+	wrapped := &Error{
+		error:   err,
+		context: msg,
+		stack:   callers(4),
+	}
+
+	var inner *Error
+	if errors.As(err, &inner) {
+		wrapped.code = inner.code
+		wrapped.t = inner.t
+		wrapped.message = inner.message
+		wrapped.key = inner.key
+		wrapped.args = inner.args
+	} else {
+		wrapped.code = 500
+		wrapped.t = InternalType
+		wrapped.message = InternalMsg
+		wrapped.key = InternalType
 	}
+
+	return wrapped
+}
+
+// callers returns the program counters of the stack,
+// skipping the frames of the error constructors
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip, pcs)
+	return pcs[:n]
 }
@@ -2,6 +2,9 @@ package godierr
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,3 +20,92 @@ func TestGodiError(t *testing.T) {
 	assert.Equal(t, msg, err.Message())
 	assert.Equal(t, formattedErr, err.Error())
 }
+
+func TestUnwrap(t *testing.T) {
+	err := New(500, "RANDOM_ERROR", "this is an error", io.EOF)
+
+	assert.Equal(t, io.EOF, err.Unwrap())
+	assert.True(t, errors.Is(err, io.EOF))
+
+	var godiErr *Error
+	assert.True(t, errors.As(fmt.Errorf("handler: %w", err), &godiErr))
+	assert.Equal(t, "RANDOM_ERROR", godiErr.Type())
+}
+
+func TestWrap(t *testing.T) {
+	t.Run("nil errors", func(t *testing.T) {
+		assert.Nil(t, Wrap(nil, "reading user"))
+		assert.Nil(t, Wrapf(nil, "reading user %d", 1))
+	})
+
+	t.Run("keeps the response of wrapped errors", func(t *testing.T) {
+		err := Wrapf(InvalidArgsError("id"), "reading user %d", 1)
+
+		var godiErr *Error
+		assert.True(t, errors.As(err, &godiErr))
+		assert.Equal(t, 400, godiErr.Code())
+		assert.Equal(t, InvalidArgType, godiErr.Type())
+		assert.Equal(t, "invalid argument(s) passed in: id", godiErr.Message())
+		assert.Equal(t, "reading user 1: invalid argument(s) passed in: id", err.Error())
+	})
+
+	t.Run("other errors are internal", func(t *testing.T) {
+		err := Wrap(io.EOF, "reading user")
+
+		var godiErr *Error
+		assert.True(t, errors.As(err, &godiErr))
+		assert.Equal(t, 500, godiErr.Code())
+		assert.Equal(t, InternalType, godiErr.Type())
+		assert.Equal(t, InternalMsg, godiErr.Message())
+		assert.Equal(t, "reading user: EOF", err.Error())
+		assert.True(t, errors.Is(err, io.EOF))
+	})
+}
+
+func TestFields(t *testing.T) {
+	inner := InvalidArgsError("id").
+		WithField("userId", 1).
+		WithField("table", "users")
+	err := Wrap(inner, "reading user").(*Error).WithFields(map[string]interface{}{
+		"table": "accounts",
+		"retry": false,
+	})
+
+	assert.Equal(t, map[string]interface{}{
+		"userId": 1,
+		"table":  "accounts",
+		"retry":  false,
+	}, err.Fields())
+	assert.NotContains(t, err.Error(), "userId")
+	assert.Empty(t, New(500, "RANDOM_ERROR", "no fields", nil).Fields())
+}
+
+func TestStack(t *testing.T) {
+	inner := New(500, "RANDOM_ERROR", "this is an error", nil)
+
+	assert.Contains(t, inner.Stack(), "godierr.TestStack")
+	assert.NotContains(t, inner.Stack(), "godierr.New")
+
+	wrapped := wrapInHelper(inner).(*Error)
+	assert.Equal(t, inner.Stack(), wrapped.Stack())
+
+	// errors not created by the package get the stack of where they were wrapped
+	assert.Contains(t, wrapInHelper(io.EOF).(*Error).Stack(), "godierr.wrapInHelper")
+
+	t.Run("sentinels start at the caller", func(t *testing.T) {
+		sentinels := []*Error{
+			NotFoundError("user"),
+			InvalidArgsError("email"),
+			InternalError(io.EOF),
+			NewType(RateLimitedType, "", nil),
+		}
+
+		for _, err := range sentinels {
+			assert.True(t, strings.HasPrefix(err.Stack(), "github.com/riyadhalnur/godi/v2/pkg/godierr.TestStack.func1\n"), err.Stack())
+		}
+	})
+}
+
+func wrapInHelper(err error) error {
+	return Wrap(err, "helper")
+}
@@ -0,0 +1,162 @@
+package godierr
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Definition documents an error type
+// Type - the stable error "type" sent to clients
+// HTTPStatus - the HTTP status code errors of the type are returned with
+// GRPCCode - the equivalent gRPC status code, as the numeric value of google.golang.org/grpc/codes
+// Message - the default client facing message
+// Description - when the error is returned. Used for documentation e.g. the OpenAPI output
+type Definition struct {
+	Type        string
+	HTTPStatus  int
+	GRPCCode    uint32
+	Message     string
+	Description string
+}
+
+// gRPC status codes, as the numeric values of google.golang.org/grpc/codes,
+// to set the GRPCCode of definitions without depending on gRPC
+const (
+	GRPCCanceled           uint32 = 1
+	GRPCUnknown            uint32 = 2
+	GRPCInvalidArgument    uint32 = 3
+	GRPCDeadlineExceeded   uint32 = 4
+	GRPCNotFound           uint32 = 5
+	GRPCAlreadyExists      uint32 = 6
+	GRPCPermissionDenied   uint32 = 7
+	GRPCResourceExhausted  uint32 = 8
+	GRPCFailedPrecondition uint32 = 9
+	GRPCAborted            uint32 = 10
+	GRPCOutOfRange         uint32 = 11
+	GRPCUnimplemented      uint32 = 12
+	GRPCInternal           uint32 = 13
+	GRPCUnavailable        uint32 = 14
+	GRPCDataLoss           uint32 = 15
+	GRPCUnauthenticated    uint32 = 16
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Definition{}
+)
+
+// builtinDefinitions are the definitions of the error types
+// formed by the sentinel constructors of the package
+var builtinDefinitions = []Definition{
+	{RequiredArgType, 400, GRPCInvalidArgument, RequiredArgMsg, "A required argument is missing"},
+	{InvalidArgType, 400, GRPCInvalidArgument, InvalidArgMsg, "An argument is malformed or out of range"},
+	{UnauthenticatedType, 401, GRPCUnauthenticated, UnauthenticatedMsg, "The request does not have valid credentials"},
+	{PermissionDeniedType, 403, GRPCPermissionDenied, PermissionDeniedMsg, "The caller is not allowed to perform the operation"},
+	{NotFoundType, 404, GRPCNotFound, NotFoundMsg, "The requested resource does not exist"},
+	{NotAcceptableType, 406, GRPCInvalidArgument, NotAcceptableMsg, "The response cannot be encoded in any media type the client accepts"},
+	{ConflictType, 409, GRPCAlreadyExists, ConflictMsg, "The request conflicts with the current state of the resource"},
+	{PreconditionFailedType, 412, GRPCFailedPrecondition, PreconditionFailedMsg, "A precondition of the request does not hold"},
+	{PayloadTooLargeType, 413, GRPCResourceExhausted, PayloadTooLargeMsg, "The request body is larger than allowed"},
+	{UnsupportedEncodingType, 415, GRPCInvalidArgument, UnsupportedEncodingMsg, "The content encoding of the request body is not supported"},
+	{UnprocessableType, 422, GRPCInvalidArgument, UnprocessableMsg, "The request is well-formed but cannot be processed"},
+	{RateLimitedType, 429, GRPCResourceExhausted, RateLimitedMsg, "The client sent too many requests"},
+	{InternalType, 500, GRPCInternal, InternalMsg, "An unexpected error occurred"},
+	{UnavailableType, 503, GRPCUnavailable, UnavailableMsg, "The service is unable to handle the request"},
+	{TimeoutType, 504, GRPCDeadlineExceeded, TimeoutMsg, "The request did not complete in time"},
+}
+
+func init() {
+	for _, def := range builtinDefinitions {
+		registry[def.Type] = def
+	}
+}
+
+// Register adds the definition of an application
+// error type, so errors of the type can be formed using
+// NewType and are documented along with the built-in ones.
+// Returns an error when the type is already registered
+func Register(def Definition) error {
+	if def.Type == "" {
+		return RequiredArgsError("type")
+	}
+	if def.HTTPStatus < 400 || def.HTTPStatus > 599 {
+		return InvalidArgsError("http status")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[def.Type]; ok {
+		return fmt.Errorf("error type %s is already registered", def.Type)
+	}
+	registry[def.Type] = def
+
+	return nil
+}
+
+// unregister removes the definition of an error type e.g. in tests
+func unregister(t string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	delete(registry, t)
+}
+
+// Lookup returns the definition of the error type
+func Lookup(t string) (Definition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	def, ok := registry[t]
+	return def, ok
+}
+
+// Definitions returns the definitions of all
+// the error types, ordered by HTTP status and type
+func Definitions() []Definition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	defs := make([]Definition, 0, len(registry))
+	for _, def := range registry {
+		defs = append(defs, def)
+	}
+
+	sort.Slice(defs, func(i, j int) bool {
+		if defs[i].HTTPStatus != defs[j].HTTPStatus {
+			return defs[i].HTTPStatus < defs[j].HTTPStatus
+		}
+		return defs[i].Type < defs[j].Type
+	})
+	return defs
+}
+
+// NewType returns an Error of a registered type. Takes the
+// type, the message and the original error, if any. The default
+// message of the type is used when msg is empty, which can be
+// translated using the type as the message key. Unknown types
+// are returned as internal errors
+func NewType(t, msg string, err error) *Error {
+	def, ok := Lookup(t)
+	if !ok {
+		def, _ = Lookup(InternalType)
+	}
+
+	e := newSkip(1, def.HTTPStatus, def.Type, msg, err)
+	if msg == "" {
+		e.message = def.Message
+		e.key = def.Type
+	}
+	return e
+}
+
+// GRPCCode returns the gRPC status code equivalent to the error
+// type, as the numeric value of google.golang.org/grpc/codes.
+// Unregistered types are GRPCUnknown
+func (e *Error) GRPCCode() uint32 {
+	if def, ok := Lookup(e.t); ok {
+		return def.GRPCCode
+	}
+	return GRPCUnknown
+}
@@ -0,0 +1,103 @@
+package godierr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("sentinels match their definitions", func(t *testing.T) {
+		sentinels := []*Error{
+			RequiredArgsError(),
+			InvalidArgsError(),
+			UnauthenticatedError(nil),
+			PermissionDeniedError(nil),
+			NotFoundError(),
+			NotAcceptableError(),
+			ConflictError(),
+			PreconditionFailedError(),
+			PayloadTooLargeError(0),
+			UnsupportedEncodingError(""),
+			UnprocessableError(),
+			RateLimitedError(),
+			InternalError(nil),
+			UnavailableError(nil),
+			TimeoutError(nil),
+		}
+		assert.Len(t, builtinDefinitions, len(sentinels))
+
+		for _, err := range sentinels {
+			def, ok := Lookup(err.Type())
+			assert.True(t, ok, err.Type())
+			assert.Equal(t, def.HTTPStatus, err.Code(), err.Type())
+			assert.Equal(t, def.GRPCCode, err.GRPCCode(), err.Type())
+		}
+	})
+
+	t.Run("grpc codes", func(t *testing.T) {
+		assert.Equal(t, GRPCInvalidArgument, InvalidArgsError().GRPCCode())
+		assert.Equal(t, GRPCNotFound, NotFoundError().GRPCCode())
+		assert.Equal(t, GRPCResourceExhausted, PayloadTooLargeError(0).GRPCCode())
+		assert.Equal(t, GRPCInvalidArgument, UnprocessableError().GRPCCode())
+		assert.Equal(t, GRPCResourceExhausted, RateLimitedError().GRPCCode())
+	})
+
+	t.Run("registers application types", func(t *testing.T) {
+		t.Cleanup(func() { unregister("PAYMENT_REQUIRED") })
+
+		err := Register(Definition{
+			Type:        "PAYMENT_REQUIRED",
+			HTTPStatus:  402,
+			GRPCCode:    GRPCFailedPrecondition,
+			Message:     "payment required",
+			Description: "The account has no credit left",
+		})
+		assert.Nil(t, err)
+
+		paymentErr := NewType("PAYMENT_REQUIRED", "", nil)
+		assert.Equal(t, 402, paymentErr.Code())
+		assert.Equal(t, "payment required", paymentErr.Message())
+		assert.Equal(t, GRPCFailedPrecondition, paymentErr.GRPCCode())
+		assert.Contains(t, paymentErr.Stack(), "godierr.TestRegistry")
+
+		assert.Contains(t, Definitions(), Definition{
+			Type:        "PAYMENT_REQUIRED",
+			HTTPStatus:  402,
+			GRPCCode:    GRPCFailedPrecondition,
+			Message:     "payment required",
+			Description: "The account has no credit left",
+		})
+
+		err = Register(Definition{Type: "PAYMENT_REQUIRED", HTTPStatus: 402})
+		assert.EqualError(t, err, "error type PAYMENT_REQUIRED is already registered")
+	})
+
+	t.Run("invalid definitions", func(t *testing.T) {
+		var godiErr *Error
+
+		assert.True(t, errors.As(Register(Definition{HTTPStatus: 400}), &godiErr))
+		assert.Equal(t, RequiredArgType, godiErr.Type())
+
+		assert.True(t, errors.As(Register(Definition{Type: "OK", HTTPStatus: 200}), &godiErr))
+		assert.Equal(t, InvalidArgType, godiErr.Type())
+	})
+
+	t.Run("unknown types", func(t *testing.T) {
+		err := NewType("UNKNOWN_TYPE", "something broke", nil)
+
+		assert.Equal(t, 500, err.Code())
+		assert.Equal(t, InternalType, err.Type())
+		assert.Equal(t, "something broke", err.Message())
+		assert.Equal(t, GRPCUnknown, New(500, "UNKNOWN_TYPE", "", nil).GRPCCode())
+	})
+
+	t.Run("definitions are ordered", func(t *testing.T) {
+		defs := Definitions()
+
+		for i := 1; i < len(defs); i++ {
+			assert.LessOrEqual(t, defs[i-1].HTTPStatus, defs[i].HTTPStatus)
+		}
+	})
+}
@@ -10,23 +10,185 @@ const (
 	RequiredArgType string = "REQUIRED_ARGUMENT"
 	// InvalidArgType is the constant error "type" for invalid arguments
 	InvalidArgType string = "INVALID_ARGUMENT"
+	// NotFoundType is the constant error "type" for resources that do not exist
+	NotFoundType string = "NOT_FOUND"
+	// ConflictType is the constant error "type" for requests conflicting
+	// with the current state of a resource e.g. duplicates
+	ConflictType string = "CONFLICT"
+	// UnauthenticatedType is the constant error "type" for requests
+	// without valid credentials
+	UnauthenticatedType string = "UNAUTHENTICATED"
+	// PermissionDeniedType is the constant error "type" for authenticated
+	// requests not allowed to perform the operation
+	PermissionDeniedType string = "PERMISSION_DENIED"
+	// PreconditionFailedType is the constant error "type" for requests
+	// with conditions that do not hold e.g. a stale If-Match
+	PreconditionFailedType string = "PRECONDITION_FAILED"
+	// UnprocessableType is the constant error "type" for well-formed
+	// requests that cannot be processed e.g. failing business rules
+	UnprocessableType string = "UNPROCESSABLE"
+	// NotAcceptableType is the constant error "type" for responses that cannot
+	// be encoded in any of the media types the client accepts
+	NotAcceptableType string = "NOT_ACCEPTABLE"
+	// TimeoutType is the constant error "type" for requests that exceeded their deadline
+	TimeoutType string = "TIMEOUT"
+	// RateLimitedType is the constant error "type" for clients exceeding the rate limit
+	RateLimitedType string = "RATE_LIMITED"
+	// UnavailableType is the constant error "type" for requests that could not be served
+	UnavailableType string = "UNAVAILABLE"
+	// InternalType is the constant error "type" for unexpected errors
+	InternalType string = "INTERNAL"
+	// PayloadTooLargeType is the constant error "type" for request bodies over the size limit
+	PayloadTooLargeType string = "PAYLOAD_TOO_LARGE"
+	// UnsupportedEncodingType is the constant error "type" for request bodies
+	// with a content encoding the server cannot decode
+	UnsupportedEncodingType string = "UNSUPPORTED_ENCODING"
 
 	// RequiredArgMsg is the constant extended error "message" for required arguments
 	RequiredArgMsg string = "missing required argument(s)"
 	// InvalidArgMsg is the constant extended error "message" for invalid arguments
 	InvalidArgMsg string = "invalid argument(s) passed in"
+	// NotFoundMsg is the constant extended error "message" for resources that do not exist
+	NotFoundMsg string = "resource(s) not found"
+	// ConflictMsg is the constant extended error "message" for conflicts
+	ConflictMsg string = "conflict with the current state of resource(s)"
+	// UnauthenticatedMsg is the constant extended error "message" for unauthenticated requests
+	UnauthenticatedMsg string = "authentication required"
+	// PermissionDeniedMsg is the constant extended error "message" for denied requests
+	PermissionDeniedMsg string = "permission denied"
+	// PreconditionFailedMsg is the constant extended error "message" for failed preconditions
+	PreconditionFailedMsg string = "precondition(s) failed"
+	// UnprocessableMsg is the constant extended error "message" for unprocessable requests
+	UnprocessableMsg string = "unable to process argument(s)"
+	// NotAcceptableMsg is the constant extended error "message" for not acceptable responses
+	NotAcceptableMsg string = "no acceptable media type, available"
+	// TimeoutMsg is the constant extended error "message" for timeouts
+	TimeoutMsg string = "request timed out"
+	// RateLimitedMsg is the constant extended error "message" for rate limited clients
+	RateLimitedMsg string = "too many requests"
+	// UnavailableMsg is the constant extended error "message" for unavailable services
+	UnavailableMsg string = "service unavailable"
+	// InternalMsg is the constant extended error "message" for unexpected errors
+	InternalMsg string = "internal server error"
+	// PayloadTooLargeMsg is the constant extended error "message" for request bodies over the size limit
+	PayloadTooLargeMsg string = "request body too large, limit"
+	// UnsupportedEncodingMsg is the constant extended error "message" for unsupported content encodings
+	UnsupportedEncodingMsg string = "unsupported content encoding"
 )
 
 // RequiredArgsError forms standardised required arguments
 // error type. Takes a list of arguments
 func RequiredArgsError(args ...string) *Error {
 	msg := fmt.Sprintf("%s: %s", RequiredArgMsg, strings.Join(args, ", "))
-	return New(400, RequiredArgType, msg, nil)
+	return newSkip(1, 400, RequiredArgType, msg, nil).WithMessageKey(RequiredArgType, map[string]interface{}{
+		"args": strings.Join(args, ", "),
+	})
 }
 
 // InvalidArgsError forms standardised invalid arguments
 // error type. Takes a list of arguments
 func InvalidArgsError(args ...string) *Error {
 	msg := fmt.Sprintf("%s: %s", InvalidArgMsg, strings.Join(args, ", "))
-	return New(400, InvalidArgType, msg, nil)
+	return newSkip(1, 400, InvalidArgType, msg, nil).WithMessageKey(InvalidArgType, map[string]interface{}{
+		"args": strings.Join(args, ", "),
+	})
+}
+
+// NotFoundError forms standardised not found
+// error type. Takes a list of resources
+func NotFoundError(resources ...string) *Error {
+	msg := fmt.Sprintf("%s: %s", NotFoundMsg, strings.Join(resources, ", "))
+	return newSkip(1, 404, NotFoundType, msg, nil).WithMessageKey(NotFoundType, map[string]interface{}{
+		"resources": strings.Join(resources, ", "),
+	})
+}
+
+// ConflictError forms standardised conflict
+// error type. Takes a list of resources
+func ConflictError(resources ...string) *Error {
+	msg := fmt.Sprintf("%s: %s", ConflictMsg, strings.Join(resources, ", "))
+	return newSkip(1, 409, ConflictType, msg, nil).WithMessageKey(ConflictType, map[string]interface{}{
+		"resources": strings.Join(resources, ", "),
+	})
+}
+
+// UnauthenticatedError forms standardised unauthenticated
+// error type. Takes the original error, if any
+func UnauthenticatedError(err error) *Error {
+	return newSkip(1, 401, UnauthenticatedType, UnauthenticatedMsg, err).WithMessageKey(UnauthenticatedType, nil)
+}
+
+// PermissionDeniedError forms standardised permission
+// denied error type. Takes the original error, if any
+func PermissionDeniedError(err error) *Error {
+	return newSkip(1, 403, PermissionDeniedType, PermissionDeniedMsg, err).WithMessageKey(PermissionDeniedType, nil)
+}
+
+// PreconditionFailedError forms standardised precondition
+// failed error type. Takes a list of the failed conditions
+func PreconditionFailedError(conditions ...string) *Error {
+	msg := fmt.Sprintf("%s: %s", PreconditionFailedMsg, strings.Join(conditions, ", "))
+	return newSkip(1, 412, PreconditionFailedType, msg, nil).WithMessageKey(PreconditionFailedType, map[string]interface{}{
+		"conditions": strings.Join(conditions, ", "),
+	})
+}
+
+// UnprocessableError forms standardised unprocessable
+// error type. Takes a list of arguments
+func UnprocessableError(args ...string) *Error {
+	msg := fmt.Sprintf("%s: %s", UnprocessableMsg, strings.Join(args, ", "))
+	return newSkip(1, 422, UnprocessableType, msg, nil).WithMessageKey(UnprocessableType, map[string]interface{}{
+		"args": strings.Join(args, ", "),
+	})
+}
+
+// InternalError forms standardised internal
+// error type. Takes the original error, if any
+func InternalError(err error) *Error {
+	return newSkip(1, 500, InternalType, InternalMsg, err).WithMessageKey(InternalType, nil)
+}
+
+// NotAcceptableError forms standardised not acceptable
+// error type. Takes the list of media types available
+func NotAcceptableError(mediaTypes ...string) *Error {
+	msg := fmt.Sprintf("%s: %s", NotAcceptableMsg, strings.Join(mediaTypes, ", "))
+	return newSkip(1, 406, NotAcceptableType, msg, nil).WithMessageKey(NotAcceptableType, map[string]interface{}{
+		"mediaTypes": strings.Join(mediaTypes, ", "),
+	})
+}
+
+// TimeoutError forms standardised timeout
+// error type. Takes the original error, if any
+func TimeoutError(err error) *Error {
+	return newSkip(1, 504, TimeoutType, TimeoutMsg, err).WithMessageKey(TimeoutType, nil)
+}
+
+// UnavailableError forms standardised unavailable
+// error type. Takes the original error, if any
+func UnavailableError(err error) *Error {
+	return newSkip(1, 503, UnavailableType, UnavailableMsg, err).WithMessageKey(UnavailableType, nil)
+}
+
+// RateLimitedError forms standardised rate limited
+// error type
+func RateLimitedError() *Error {
+	return newSkip(1, 429, RateLimitedType, RateLimitedMsg, nil).WithMessageKey(RateLimitedType, nil)
+}
+
+// PayloadTooLargeError forms standardised payload too large
+// error type. Takes the size limit in bytes
+func PayloadTooLargeError(limit int64) *Error {
+	msg := fmt.Sprintf("%s: %d bytes", PayloadTooLargeMsg, limit)
+	return newSkip(1, 413, PayloadTooLargeType, msg, nil).WithMessageKey(PayloadTooLargeType, map[string]interface{}{
+		"limit": limit,
+	})
+}
+
+// UnsupportedEncodingError forms standardised unsupported
+// encoding error type. Takes the content encoding of the request
+func UnsupportedEncodingError(encoding string) *Error {
+	msg := fmt.Sprintf("%s: %s", UnsupportedEncodingMsg, encoding)
+	return newSkip(1, 415, UnsupportedEncodingType, msg, nil).WithMessageKey(UnsupportedEncodingType, map[string]interface{}{
+		"encoding": encoding,
+	})
 }
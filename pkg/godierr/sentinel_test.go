@@ -1,6 +1,7 @@
 package godierr
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -22,4 +23,75 @@ func TestSentinelErrors(t *testing.T) {
 		assert.Equal(t, InvalidArgType, err.Type())
 		assert.Contains(t, err.Error(), InvalidArgMsg)
 	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		err := NotAcceptableError("application/json", "application/xml")
+
+		assert.Equal(t, 406, err.Code())
+		assert.Equal(t, NotAcceptableType, err.Type())
+		assert.Equal(t, "no acceptable media type, available: application/json, application/xml", err.Error())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		err := TimeoutError(context.DeadlineExceeded)
+
+		assert.Equal(t, 504, err.Code())
+		assert.Equal(t, TimeoutType, err.Type())
+		assert.Equal(t, "request timed out due to context deadline exceeded", err.Error())
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		err := UnavailableError(nil)
+
+		assert.Equal(t, 503, err.Code())
+		assert.Equal(t, UnavailableType, err.Type())
+		assert.Equal(t, UnavailableMsg, err.Error())
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		err := RateLimitedError()
+
+		assert.Equal(t, 429, err.Code())
+		assert.Equal(t, RateLimitedType, err.Type())
+		assert.Equal(t, RateLimitedMsg, err.Error())
+	})
+
+	t.Run("payload too large", func(t *testing.T) {
+		err := PayloadTooLargeError(1024)
+
+		assert.Equal(t, 413, err.Code())
+		assert.Equal(t, PayloadTooLargeType, err.Type())
+		assert.Equal(t, "request body too large, limit: 1024 bytes", err.Error())
+	})
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		err := UnsupportedEncodingError("compress")
+
+		assert.Equal(t, 415, err.Code())
+		assert.Equal(t, UnsupportedEncodingType, err.Type())
+		assert.Equal(t, "unsupported content encoding: compress", err.Error())
+	})
+
+	t.Run("catalogue", func(t *testing.T) {
+		cases := []struct {
+			err  *Error
+			code int
+			t    string
+			msg  string
+		}{
+			{NotFoundError("user"), 404, NotFoundType, "resource(s) not found: user"},
+			{ConflictError("email"), 409, ConflictType, "conflict with the current state of resource(s): email"},
+			{UnauthenticatedError(nil), 401, UnauthenticatedType, UnauthenticatedMsg},
+			{PermissionDeniedError(nil), 403, PermissionDeniedType, PermissionDeniedMsg},
+			{PreconditionFailedError("If-Match"), 412, PreconditionFailedType, "precondition(s) failed: If-Match"},
+			{UnprocessableError("amount"), 422, UnprocessableType, "unable to process argument(s): amount"},
+			{InternalError(nil), 500, InternalType, InternalMsg},
+		}
+
+		for _, c := range cases {
+			assert.Equal(t, c.code, c.err.Code(), c.t)
+			assert.Equal(t, c.t, c.err.Type())
+			assert.Equal(t, c.msg, c.err.Error())
+		}
+	})
 }
@@ -0,0 +1,146 @@
+// Package i18n translates client facing messages
+// using message catalogs loaded from files
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+const (
+	// DefaultLanguage is the language used when none
+	// of the languages a client accepts are available
+	DefaultLanguage string = "en"
+)
+
+// Catalogs holds the message catalogs of every language
+type Catalogs struct {
+	fallback string
+	messages map[string]map[string]string
+}
+
+// Load reads the message catalogs in the root of the file system.
+// Each JSON file holds the messages of the language it is named after,
+// e.g. bn.json, keyed by message key. Messages can have placeholders
+// e.g. {resources} filled in from the arguments of the message.
+// Fallback is the language used when a client accepts none of the
+// available ones. Defaults to DefaultLanguage
+func Load(fsys fs.FS, fallback string) (*Catalogs, error) {
+	if fallback == "" {
+		fallback = DefaultLanguage
+	}
+
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalogs{
+		fallback: normalize(fallback),
+		messages: make(map[string]map[string]string, len(files)),
+	}
+
+	for _, file := range files {
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		var messages map[string]string
+		if err := json.Unmarshal(b, &messages); err != nil {
+			return nil, fmt.Errorf("unable to read catalog %s: %v", file, err)
+		}
+		c.messages[normalize(strings.TrimSuffix(file, path.Ext(file)))] = messages
+	}
+
+	return c, nil
+}
+
+// Languages returns the languages with a catalog
+func (c *Catalogs) Languages() []string {
+	languages := make([]string, 0, len(c.messages))
+	for lang := range c.messages {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Match returns the available language the client prefers
+// based on the Accept-Language header. A language matches
+// regional variants e.g. bn matches bn-BD
+func (c *Catalogs) Match(acceptLanguage string) string {
+	type languageRange struct {
+		tag string
+		q   float64
+	}
+
+	var ranges []languageRange
+	for _, qv := range util.ParseQualityList(acceptLanguage) {
+		if qv.Q > 0 {
+			ranges = append(ranges, languageRange{tag: normalize(qv.Value), q: qv.Q})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	for _, lr := range ranges {
+		if lr.tag == "*" {
+			return c.fallback
+		}
+		if _, ok := c.messages[lr.tag]; ok {
+			return lr.tag
+		}
+
+		base := strings.SplitN(lr.tag, "-", 2)[0]
+		if _, ok := c.messages[base]; ok {
+			return base
+		}
+	}
+
+	return c.fallback
+}
+
+// Fallback returns the language used when a
+// client accepts none of the available ones
+func (c *Catalogs) Fallback() string {
+	return c.fallback
+}
+
+// Translate returns the message for the key in the language, with
+// its placeholders filled in from the arguments. Reports false
+// when the catalog of the language does not have the message
+func (c *Catalogs) Translate(lang, key string, args map[string]interface{}) (string, bool) {
+	msg, ok := c.messages[normalize(lang)][key]
+	if !ok {
+		return "", false
+	}
+
+	return Format(msg, args), true
+}
+
+// Format fills in the placeholders of the message
+// e.g. {resources} with the values of the arguments
+func Format(msg string, args map[string]interface{}) string {
+	if len(args) == 0 {
+		return msg
+	}
+
+	replacements := make([]string, 0, len(args)*2)
+	for name, value := range args {
+		replacements = append(replacements, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(replacements...).Replace(msg)
+}
+
+func normalize(tag string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(tag), "_", "-", -1))
+}
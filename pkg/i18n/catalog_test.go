@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogs(t *testing.T) {
+	files := fstest.MapFS{
+		"en.json":    {Data: []byte(`{"NOT_FOUND": "resource(s) not found: {resources}", "INTERNAL": "internal server error"}`)},
+		"bn.json":    {Data: []byte(`{"NOT_FOUND": "খুঁজে পাওয়া যায়নি: {resources}"}`)},
+		"pt_BR.json": {Data: []byte(`{"NOT_FOUND": "não encontrado: {resources}"}`)},
+		"README.md":  {Data: []byte(`not a catalog`)},
+	}
+
+	catalogs, err := Load(files, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bn", "en", "pt-br"}, catalogs.Languages())
+	assert.Equal(t, DefaultLanguage, catalogs.Fallback())
+
+	t.Run("match", func(t *testing.T) {
+		cases := []struct {
+			name           string
+			acceptLanguage string
+			lang           string
+		}{
+			{"no header", "", "en"},
+			{"exact", "bn", "bn"},
+			{"regional variant", "bn-BD", "bn"},
+			{"case insensitive", "PT-br", "pt-br"},
+			{"quality values", "en;q=0.5, bn-BD;q=0.9", "bn"},
+			{"unavailable skipped", "fr-FR, fr;q=0.9, bn;q=0.8", "bn"},
+			{"rejected", "bn;q=0, fr", "en"},
+			{"wildcard", "fr, *;q=0.5", "en"},
+			{"unavailable", "fr-FR", "en"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				assert.Equal(t, c.lang, catalogs.Match(c.acceptLanguage))
+			})
+		}
+	})
+
+	t.Run("translate", func(t *testing.T) {
+		msg, ok := catalogs.Translate("bn", "NOT_FOUND", map[string]interface{}{"resources": "user"})
+		assert.True(t, ok)
+		assert.Equal(t, "খুঁজে পাওয়া যায়নি: user", msg)
+
+		_, ok = catalogs.Translate("bn", "INTERNAL", nil)
+		assert.False(t, ok)
+
+		_, ok = catalogs.Translate("fr", "NOT_FOUND", nil)
+		assert.False(t, ok)
+	})
+
+	t.Run("invalid catalog", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{"en.json": {Data: []byte(`["not", "a", "map"]`)}}, "en")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "limit: 1024 bytes", Format("limit: {limit} bytes", map[string]interface{}{"limit": int64(1024)}))
+	assert.Equal(t, "missing: {args}", Format("missing: {args}", nil))
+	assert.Equal(t, "a, b and {c}", Format("{a}, {b} and {c}", map[string]interface{}{"a": "a", "b": "b"}))
+}
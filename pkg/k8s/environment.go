@@ -0,0 +1,137 @@
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const header string = "# Code generated by api manifests. DO NOT EDIT.\n"
+
+// LoadEnvironment applies the options of the YAML file on top of
+// the base e.g. deploy/environments/dev.yml on top of base.yml.
+// Maps are merged, other options set in the file replace the base
+func LoadEnvironment(path string, base Options) (Options, error) {
+	opts := base
+	opts.Env = copyMap(base.Env)
+	opts.Labels = copyMap(base.Labels)
+	opts.Resources = Resources{
+		Requests: copyMap(base.Resources.Requests),
+		Limits:   copyMap(base.Resources.Limits),
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return base, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&opts); err != nil && err != io.EOF {
+		return base, fmt.Errorf("k8s: %s: %w", path, err)
+	}
+	return opts, nil
+}
+
+// Write writes the manifests as a YAML stream
+func Write(w io.Writer, manifests []Manifest) error {
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	for i, m := range manifests {
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if err := encode(w, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteDir writes a file per manifest to the directory, named after
+// its kind e.g. config-map.yml, and a kustomization.yml listing them.
+// Returns the paths of the files written
+func WriteDir(dir string, manifests []Manifest) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	written := []string{}
+	resources := []string{}
+	for _, m := range manifests {
+		name := kebabCase(m.Kind) + ".yml"
+		if err := writeFile(filepath.Join(dir, name), m); err != nil {
+			return written, err
+		}
+		written = append(written, filepath.Join(dir, name))
+		resources = append(resources, name)
+	}
+
+	kustomization := struct {
+		APIVersion string   `yaml:"apiVersion"`
+		Kind       string   `yaml:"kind"`
+		Resources  []string `yaml:"resources"`
+	}{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  resources,
+	}
+	name := filepath.Join(dir, "kustomization.yml")
+	if err := writeFile(name, kustomization); err != nil {
+		return written, err
+	}
+	return append(written, name), nil
+}
+
+func writeFile(name string, v interface{}) error {
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	if err := encode(&buf, v); err != nil {
+		return err
+	}
+	return os.WriteFile(name, buf.Bytes(), 0644)
+}
+
+func encode(w io.Writer, v interface{}) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// kebabCase turns a kind into a file name e.g. ConfigMap into config-map
+func kebabCase(kind string) string {
+	var b strings.Builder
+	for i, r := range kind {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	cp := make(map[string]string, len(m))
+	for k, v := range m {
+		cp[k] = v
+	}
+	return cp
+}
@@ -0,0 +1,85 @@
+package k8s
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server"
+)
+
+func TestLoadEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dev.yml")
+	err := ioutil.WriteFile(path, []byte(`
+name: dev-orders
+replicas: 1
+env:
+  DEBUG: "true"
+resources:
+  limits:
+    memory: 1G
+`), 0644)
+	assert.Nil(t, err)
+
+	base := Options{
+		Name:      "orders",
+		Image:     "acme/orders:latest",
+		Replicas:  3,
+		Env:       map[string]string{"REDIS_ADDR": "redis:6379"},
+		Resources: Resources{Limits: map[string]string{"cpu": "500m", "memory": "512M"}},
+	}
+	opts, err := LoadEnvironment(path, base)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "dev-orders", opts.Name)
+	assert.Equal(t, "acme/orders:latest", opts.Image)
+	assert.Equal(t, 1, opts.Replicas)
+	assert.Equal(t, map[string]string{"REDIS_ADDR": "redis:6379", "DEBUG": "true"}, opts.Env)
+	assert.Equal(t, map[string]string{"cpu": "500m", "memory": "1G"}, opts.Resources.Limits)
+	// the base is left as is
+	assert.Equal(t, map[string]string{"REDIS_ADDR": "redis:6379"}, base.Env)
+	assert.Equal(t, "512M", base.Resources.Limits["memory"])
+
+	t.Run("unknown option", func(t *testing.T) {
+		invalid := filepath.Join(dir, "invalid.yml")
+		assert.Nil(t, ioutil.WriteFile(invalid, []byte("replica: 1\n"), 0644))
+
+		_, err := LoadEnvironment(invalid, base)
+		assert.Contains(t, err.Error(), "field replica not found")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadEnvironment(filepath.Join(dir, "missing.yml"), base)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestWriteDir(t *testing.T) {
+	manifests, err := Generate(&server.Config{Port: "3001", Timeout: 30}, testRoutes, Options{Name: "orders", Image: "orders"})
+	assert.Nil(t, err)
+
+	dir := filepath.Join(t.TempDir(), "base")
+	written, err := WriteDir(dir, manifests)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "config-map.yml"),
+		filepath.Join(dir, "deployment.yml"),
+		filepath.Join(dir, "service.yml"),
+		filepath.Join(dir, "pod-disruption-budget.yml"),
+		filepath.Join(dir, "kustomization.yml"),
+	}, written)
+
+	kustomization, err := ioutil.ReadFile(filepath.Join(dir, "kustomization.yml"))
+	assert.Nil(t, err)
+	assert.Equal(t, header+`apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- config-map.yml
+- deployment.yml
+- service.yml
+- pod-disruption-budget.yml
+`, string(kustomization))
+}
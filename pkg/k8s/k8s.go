@@ -0,0 +1,365 @@
+// Package k8s renders the Kubernetes manifests of a server from its
+// effective configuration, so ports, probes and shutdown timeouts
+// never drift from the code. Environments override the options
+// of the manifests using YAML files
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server"
+)
+
+const (
+	// ConfigFileName is the name of the config file
+	// mounted from the ConfigMap
+	ConfigFileName string = "config.json"
+
+	// time given to the server on top of its shutdown
+	// timeout before the pod is killed
+	shutdownMargin time.Duration = 5 * time.Second
+	healthRoute    string        = "health"
+	portName       string        = "http"
+)
+
+// Options configures the manifests. Zero values use the defaults
+//
+// Name (required) - name of the resources and of the container
+// Namespace - namespace of the resources
+// Environment - set as the ENV variable of the container. Defaults to base
+// Image (required) - image of the container
+// ImagePullPolicy - defaults to IfNotPresent
+// Replicas - replicas of the deployment, and the minimum of the autoscaler. Defaults to 2
+// MaxReplicas - maximum replicas of the autoscaler. No autoscaler is rendered when
+// not above the replicas
+// TargetCPUUtilization - average CPU utilization, as a percentage of the
+// request, the autoscaler targets. Defaults to 80
+// MaxUnavailable - pods the disruption budget allows to be down. Defaults to 1
+// Resources - requests and limits of the container
+// Env - environment variables of the container
+// Labels - added to the labels of the resources
+// ConfigFile - applied on top of the server config of the environment
+type Options struct {
+	Name                 string            `yaml:"name"`
+	Namespace            string            `yaml:"namespace"`
+	Environment          string            `yaml:"environment"`
+	Image                string            `yaml:"image"`
+	ImagePullPolicy      string            `yaml:"imagePullPolicy"`
+	Replicas             int               `yaml:"replicas"`
+	MaxReplicas          int               `yaml:"maxReplicas"`
+	TargetCPUUtilization int               `yaml:"targetCPUUtilization"`
+	MaxUnavailable       int               `yaml:"maxUnavailable"`
+	Resources            Resources         `yaml:"resources"`
+	Env                  map[string]string `yaml:"env"`
+	Labels               map[string]string `yaml:"labels"`
+	ConfigFile           string            `yaml:"configFile"`
+}
+
+// Resources are the requests and limits of a container
+// e.g. {requests: {cpu: 250m}, limits: {memory: 512M}}
+type Resources struct {
+	Requests map[string]string `yaml:"requests,omitempty"`
+	Limits   map[string]string `yaml:"limits,omitempty"`
+}
+
+// Manifest is a Kubernetes resource
+type Manifest struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   Metadata          `yaml:"metadata"`
+	Spec       interface{}       `yaml:"spec,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+}
+
+// Metadata identifies a resource
+type Metadata struct {
+	Name      string            `yaml:"name,omitempty"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type (
+	selector struct {
+		MatchLabels map[string]string `yaml:"matchLabels"`
+	}
+	deploymentSpec struct {
+		Replicas int         `yaml:"replicas"`
+		Selector selector    `yaml:"selector"`
+		Strategy strategy    `yaml:"strategy"`
+		Template podTemplate `yaml:"template"`
+	}
+	strategy struct {
+		Type          string        `yaml:"type"`
+		RollingUpdate rollingUpdate `yaml:"rollingUpdate"`
+	}
+	rollingUpdate struct {
+		MaxSurge       int `yaml:"maxSurge"`
+		MaxUnavailable int `yaml:"maxUnavailable"`
+	}
+	podTemplate struct {
+		Metadata Metadata `yaml:"metadata"`
+		Spec     podSpec  `yaml:"spec"`
+	}
+	podSpec struct {
+		TerminationGracePeriodSeconds int64       `yaml:"terminationGracePeriodSeconds"`
+		Containers                    []container `yaml:"containers"`
+		Volumes                       []volume    `yaml:"volumes"`
+	}
+	container struct {
+		Name            string          `yaml:"name"`
+		Image           string          `yaml:"image"`
+		ImagePullPolicy string          `yaml:"imagePullPolicy"`
+		Ports           []containerPort `yaml:"ports"`
+		Env             []envVar        `yaml:"env"`
+		Resources       Resources       `yaml:"resources,omitempty"`
+		LivenessProbe   probe           `yaml:"livenessProbe"`
+		ReadinessProbe  probe           `yaml:"readinessProbe"`
+		VolumeMounts    []volumeMount   `yaml:"volumeMounts"`
+	}
+	containerPort struct {
+		Name          string `yaml:"name"`
+		ContainerPort int    `yaml:"containerPort"`
+	}
+	envVar struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	}
+	probe struct {
+		HTTPGet             httpGet `yaml:"httpGet"`
+		InitialDelaySeconds int     `yaml:"initialDelaySeconds"`
+		PeriodSeconds       int     `yaml:"periodSeconds"`
+		TimeoutSeconds      int     `yaml:"timeoutSeconds"`
+	}
+	httpGet struct {
+		Path string `yaml:"path"`
+		Port string `yaml:"port"`
+	}
+	volume struct {
+		Name      string          `yaml:"name"`
+		ConfigMap configMapSource `yaml:"configMap"`
+	}
+	configMapSource struct {
+		Name string `yaml:"name"`
+	}
+	volumeMount struct {
+		Name      string `yaml:"name"`
+		MountPath string `yaml:"mountPath"`
+		ReadOnly  bool   `yaml:"readOnly"`
+	}
+	serviceSpec struct {
+		Selector map[string]string `yaml:"selector"`
+		Ports    []servicePort     `yaml:"ports"`
+	}
+	servicePort struct {
+		Name       string `yaml:"name"`
+		Port       int    `yaml:"port"`
+		TargetPort string `yaml:"targetPort"`
+		Protocol   string `yaml:"protocol"`
+	}
+	autoscalerSpec struct {
+		ScaleTargetRef scaleTarget `yaml:"scaleTargetRef"`
+		MinReplicas    int         `yaml:"minReplicas"`
+		MaxReplicas    int         `yaml:"maxReplicas"`
+		Metrics        []metric    `yaml:"metrics"`
+	}
+	scaleTarget struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Name       string `yaml:"name"`
+	}
+	metric struct {
+		Type     string         `yaml:"type"`
+		Resource resourceMetric `yaml:"resource"`
+	}
+	resourceMetric struct {
+		Name   string       `yaml:"name"`
+		Target metricTarget `yaml:"target"`
+	}
+	metricTarget struct {
+		Type               string `yaml:"type"`
+		AverageUtilization int    `yaml:"averageUtilization"`
+	}
+	disruptionBudgetSpec struct {
+		MaxUnavailable int      `yaml:"maxUnavailable"`
+		Selector       selector `yaml:"selector"`
+	}
+)
+
+// Generate returns the ConfigMap holding the effective config,
+// and the Deployment, Service, HorizontalPodAutoscaler and
+// PodDisruptionBudget of the server. Probes use the health
+// route of the routes e.g. from server.Server.Routes
+func Generate(cfg *server.Config, routes []server.RouteInfo, opts Options) ([]Manifest, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("k8s: name is required")
+	}
+	if opts.Image == "" {
+		return nil, fmt.Errorf("k8s: image is required")
+	}
+	if opts.Environment == "" {
+		opts.Environment = "base"
+	}
+	if opts.ImagePullPolicy == "" {
+		opts.ImagePullPolicy = "IfNotPresent"
+	}
+	if opts.Replicas == 0 {
+		opts.Replicas = 2
+	}
+	if opts.TargetCPUUtilization == 0 {
+		opts.TargetCPUUtilization = 80
+	}
+	if opts.MaxUnavailable == 0 {
+		opts.MaxUnavailable = 1
+	}
+
+	effective := cfg.Effective()
+	port, err := strconv.Atoi(effective.Port)
+	if err != nil {
+		return nil, fmt.Errorf("k8s: invalid port %q", effective.Port)
+	}
+
+	healthPath := ""
+	for _, route := range routes {
+		if route.Name == healthRoute {
+			healthPath = route.Path
+		}
+	}
+	if healthPath == "" {
+		return nil, fmt.Errorf("k8s: no %s route to probe", healthRoute)
+	}
+
+	config, err := json.MarshalIndent(effective, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	selectorLabels := map[string]string{"run": opts.Name}
+	labels := map[string]string{}
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+	for k, v := range selectorLabels {
+		labels[k] = v
+	}
+	metadata := func(name string) Metadata {
+		return Metadata{Name: name, Namespace: opts.Namespace, Labels: labels}
+	}
+
+	configName := opts.Name + "-config"
+	configDir := "/etc/" + opts.Name
+	env := []envVar{
+		{Name: "ENV", Value: opts.Environment},
+		{Name: "CONFIG_FILE", Value: configDir + "/" + ConfigFileName},
+	}
+	names := make([]string, 0, len(opts.Env))
+	for name := range opts.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, envVar{Name: name, Value: opts.Env[name]})
+	}
+
+	healthProbe := probe{
+		HTTPGet:             httpGet{Path: healthPath, Port: portName},
+		InitialDelaySeconds: 5,
+		PeriodSeconds:       5,
+		TimeoutSeconds:      5,
+	}
+
+	manifests := []Manifest{
+		{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+			Metadata:   metadata(configName),
+			Data:       map[string]string{ConfigFileName: string(config) + "\n"},
+		},
+		{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Metadata:   metadata(opts.Name),
+			Spec: deploymentSpec{
+				Replicas: opts.Replicas,
+				Selector: selector{MatchLabels: selectorLabels},
+				Strategy: strategy{
+					Type:          "RollingUpdate",
+					RollingUpdate: rollingUpdate{MaxSurge: 1, MaxUnavailable: opts.MaxUnavailable},
+				},
+				Template: podTemplate{
+					Metadata: Metadata{Labels: labels},
+					Spec: podSpec{
+						// the server finishes the requests in flight before being killed
+						TerminationGracePeriodSeconds: int64(seconds(effective.ShutdownTimeout + shutdownMargin)),
+						Containers: []container{
+							{
+								Name:            opts.Name,
+								Image:           opts.Image,
+								ImagePullPolicy: opts.ImagePullPolicy,
+								Ports:           []containerPort{{Name: portName, ContainerPort: port}},
+								Env:             env,
+								Resources:       opts.Resources,
+								LivenessProbe:   healthProbe,
+								ReadinessProbe:  healthProbe,
+								VolumeMounts:    []volumeMount{{Name: "config", MountPath: configDir, ReadOnly: true}},
+							},
+						},
+						Volumes: []volume{{Name: "config", ConfigMap: configMapSource{Name: configName}}},
+					},
+				},
+			},
+		},
+		{
+			APIVersion: "v1",
+			Kind:       "Service",
+			Metadata:   metadata(opts.Name),
+			Spec: serviceSpec{
+				Selector: selectorLabels,
+				Ports:    []servicePort{{Name: portName, Port: port, TargetPort: portName, Protocol: "TCP"}},
+			},
+		},
+	}
+
+	if opts.MaxReplicas > opts.Replicas {
+		manifests = append(manifests, Manifest{
+			APIVersion: "autoscaling/v2",
+			Kind:       "HorizontalPodAutoscaler",
+			Metadata:   metadata(opts.Name),
+			Spec: autoscalerSpec{
+				ScaleTargetRef: scaleTarget{APIVersion: "apps/v1", Kind: "Deployment", Name: opts.Name},
+				MinReplicas:    opts.Replicas,
+				MaxReplicas:    opts.MaxReplicas,
+				Metrics: []metric{
+					{
+						Type: "Resource",
+						Resource: resourceMetric{
+							Name:   "cpu",
+							Target: metricTarget{Type: "Utilization", AverageUtilization: opts.TargetCPUUtilization},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	manifests = append(manifests, Manifest{
+		APIVersion: "policy/v1",
+		Kind:       "PodDisruptionBudget",
+		Metadata:   metadata(opts.Name),
+		Spec: disruptionBudgetSpec{
+			MaxUnavailable: opts.MaxUnavailable,
+			Selector:       selector{MatchLabels: selectorLabels},
+		},
+	})
+
+	return manifests, nil
+}
+
+// seconds rounds the duration up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,123 @@
+package k8s
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server"
+)
+
+var testRoutes = []server.RouteInfo{
+	{Name: "health", Method: http.MethodGet, Path: "/healthz"},
+}
+
+func TestGenerate(t *testing.T) {
+	cfg := &server.Config{
+		Port:            "8080",
+		Timeout:         30,
+		ShutdownTimeout: 12500 * time.Millisecond,
+	}
+
+	manifests, err := Generate(cfg, testRoutes, Options{
+		Name:        "orders",
+		Namespace:   "shop",
+		Image:       "acme/orders:1.0.0",
+		MaxReplicas: 6,
+		Env:         map[string]string{"REDIS_ADDR": "redis:6379", "DEBUG": "false"},
+		Labels:      map[string]string{"team": "checkout"},
+	})
+	assert.Nil(t, err)
+
+	kinds := []string{}
+	for _, m := range manifests {
+		kinds = append(kinds, m.Kind)
+		assert.Equal(t, "shop", m.Metadata.Namespace)
+		assert.Equal(t, map[string]string{"run": "orders", "team": "checkout"}, m.Metadata.Labels)
+	}
+	assert.Equal(t, []string{"ConfigMap", "Deployment", "Service", "HorizontalPodAutoscaler", "PodDisruptionBudget"}, kinds)
+
+	config := manifests[0].Data[ConfigFileName]
+	assert.Contains(t, config, `"port": "8080"`)
+	assert.Contains(t, config, `"shutdownTimeout": "12.5s"`)
+	assert.Contains(t, config, `"readTimeout": "30s"`)
+
+	deployment := manifests[1].Spec.(deploymentSpec)
+	assert.Equal(t, 2, deployment.Replicas)
+	assert.Equal(t, map[string]string{"run": "orders"}, deployment.Selector.MatchLabels)
+
+	pod := deployment.Template.Spec
+	assert.Equal(t, int64(18), pod.TerminationGracePeriodSeconds)
+	assert.Equal(t, "orders-config", pod.Volumes[0].ConfigMap.Name)
+
+	c := pod.Containers[0]
+	assert.Equal(t, "acme/orders:1.0.0", c.Image)
+	assert.Equal(t, "IfNotPresent", c.ImagePullPolicy)
+	assert.Equal(t, []containerPort{{Name: "http", ContainerPort: 8080}}, c.Ports)
+	assert.Equal(t, "/healthz", c.LivenessProbe.HTTPGet.Path)
+	assert.Equal(t, "/healthz", c.ReadinessProbe.HTTPGet.Path)
+	assert.Equal(t, []envVar{
+		{Name: "ENV", Value: "base"},
+		{Name: "CONFIG_FILE", Value: "/etc/orders/config.json"},
+		{Name: "DEBUG", Value: "false"},
+		{Name: "REDIS_ADDR", Value: "redis:6379"},
+	}, c.Env)
+	assert.Equal(t, "/etc/orders", c.VolumeMounts[0].MountPath)
+
+	service := manifests[2].Spec.(serviceSpec)
+	assert.Equal(t, 8080, service.Ports[0].Port)
+
+	autoscaler := manifests[3].Spec.(autoscalerSpec)
+	assert.Equal(t, 2, autoscaler.MinReplicas)
+	assert.Equal(t, 6, autoscaler.MaxReplicas)
+	assert.Equal(t, 80, autoscaler.Metrics[0].Resource.Target.AverageUtilization)
+
+	t.Run("writes a YAML stream", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Nil(t, Write(&buf, manifests))
+		assert.True(t, strings.HasPrefix(buf.String(), header))
+
+		dec := yaml.NewDecoder(&buf)
+		count := 0
+		for {
+			var doc map[string]interface{}
+			if dec.Decode(&doc) != nil {
+				break
+			}
+			count++
+		}
+		assert.Equal(t, len(manifests), count)
+	})
+
+	t.Run("no autoscaler without max replicas", func(t *testing.T) {
+		manifests, err := Generate(cfg, testRoutes, Options{Name: "orders", Image: "orders", Replicas: 3, MaxReplicas: 3})
+		assert.Nil(t, err)
+		assert.Len(t, manifests, 4)
+		assert.Equal(t, "PodDisruptionBudget", manifests[3].Kind)
+	})
+
+	cases := []struct {
+		name   string
+		cfg    *server.Config
+		routes []server.RouteInfo
+		opts   Options
+		err    string
+	}{
+		{name: "missing name", cfg: cfg, routes: testRoutes, opts: Options{Image: "orders"}, err: "k8s: name is required"},
+		{name: "missing image", cfg: cfg, routes: testRoutes, opts: Options{Name: "orders"}, err: "k8s: image is required"},
+		{name: "invalid port", cfg: &server.Config{Port: ":8080"}, routes: testRoutes, opts: Options{Name: "orders", Image: "orders"}, err: `k8s: invalid port ":8080"`},
+		{name: "no health route", cfg: cfg, opts: Options{Name: "orders", Image: "orders"}, err: "k8s: no health route to probe"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Generate(c.cfg, c.routes, c.opts)
+			assert.EqualError(t, err, c.err)
+		})
+	}
+}
@@ -3,12 +3,25 @@ package logger
 import (
 	"os"
 	"strconv"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var logger = NewLogger()
+var (
+	// level overrides the DEBUG mode check when set through SetLevel
+	level    = zap.NewAtomicLevel()
+	levelSet int32
+
+	logger = NewLogger()
+	// current holds the *zap.SugaredLogger the functions log to
+	current atomic.Value
+)
+
+func init() {
+	current.Store(logger)
+}
 
 // NewLogger returns a new instance of zap sugar logger
 func NewLogger() *zap.SugaredLogger {
@@ -21,62 +34,96 @@ func NewLogger() *zap.SugaredLogger {
 // Debug aliases zap.Debugw to be able to log a message
 // with optional context
 func Debug(msg string, args ...interface{}) {
-	logger.Debugw(msg, args...)
+	sugared().Debugw(msg, args...)
 }
 
 // Info aliases zap.Infow to be able to log a message
 // with optional context
 func Info(msg string, args ...interface{}) {
-	logger.Infow(msg, args...)
+	sugared().Infow(msg, args...)
 }
 
 // Warn aliases zap.Warnw to be able to log a message
 // with optional context
 func Warn(msg string, args ...interface{}) {
-	logger.Warnw(msg, args...)
+	sugared().Warnw(msg, args...)
 }
 
 // Error aliases zap.Errorw to be able to log a message
 // with optional context
 func Error(msg string, args ...interface{}) {
-	logger.Errorw(msg, args...)
+	sugared().Errorw(msg, args...)
 }
 
 // Fatal aliases zap.Fatalw to be able to log a message
 // with optional context
 func Fatal(msg string, args ...interface{}) {
-	logger.Fatalw(msg, args...)
+	sugared().Fatalw(msg, args...)
 }
 
 // Debugf aliases zap.Debugf
 func Debugf(msg string, args ...interface{}) {
-	logger.Debugf(msg, args...)
+	sugared().Debugf(msg, args...)
 }
 
 // Infof aliases zap.Infof
 func Infof(msg string, args ...interface{}) {
-	logger.Infof(msg, args...)
+	sugared().Infof(msg, args...)
 }
 
 // Warnf aliases zap.Warnf
 func Warnf(msg string, args ...interface{}) {
-	logger.Warnf(msg, args...)
+	sugared().Warnf(msg, args...)
 }
 
 // Errorf aliases zap.Errorf
 func Errorf(msg string, args ...interface{}) {
-	logger.Errorf(msg, args...)
+	sugared().Errorf(msg, args...)
 }
 
 // Fatalf aliases zap.Fatalf
 func Fatalf(msg string, args ...interface{}) {
-	logger.Fatalf(msg, args)
+	sugared().Fatalf(msg, args)
+}
+
+// Replace swaps the logger the functions log to, e.g. for a logger
+// capturing the entries in tests, and returns a function restoring
+// the previous one
+func Replace(l *zap.SugaredLogger) func() {
+	previous := current.Load()
+	current.Store(l)
+	return func() {
+		current.Store(previous)
+	}
+}
+
+func sugared() *zap.SugaredLogger {
+	return current.Load().(*zap.SugaredLogger)
+}
+
+// SetLevel changes the minimum level logged at runtime
+// e.g. debug, info, warn, error. Takes precedence over DEBUG mode.
+// Passing in an empty level restores the DEBUG mode behaviour
+func SetLevel(lvl string) error {
+	if lvl == "" {
+		atomic.StoreInt32(&levelSet, 0)
+		return nil
+	}
+
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(lvl)); err != nil {
+		return err
+	}
+
+	level.SetLevel(l)
+	atomic.StoreInt32(&levelSet, 1)
+	return nil
 }
 
 func newZap() *zap.Logger {
 	// send anything above or equal to error level to stderr
 	highPriority := zap.LevelEnablerFunc(func(loggingLvl zapcore.Level) bool {
-		return loggingLvl >= zapcore.ErrorLevel
+		return loggingLvl >= zapcore.ErrorLevel && isLevelEnabled(loggingLvl)
 	})
 
 	// send everything less than error level to stdout
@@ -84,6 +131,10 @@ func newZap() *zap.Logger {
 	lowPriority := zap.LevelEnablerFunc(func(loggingLvl zapcore.Level) bool {
 		isLessThanErr := loggingLvl < zapcore.ErrorLevel
 
+		if atomic.LoadInt32(&levelSet) == 1 {
+			return isLessThanErr && level.Enabled(loggingLvl)
+		}
+
 		if isDebugMode() {
 			return isLessThanErr
 		}
@@ -123,6 +174,10 @@ func getEncoderCfg() zapcore.EncoderConfig {
 	}
 }
 
+func isLevelEnabled(lvl zapcore.Level) bool {
+	return atomic.LoadInt32(&levelSet) == 0 || level.Enabled(lvl)
+}
+
 func isDebugMode() bool {
 	mode := os.Getenv("DEBUG")
 	modeBool, _ := strconv.ParseBool(mode)
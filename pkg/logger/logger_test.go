@@ -5,6 +5,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestIsDebugMode(t *testing.T) {
@@ -39,3 +42,34 @@ func TestStdout(t *testing.T) {
 	// Output:
 	// {"level":"INFO","timestamp":"2020-10-27T17:52:44.121+0800","caller":"testing/testing.go:1123","message":"info will be logged"}
 }
+
+func TestSetLevel(t *testing.T) {
+	defer SetLevel("")
+
+	err := SetLevel("warn")
+	assert.Nil(t, err)
+	assert.False(t, isLevelEnabled(zapcore.InfoLevel))
+	assert.True(t, isLevelEnabled(zapcore.ErrorLevel))
+
+	err = SetLevel("loud")
+	assert.NotNil(t, err)
+	assert.False(t, isLevelEnabled(zapcore.InfoLevel))
+
+	err = SetLevel("")
+	assert.Nil(t, err)
+	assert.True(t, isLevelEnabled(zapcore.InfoLevel))
+}
+
+func TestReplace(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	restore := Replace(zap.New(core).Sugar())
+
+	Info("captured", "id", 1)
+	Debugf("captured %d", 2)
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, "captured 2", logs.All()[1].Message)
+
+	restore()
+	Info("not captured")
+	assert.Equal(t, 2, logs.Len())
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+const (
+	// DefaultMaxBodyBytes is the maximum size of
+	// a request body when not configured
+	DefaultMaxBodyBytes int64 = 1 << 20
+
+	// minimum memory allowed for zstd decoders, so bodies
+	// using the default window size of encoders can be read
+	zstdMinDecoderMemory int64 = 8 << 20
+)
+
+// BodyLimitOptions configures the request body limits
+// MaxBytes - maximum size of the request body as sent by the client.
+// Not limited when 0 or less
+// MaxDecompressedBytes - maximum size of a compressed request body
+// once decompressed. Defaults to MaxBytes, or to DefaultMaxBodyBytes
+// when the body is not limited, so decompression is always bounded
+type BodyLimitOptions struct {
+	MaxBytes             int64
+	MaxDecompressedBytes int64
+}
+
+// BodyLimit limits the size of request bodies and transparently
+// decompresses bodies sent with a gzip or zstd Content-Encoding.
+// Requests declaring a larger Content-Length are rejected straight away.
+// Otherwise reading past the limit returns a payload too large error
+// which handlers can return as it is
+func BodyLimit(opts BodyLimitOptions) func(http.Handler) http.Handler {
+	if opts.MaxDecompressedBytes <= 0 {
+		opts.MaxDecompressedBytes = opts.MaxBytes
+		if opts.MaxBytes <= 0 {
+			opts.MaxDecompressedBytes = DefaultMaxBodyBytes
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if opts.MaxBytes > 0 && r.ContentLength > opts.MaxBytes {
+				rejectBody(w, r, godierr.PayloadTooLargeError(opts.MaxBytes))
+				return
+			}
+
+			body := limitBody(r.Body, opts.MaxBytes)
+
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+			switch encoding {
+			case "", "identity":
+				r.Body = body
+				next.ServeHTTP(w, r)
+				return
+			case "gzip", "x-gzip", "zstd":
+			default:
+				body.Close()
+				rejectBody(w, r, godierr.UnsupportedEncodingError(encoding))
+				return
+			}
+
+			decoded, err := newBodyDecoder(encoding, body, opts.MaxDecompressedBytes)
+			if err != nil {
+				body.Close()
+				var godiErr *godierr.Error
+				if errors.As(err, &godiErr) {
+					rejectBody(w, r, godiErr)
+					return
+				}
+				rejectBody(w, r, godierr.InvalidArgsError("body"))
+				return
+			}
+
+			// handlers see the body as if it was sent uncompressed
+			r.Body = limitBody(decoded, opts.MaxDecompressedBytes)
+			r.ContentLength = -1
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func rejectBody(w http.ResponseWriter, r *http.Request, err *godierr.Error) {
+	// the rest of the body is not read, so do not reuse the connection
+	w.Header().Set("Connection", "close")
+	util.RespondErr(w, r, err)
+}
+
+// limitedBody returns a payload too large error
+// once more than limit bytes are read
+type limitedBody struct {
+	io.ReadCloser
+
+	limit     int64
+	remaining int64
+	err       error
+}
+
+func limitBody(body io.ReadCloser, limit int64) io.ReadCloser {
+	if limit <= 0 {
+		return body
+	}
+	return &limitedBody{
+		ReadCloser: body,
+		limit:      limit,
+		remaining:  limit,
+	}
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// read one byte past the limit to tell whether it is exceeded
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.ReadCloser.Read(p)
+	if int64(n) <= l.remaining {
+		l.remaining -= int64(n)
+		return n, err
+	}
+
+	n = int(l.remaining)
+	l.remaining = 0
+	l.err = godierr.PayloadTooLargeError(l.limit)
+	return n, l.err
+}
+
+// bodyDecoder decompresses a request body
+type bodyDecoder struct {
+	decoder  io.Reader
+	close    func()
+	body     io.ReadCloser
+	maxBytes int64
+}
+
+func newBodyDecoder(encoding string, body io.ReadCloser, maxBytes int64) (*bodyDecoder, error) {
+	if encoding == "zstd" {
+		opts := []zstd.DOption{zstd.WithDecoderConcurrency(1)}
+		if maxBytes > 0 {
+			// bounds the memory used for the window,
+			// the decoded size is limited by the caller
+			memory := maxBytes
+			if memory < zstdMinDecoderMemory {
+				memory = zstdMinDecoderMemory
+			}
+			opts = append(opts, zstd.WithDecoderMaxMemory(uint64(memory)))
+		}
+
+		decoder, err := zstd.NewReader(body, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &bodyDecoder{decoder: decoder, close: decoder.Close, body: body, maxBytes: maxBytes}, nil
+	}
+
+	decoder, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, err
+	}
+	return &bodyDecoder{decoder: decoder, close: func() { decoder.Close() }, body: body, maxBytes: maxBytes}, nil
+}
+
+func (d *bodyDecoder) Read(p []byte) (int, error) {
+	n, err := d.decoder.Read(p)
+	if err == nil || err == io.EOF {
+		return n, err
+	}
+
+	// limit errors are passed on as they are,
+	// anything else means the body is corrupt
+	var godiErr *godierr.Error
+	if errors.As(err, &godiErr) {
+		return n, godiErr
+	}
+	if err == zstd.ErrDecoderSizeExceeded || err == zstd.ErrWindowSizeExceeded {
+		return n, godierr.PayloadTooLargeError(d.maxBytes)
+	}
+	return n, godierr.InvalidArgsError("body")
+}
+
+func (d *bodyDecoder) Close() error {
+	d.close()
+	return d.body.Close()
+}
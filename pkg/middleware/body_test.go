@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+func gzipBody(t *testing.T, body string) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	_, err := gw.Write([]byte(body))
+	assert.Nil(t, err)
+	assert.Nil(t, gw.Close())
+	return buf.Bytes()
+}
+
+func zstdBody(t *testing.T, body string) []byte {
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	assert.Nil(t, err)
+	_, err = zw.Write([]byte(body))
+	assert.Nil(t, err)
+	assert.Nil(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestBodyLimit(t *testing.T) {
+	// echoes the body back or responds with the error returned reading it
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			if godiErr, ok := err.(*godierr.Error); ok {
+				util.ErrorJSON(w, &util.ErrorResponse{
+					Code:    godiErr.Code(),
+					Type:    godiErr.Type(),
+					Message: godiErr.Message(),
+				})
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		assert.Empty(t, r.Header.Get("Content-Encoding"))
+		w.Write(body)
+	})
+
+	serve := func(opts BodyLimitOptions, body []byte, encoding string, contentLength int64) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		req.ContentLength = contentLength
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+
+		rr := httptest.NewRecorder()
+		BodyLimit(opts)(echo).ServeHTTP(rr, req)
+		return rr
+	}
+
+	errorType := func(rr *httptest.ResponseRecorder) string {
+		var res util.ErrorResponse
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&res))
+		return res.Type
+	}
+
+	t.Run("within limit", func(t *testing.T) {
+		rr := serve(BodyLimitOptions{MaxBytes: 10}, []byte("0123456789"), "", 10)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "0123456789", rr.Body.String())
+	})
+
+	t.Run("content length over limit", func(t *testing.T) {
+		rr := serve(BodyLimitOptions{MaxBytes: 5}, []byte("0123456789"), "", 10)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, "close", rr.Header().Get("Connection"))
+		assert.Equal(t, godierr.PayloadTooLargeType, errorType(rr))
+	})
+
+	t.Run("body over limit without content length", func(t *testing.T) {
+		rr := serve(BodyLimitOptions{MaxBytes: 5}, []byte("0123456789"), "", -1)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, godierr.PayloadTooLargeType, errorType(rr))
+	})
+
+	t.Run("not limited", func(t *testing.T) {
+		rr := serve(BodyLimitOptions{}, []byte("0123456789"), "", -1)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "0123456789", rr.Body.String())
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		rr := serve(BodyLimitOptions{MaxBytes: 1024}, gzipBody(t, "hello world"), "gzip", -1)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "hello world", rr.Body.String())
+	})
+
+	t.Run("zstd", func(t *testing.T) {
+		rr := serve(BodyLimitOptions{MaxBytes: 1024}, zstdBody(t, "hello world"), "zstd", -1)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "hello world", rr.Body.String())
+	})
+
+	t.Run("decompression bomb", func(t *testing.T) {
+		bomb := strings.Repeat("0", 1<<20)
+
+		for encoding, body := range map[string][]byte{
+			"gzip": gzipBody(t, bomb),
+			"zstd": zstdBody(t, bomb),
+		} {
+			assert.Less(t, len(body), 4096)
+
+			rr := serve(BodyLimitOptions{MaxBytes: 4096, MaxDecompressedBytes: 64 << 10}, body, encoding, -1)
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, encoding)
+			assert.Equal(t, godierr.PayloadTooLargeType, errorType(rr), encoding)
+		}
+	})
+
+	t.Run("decompression bomb without body limit", func(t *testing.T) {
+		body := gzipBody(t, strings.Repeat("0", int(DefaultMaxBodyBytes)+1))
+
+		rr := serve(BodyLimitOptions{MaxBytes: -1}, body, "gzip", -1)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, godierr.PayloadTooLargeType, errorType(rr))
+	})
+
+	t.Run("corrupt body", func(t *testing.T) {
+		rr := serve(BodyLimitOptions{MaxBytes: 1024}, []byte("not gzip"), "gzip", -1)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, godierr.InvalidArgType, errorType(rr))
+	})
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		rr := serve(BodyLimitOptions{MaxBytes: 1024}, []byte("hello"), "compress", -1)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+		assert.Equal(t, godierr.UnsupportedEncodingType, errorType(rr))
+	})
+}
@@ -0,0 +1,332 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+const (
+	// CacheStatusHeader tells whether a response was served from the cache
+	CacheStatusHeader string = "X-Cache"
+
+	// tags the responses are stored with, to invalidate them
+	cachePathTag  string = "path:"
+	cacheRouteTag string = "route:"
+)
+
+// headers sent with 304 responses
+var notModifiedHeaders = []string{"Cache-Control", "Content-Location", "ETag", "Expires", "Last-Modified", "Vary"}
+
+// CacheEntry is a response stored in the cache
+// Response - the status, headers and body of the response
+// StoredAt - when the response was stored. Used for the Age header
+type CacheEntry struct {
+	Response *util.Response
+	StoredAt time.Time
+}
+
+// CacheStore stores cached responses by key.
+// Implementations must be safe for concurrent use
+type CacheStore interface {
+	// Get returns the entry stored for the key. Nil when not cached or expired
+	Get(ctx context.Context, key string) (*CacheEntry, error)
+	// Set stores the entry for ttl along with tags to invalidate it by
+	Set(ctx context.Context, key string, entry *CacheEntry, ttl time.Duration, tags []string) error
+	// Invalidate drops the entries stored with any of the tags
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+// CacheOptions configures the caching of the responses of a route
+// TTL - how long full responses are stored. Only ETags and conditional
+// requests are handled when 0
+// VaryHeaders - request headers the response depends on. Defaults to Accept
+// Shared - store a single response for all the principals. By default
+// responses to requests with a principal, read from util.PrincipalKey,
+// are stored per principal
+// Key - returns the cache key of the request instead of the default one,
+// built from the path, the query, the vary headers and the principal
+type CacheOptions struct {
+	TTL         time.Duration
+	VaryHeaders []string
+	Shared      bool
+	Key         func(r *http.Request) string
+}
+
+// Cache handles conditional requests for GET routes using ETags computed
+// over the response body, and stores full responses in a CacheStore.
+// Stored responses can be invalidated by path or route name
+type Cache struct {
+	store CacheStore
+	now   func() time.Time
+}
+
+// NewCache returns a new instance of Cache storing
+// responses in store. Defaults to a MemoryCacheStore
+func NewCache(store CacheStore) *Cache {
+	if store == nil {
+		store = NewMemoryCacheStore(0)
+	}
+
+	return &Cache{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Handler returns the middleware caching the responses of GET requests.
+// Successful responses get an ETag, unless the handler set one, and
+// requests with a matching If-None-Match or If-Modified-Since get a 304.
+// Responses are stored when TTL is set, per principal unless Shared,
+// unless they set a cookie or Cache-Control forbids it. Requests with Cache-Control: no-cache skip
+// stored responses. Streamed responses are passed through as they are
+func (c *Cache) Handler(opts CacheOptions) func(http.Handler) http.Handler {
+	if len(opts.VaryHeaders) == 0 {
+		opts.VaryHeaders = []string{"Accept"}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			key := c.key(r, opts)
+
+			if opts.TTL > 0 && !strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+				// the store failing is treated as a miss so requests are still served
+				if entry, err := c.store.Get(ctx, key); err == nil && entry != nil {
+					c.serveEntry(w, r, entry)
+					return
+				}
+			}
+
+			// buffered so the ETag can be set before the response is sent
+			rec := newResponseRecorder(w, 0)
+			rec.buffered = true
+			next.ServeHTTP(rec, r)
+			if rec.flushed {
+				return
+			}
+
+			header := w.Header()
+			if rec.status != http.StatusOK {
+				rec.send()
+				return
+			}
+
+			if header.Get("ETag") == "" {
+				header.Set("ETag", bodyETag(rec.body.Bytes()))
+			}
+			for _, name := range opts.VaryHeaders {
+				util.AddVary(header, name)
+			}
+
+			if opts.TTL > 0 && cacheable(header, principal(r, opts) != "") {
+				now := c.now()
+				if header.Get("Last-Modified") == "" {
+					header.Set("Last-Modified", now.UTC().Format(http.TimeFormat))
+				}
+				header.Set(CacheStatusHeader, "MISS")
+
+				entry := &CacheEntry{
+					Response: &util.Response{
+						StatusCode: rec.status,
+						Headers:    storedHeaders(rec.header()),
+						Body:       rec.body.String(),
+					},
+					StoredAt: now,
+				}
+				if err := c.store.Set(ctx, key, entry, opts.TTL, cacheTags(r)); err != nil {
+					logger.Warn("Unable to store cached response", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+				}
+			}
+
+			if notModified(r, header) {
+				writeNotModified(w, header)
+				return
+			}
+			rec.send()
+		})
+	}
+}
+
+// Invalidate drops the stored responses of the paths e.g. /users/1
+func (c *Cache) Invalidate(ctx context.Context, paths ...string) error {
+	tags := make([]string, 0, len(paths))
+	for _, path := range paths {
+		tags = append(tags, cachePathTag+path)
+	}
+	return c.store.Invalidate(ctx, tags...)
+}
+
+// InvalidateRoutes drops the stored responses of the named routes
+func (c *Cache) InvalidateRoutes(ctx context.Context, names ...string) error {
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, cacheRouteTag+name)
+	}
+	return c.store.Invalidate(ctx, tags...)
+}
+
+// serveEntry writes the stored response. The headers set for the
+// request by the outer middlewares e.g. CORS are kept
+func (c *Cache) serveEntry(w http.ResponseWriter, r *http.Request, entry *CacheEntry) {
+	header := w.Header()
+	for key, value := range entry.Response.Headers {
+		key = http.CanonicalHeaderKey(key)
+		switch {
+		case key == "Vary":
+			util.AddVary(header, strings.Split(value, ",")...)
+		case strings.HasPrefix(key, "Access-Control-") && header.Get(key) != "":
+			// set by CORS for the origin of the request
+		default:
+			header.Set(key, value)
+		}
+	}
+	age := c.now().Sub(entry.StoredAt) / time.Second
+	if age < 0 {
+		age = 0
+	}
+	header.Set("Age", strconv.FormatInt(int64(age), 10))
+	header.Set(CacheStatusHeader, "HIT")
+
+	if notModified(r, header) {
+		writeNotModified(w, header)
+		return
+	}
+
+	w.WriteHeader(entry.Response.StatusCode)
+	w.Write([]byte(entry.Response.Body))
+}
+
+// key returns the cache key of the request
+func (c *Cache) key(r *http.Request, opts CacheOptions) string {
+	if opts.Key != nil {
+		return opts.Key(r)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery))
+	for _, name := range opts.VaryHeaders {
+		h.Write([]byte{0})
+		h.Write([]byte(strings.Join(r.Header.Values(name), ",")))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(principal(r, opts)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// principal returns the principal the response is stored for.
+// Empty for shared responses and requests without a principal
+func principal(r *http.Request, opts CacheOptions) string {
+	if opts.Shared {
+		return ""
+	}
+	p, _ := r.Context().Value(util.PrincipalKey).(string)
+	return p
+}
+
+func cacheTags(r *http.Request) []string {
+	tags := []string{cachePathTag + r.URL.Path}
+	if route := mux.CurrentRoute(r); route != nil && route.GetName() != "" {
+		tags = append(tags, cacheRouteTag+route.GetName())
+	}
+	return tags
+}
+
+// cacheable reports whether the response can be stored. Responses
+// marked private are only stored when they are cached per principal
+func cacheable(header http.Header, perPrincipal bool) bool {
+	if header.Get("Set-Cookie") != "" {
+		return false
+	}
+
+	cacheControl := strings.ToLower(header.Get("Cache-Control"))
+	if strings.Contains(cacheControl, "no-store") {
+		return false
+	}
+	return perPrincipal || !strings.Contains(cacheControl, "private")
+}
+
+// storedHeaders returns the headers set by the handler to store.
+// Multiple values of a header are joined
+func storedHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for key, values := range header {
+		if len(values) != 0 && !unreplayedHeaders[http.CanonicalHeaderKey(key)] && http.CanonicalHeaderKey(key) != CacheStatusHeader {
+			headers[key] = strings.Join(values, ", ")
+		}
+	}
+	return headers
+}
+
+// bodyETag returns a strong ETag for the body
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified evaluates the conditional headers of the request
+// against the response. If-Modified-Since is only used
+// when the request has no If-None-Match
+func notModified(r *http.Request, header http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, header.Get("ETag"))
+	}
+
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(ims)
+}
+
+// etagMatches compares the ETags in an If-None-Match header to
+// the ETag of the response using the weak comparison
+func etagMatches(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+func writeNotModified(w http.ResponseWriter, header http.Header) {
+	keep := map[string]bool{
+		CacheStatusHeader: true,
+		"Age":             true,
+		"X-Request-Id":    true,
+	}
+	for _, name := range notModifiedHeaders {
+		keep[http.CanonicalHeaderKey(name)] = true
+	}
+	for name := range header {
+		if !keep[http.CanonicalHeaderKey(name)] {
+			header.Del(name)
+		}
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+}
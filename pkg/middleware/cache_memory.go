@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheSize is the number of responses kept
+	// by a MemoryCacheStore when not configured
+	DefaultCacheSize int = 1024
+)
+
+// MemoryCacheStore keeps responses in memory, dropping the least
+// recently used ones once full. Suited to a single instance of the server
+type MemoryCacheStore struct {
+	mu      sync.Mutex
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+	tags    map[string]map[string]struct{}
+	now     func() time.Time
+}
+
+type memoryCacheItem struct {
+	key     string
+	entry   *CacheEntry
+	expires time.Time
+	tags    []string
+}
+
+// NewMemoryCacheStore returns a new instance of MemoryCacheStore
+// keeping up to size responses. Defaults to DefaultCacheSize
+func NewMemoryCacheStore(size int) *MemoryCacheStore {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+
+	return &MemoryCacheStore{
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		tags:    make(map[string]map[string]struct{}),
+		now:     time.Now,
+	}
+}
+
+// Get returns the entry stored for the key, if not expired
+func (s *MemoryCacheStore) Get(ctx context.Context, key string) (*CacheEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+
+	item := elem.Value.(*memoryCacheItem)
+	if !s.now().Before(item.expires) {
+		s.remove(elem)
+		return nil, nil
+	}
+
+	s.lru.MoveToFront(elem)
+	return item.entry, nil
+}
+
+// Set stores the entry, replacing any entry stored for the key
+func (s *MemoryCacheStore) Set(ctx context.Context, key string, entry *CacheEntry, ttl time.Duration, tags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+
+	s.entries[key] = s.lru.PushFront(&memoryCacheItem{
+		key:     key,
+		entry:   entry,
+		expires: s.now().Add(ttl),
+		tags:    tags,
+	})
+	for _, tag := range tags {
+		if s.tags[tag] == nil {
+			s.tags[tag] = make(map[string]struct{})
+		}
+		s.tags[tag][key] = struct{}{}
+	}
+
+	for s.lru.Len() > s.size {
+		s.remove(s.lru.Back())
+	}
+
+	return nil
+}
+
+// Invalidate drops the entries stored with any of the tags
+func (s *MemoryCacheStore) Invalidate(ctx context.Context, tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			if elem, ok := s.entries[key]; ok {
+				s.remove(elem)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of entries stored, including expired ones
+func (s *MemoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lru.Len()
+}
+
+// remove drops the entry. Must be called with the lock held
+func (s *MemoryCacheStore) remove(elem *list.Element) {
+	item := s.lru.Remove(elem).(*memoryCacheItem)
+	delete(s.entries, item.key)
+
+	for _, tag := range item.tags {
+		delete(s.tags[tag], item.key)
+		if len(s.tags[tag]) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
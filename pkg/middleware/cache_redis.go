@@ -0,0 +1,335 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+const (
+	defaultRedisPrefix   string        = "godi:cache:"
+	defaultRedisPoolSize int           = 10
+	defaultRedisTimeout  time.Duration = 5 * time.Second
+)
+
+// RedisOptions configures the connection to a server
+// speaking the Redis protocol (RESP) e.g. Redis, KeyDB, Valkey
+// Addr - host:port of the server
+// Password - sent using AUTH, if set
+// DB - the database selected using SELECT, if set
+// Prefix - prepended to every key. Defaults to godi:cache:
+// PoolSize - maximum number of idle connections kept. Defaults to 10
+// Timeout - for dialing and for each command unless the context ends sooner. Defaults to 5s
+type RedisOptions struct {
+	Addr     string
+	Password string
+	DB       int
+	Prefix   string
+	PoolSize int
+	Timeout  time.Duration
+}
+
+// RedisCacheStore keeps responses in a server speaking the Redis protocol,
+// so they are shared by every instance of the server. Entries expire using
+// PX. Tags are sets of keys, expiring along with their longest lived entry
+type RedisCacheStore struct {
+	client *respClient
+	prefix string
+}
+
+// redisCacheEntry is the stored form of an entry.
+// The body is kept as bytes so binary bodies survive JSON
+type redisCacheEntry struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       []byte            `json:"body,omitempty"`
+	StoredAt   time.Time         `json:"storedAt"`
+}
+
+// NewRedisCacheStore returns a new instance of RedisCacheStore.
+// Connections are opened when needed
+func NewRedisCacheStore(opts RedisOptions) *RedisCacheStore {
+	if opts.Prefix == "" {
+		opts.Prefix = defaultRedisPrefix
+	}
+
+	return &RedisCacheStore{
+		client: newRESPClient(opts),
+		prefix: opts.Prefix,
+	}
+}
+
+// Get returns the entry stored for the key
+func (s *RedisCacheStore) Get(ctx context.Context, key string) (*CacheEntry, error) {
+	reply, err := s.client.do(ctx, "GET", s.prefix+key)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+
+	b, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply to GET: %v", reply)
+	}
+
+	var stored redisCacheEntry
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+
+	return &CacheEntry{
+		Response: &util.Response{
+			StatusCode: stored.StatusCode,
+			Headers:    stored.Headers,
+			Body:       string(stored.Body),
+		},
+		StoredAt: stored.StoredAt,
+	}, nil
+}
+
+// Set stores the entry and adds its key to the set of each tag
+func (s *RedisCacheStore) Set(ctx context.Context, key string, entry *CacheEntry, ttl time.Duration, tags []string) error {
+	b, err := json.Marshal(&redisCacheEntry{
+		StatusCode: entry.Response.StatusCode,
+		Headers:    entry.Response.Headers,
+		Body:       []byte(entry.Response.Body),
+		StoredAt:   entry.StoredAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	ms := strconv.FormatInt(ttl.Milliseconds(), 10)
+	if _, err := s.client.do(ctx, "SET", s.prefix+key, string(b), "PX", ms); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		tagKey := s.prefix + "tag:" + tag
+		if _, err := s.client.do(ctx, "SADD", tagKey, key); err != nil {
+			return err
+		}
+
+		// the set lives as long as the longest lived entry in it
+		reply, err := s.client.do(ctx, "PTTL", tagKey)
+		if err != nil {
+			return err
+		}
+		if remaining, _ := reply.(int64); remaining < ttl.Milliseconds() {
+			if _, err := s.client.do(ctx, "PEXPIRE", tagKey, ms); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Invalidate deletes the entries in the sets of the tags, and the sets
+func (s *RedisCacheStore) Invalidate(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		tagKey := s.prefix + "tag:" + tag
+		reply, err := s.client.do(ctx, "SMEMBERS", tagKey)
+		if err != nil {
+			return err
+		}
+
+		members, _ := reply.([]interface{})
+		args := []string{"DEL", tagKey}
+		for _, member := range members {
+			if b, ok := member.([]byte); ok {
+				args = append(args, s.prefix+string(b))
+			}
+		}
+
+		if _, err := s.client.do(ctx, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes the idle connections
+func (s *RedisCacheStore) Close() error {
+	return s.client.close()
+}
+
+// respError is an error reply of the server
+type respError string
+
+func (e respError) Error() string {
+	return string(e)
+}
+
+// respClient sends commands to a server speaking RESP
+// over a pool of connections
+type respClient struct {
+	opts  RedisOptions
+	conns chan *respConn
+}
+
+type respConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func newRESPClient(opts RedisOptions) *respClient {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = defaultRedisPoolSize
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultRedisTimeout
+	}
+
+	return &respClient{
+		opts:  opts,
+		conns: make(chan *respConn, opts.PoolSize),
+	}
+}
+
+// do sends the command and returns the reply: a string for simple
+// strings, int64 for integers, []byte for bulk strings, []interface{}
+// for arrays and nil for null replies. Error replies are returned as errors
+func (c *respClient) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(ctx, c.opts.Timeout, args...)
+	var replyErr respError
+	if err != nil && !errors.As(err, &replyErr) {
+		// the connection is in an unknown state
+		conn.Close()
+		return nil, err
+	}
+
+	c.put(conn)
+	return reply, err
+}
+
+func (c *respClient) get(ctx context.Context) (*respConn, error) {
+	select {
+	case conn := <-c.conns:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.opts.Timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &respConn{
+		Conn: netConn,
+		r:    bufio.NewReader(netConn),
+	}
+
+	if c.opts.Password != "" {
+		if _, err := conn.do(ctx, c.opts.Timeout, "AUTH", c.opts.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.opts.DB != 0 {
+		if _, err := conn.do(ctx, c.opts.Timeout, "SELECT", strconv.Itoa(c.opts.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *respClient) put(conn *respConn) {
+	select {
+	case c.conns <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+func (c *respClient) close() error {
+	for {
+		select {
+		case conn := <-c.conns:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (conn *respConn) do(ctx context.Context, timeout time.Duration, args ...string) (interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := conn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return readRESP(conn.r)
+}
+
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("malformed reply %q", line)
+	}
+	kind, value := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return value, nil
+	case '-':
+		return nil, respError(value)
+	case ':':
+		return strconv.ParseInt(value, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readRESP(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("unknown reply type %q", kind)
+}
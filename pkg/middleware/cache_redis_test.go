@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis serves the subset of the Redis
+// protocol used by RedisCacheStore
+type fakeRedis struct {
+	mu       sync.Mutex
+	password string
+	strings  map[string]string
+	sets     map[string]map[string]bool
+	expires  map[string]time.Time
+	commands []string
+}
+
+func startFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	f := &fakeRedis{
+		password: password,
+		strings:  map[string]string{},
+		sets:     map[string]map[string]bool{},
+		expires:  map[string]time.Time{},
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	return f, ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+
+	for {
+		reply, err := readRESP(r)
+		if err != nil {
+			return
+		}
+		items := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = string(item.([]byte))
+		}
+
+		if strings.ToUpper(args[0]) == "AUTH" {
+			authed = args[1] == f.password
+		}
+		if !authed {
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		fmt.Fprint(conn, f.exec(args))
+	}
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.commands = append(f.commands, strings.ToUpper(args[0]))
+	for key, expires := range f.expires {
+		if !time.Now().Before(expires) {
+			delete(f.strings, key)
+			delete(f.sets, key)
+			delete(f.expires, key)
+		}
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		value, ok := f.strings[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		f.strings[args[1]] = args[2]
+		ms, _ := strconv.Atoi(args[4])
+		f.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return "+OK\r\n"
+	case "SADD":
+		if f.sets[args[1]] == nil {
+			f.sets[args[1]] = map[string]bool{}
+		}
+		f.sets[args[1]][args[2]] = true
+		return ":1\r\n"
+	case "SMEMBERS":
+		reply := fmt.Sprintf("*%d\r\n", len(f.sets[args[1]]))
+		for member := range f.sets[args[1]] {
+			reply += fmt.Sprintf("$%d\r\n%s\r\n", len(member), member)
+		}
+		return reply
+	case "PTTL":
+		expires, ok := f.expires[args[1]]
+		if !ok {
+			return ":-1\r\n"
+		}
+		return fmt.Sprintf(":%d\r\n", time.Until(expires).Milliseconds())
+	case "PEXPIRE":
+		ms, _ := strconv.Atoi(args[2])
+		f.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return ":1\r\n"
+	case "DEL":
+		for _, key := range args[1:] {
+			delete(f.strings, key)
+			delete(f.sets, key)
+			delete(f.expires, key)
+		}
+		return fmt.Sprintf(":%d\r\n", len(args)-1)
+	}
+
+	return "-ERR unknown command\r\n"
+}
+
+func TestRedisCacheStore(t *testing.T) {
+	ctx := context.Background()
+	fake, addr := startFakeRedis(t, "secret")
+
+	store := NewRedisCacheStore(RedisOptions{
+		Addr:     addr,
+		Password: "secret",
+		DB:       1,
+	})
+	defer store.Close()
+
+	storedAt := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	entry := &CacheEntry{
+		Response: &util.Response{
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{"Content-Type": "application/octet-stream"},
+			Body:       "\x00\xff binary",
+		},
+		StoredAt: storedAt,
+	}
+
+	got, err := store.Get(ctx, "missing")
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+
+	assert.Nil(t, store.Set(ctx, "a", entry, time.Minute, []string{"path:/a", "route:items"}))
+	assert.Nil(t, store.Set(ctx, "b", entry, time.Minute, []string{"path:/b", "route:items"}))
+
+	got, err = store.Get(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, entry.Response, got.Response)
+	assert.True(t, storedAt.Equal(got.StoredAt))
+
+	fake.mu.Lock()
+	assert.Contains(t, fake.strings, "godi:cache:a")
+	assert.Equal(t, map[string]bool{"a": true, "b": true}, fake.sets["godi:cache:tag:route:items"])
+	assert.Contains(t, fake.expires, "godi:cache:tag:route:items")
+	assert.Equal(t, 1, strings.Count(strings.Join(fake.commands, " "), "SELECT"))
+	fake.mu.Unlock()
+
+	assert.Nil(t, store.Invalidate(ctx, "path:/a"))
+	got, _ = store.Get(ctx, "a")
+	assert.Nil(t, got)
+	got, _ = store.Get(ctx, "b")
+	assert.NotNil(t, got)
+
+	assert.Nil(t, store.Invalidate(ctx, "route:items"))
+	got, _ = store.Get(ctx, "b")
+	assert.Nil(t, got)
+
+	t.Run("errors", func(t *testing.T) {
+		unauthenticated := NewRedisCacheStore(RedisOptions{Addr: addr})
+		_, err := unauthenticated.Get(ctx, "a")
+		assert.EqualError(t, err, "NOAUTH Authentication required.")
+
+		unreachable := NewRedisCacheStore(RedisOptions{Addr: "127.0.0.1:1", Timeout: 100 * time.Millisecond})
+		_, err = unreachable.Get(ctx, "a")
+		assert.NotNil(t, err)
+	})
+}
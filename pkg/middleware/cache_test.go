@@ -0,0 +1,286 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	// newRouter mounts a route counting the calls to its handler
+	newRouter := func(cache *Cache, opts CacheOptions, header http.Header) (*mux.Router, *int) {
+		calls := new(int)
+		router := mux.NewRouter()
+		router.Name("user").Path("/users/{id}").Methods(http.MethodGet).Handler(cache.Handler(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls++
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"id":"%s","lang":"%s"}`, mux.Vars(r)["id"], r.Header.Get("Accept-Language"))
+		})))
+		return router, calls
+	}
+
+	send := func(router http.Handler, path string, header map[string]string, ctx context.Context) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+		if ctx != nil {
+			req = req.WithContext(ctx)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("etag", func(t *testing.T) {
+		router, calls := newRouter(NewCache(nil), CacheOptions{}, nil)
+
+		rr := send(router, "/users/1", nil, nil)
+		etag := rr.Header().Get("ETag")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+		assert.Equal(t, "Accept", rr.Header().Get("Vary"))
+		assert.Empty(t, rr.Header().Get(CacheStatusHeader))
+
+		rr = send(router, "/users/1", map[string]string{"If-None-Match": `"other", W/` + etag}, nil)
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Body.String())
+		assert.Equal(t, etag, rr.Header().Get("ETag"))
+		assert.Empty(t, rr.Header().Get("Content-Type"))
+
+		rr = send(router, "/users/2", map[string]string{"If-None-Match": etag}, nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("etag set by the handler", func(t *testing.T) {
+		router, _ := newRouter(NewCache(nil), CacheOptions{}, http.Header{"Etag": {`"v1"`}})
+
+		rr := send(router, "/users/1", map[string]string{"If-None-Match": `"v1"`}, nil)
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+	})
+
+	t.Run("if modified since", func(t *testing.T) {
+		lastModified := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+		router, _ := newRouter(NewCache(nil), CacheOptions{}, http.Header{"Last-Modified": {lastModified.Format(http.TimeFormat)}})
+
+		rr := send(router, "/users/1", map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)}, nil)
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+
+		rr = send(router, "/users/1", map[string]string{"If-Modified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)}, nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("stored responses", func(t *testing.T) {
+		cache := NewCache(nil)
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+		router, calls := newRouter(cache, CacheOptions{TTL: time.Minute}, nil)
+
+		first := send(router, "/users/1", nil, nil)
+		assert.Equal(t, "MISS", first.Header().Get(CacheStatusHeader))
+		assert.NotEmpty(t, first.Header().Get("Last-Modified"))
+
+		now = now.Add(5 * time.Second)
+		rr := send(router, "/users/1", nil, nil)
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, "HIT", rr.Header().Get(CacheStatusHeader))
+		assert.Equal(t, "5", rr.Header().Get("Age"))
+		assert.Equal(t, first.Body.String(), rr.Body.String())
+		assert.Equal(t, first.Header().Get("ETag"), rr.Header().Get("ETag"))
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		rr = send(router, "/users/1", map[string]string{"If-None-Match": first.Header().Get("ETag")}, nil)
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Equal(t, 1, *calls)
+
+		rr = send(router, "/users/1", map[string]string{"Cache-Control": "no-cache"}, nil)
+		assert.Equal(t, "MISS", rr.Header().Get(CacheStatusHeader))
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("vary", func(t *testing.T) {
+		router, calls := newRouter(NewCache(nil), CacheOptions{
+			TTL:         time.Minute,
+			VaryHeaders: []string{"Accept-Language"},
+		}, nil)
+
+		user1 := context.WithValue(context.Background(), util.PrincipalKey, "user-1")
+		user2 := context.WithValue(context.Background(), util.PrincipalKey, "user-2")
+
+		send(router, "/users/1", map[string]string{"Accept-Language": "en"}, user1)
+		send(router, "/users/1", map[string]string{"Accept-Language": "en"}, user1)
+		assert.Equal(t, 1, *calls)
+
+		rr := send(router, "/users/1", map[string]string{"Accept-Language": "bn"}, user1)
+		assert.Equal(t, `{"id":"1","lang":"bn"}`, rr.Body.String())
+		assert.Equal(t, "Accept-Language", rr.Header().Get("Vary"))
+		assert.Equal(t, 2, *calls)
+
+		send(router, "/users/1", map[string]string{"Accept-Language": "en"}, user2)
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		router, calls := newRouter(NewCache(nil), CacheOptions{
+			TTL:    time.Minute,
+			Shared: true,
+		}, nil)
+
+		send(router, "/users/1", nil, context.WithValue(context.Background(), util.PrincipalKey, "user-1"))
+		rr := send(router, "/users/1", nil, context.WithValue(context.Background(), util.PrincipalKey, "user-2"))
+		assert.Equal(t, "HIT", rr.Header().Get(CacheStatusHeader))
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("custom key", func(t *testing.T) {
+		router, calls := newRouter(NewCache(nil), CacheOptions{
+			TTL: time.Minute,
+			Key: func(r *http.Request) string { return "users" },
+		}, nil)
+
+		send(router, "/users/1", nil, nil)
+		rr := send(router, "/users/2", nil, nil)
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, `{"id":"1","lang":""}`, rr.Body.String())
+	})
+
+	t.Run("not stored", func(t *testing.T) {
+		cases := []struct {
+			name   string
+			header http.Header
+		}{
+			{"no-store", http.Header{"Cache-Control": {"no-store"}}},
+			{"private", http.Header{"Cache-Control": {"private, max-age=60"}}},
+			{"cookie", http.Header{"Set-Cookie": {"session=1"}}},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				router, calls := newRouter(NewCache(nil), CacheOptions{TTL: time.Minute}, c.header)
+
+				send(router, "/users/1", nil, nil)
+				send(router, "/users/1", nil, nil)
+				assert.Equal(t, 2, *calls)
+			})
+		}
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		calls := 0
+		handler := NewCache(nil).Handler(CacheOptions{TTL: time.Minute})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}))
+
+		for i := 0; i < 2; i++ {
+			rr := send(handler, "/users/1", nil, nil)
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+			assert.Equal(t, "not found", rr.Body.String())
+			assert.Empty(t, rr.Header().Get("ETag"))
+		}
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("streamed responses", func(t *testing.T) {
+		handler := NewCache(nil).Handler(CacheOptions{TTL: time.Minute})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("data: 1\n\n"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("data: 2\n\n"))
+		}))
+
+		rr := send(handler, "/events", nil, nil)
+		assert.Equal(t, "data: 1\n\ndata: 2\n\n", rr.Body.String())
+		assert.True(t, rr.Flushed)
+		assert.Empty(t, rr.Header().Get("ETag"))
+	})
+
+	t.Run("invalidation", func(t *testing.T) {
+		cache := NewCache(nil)
+		router, calls := newRouter(cache, CacheOptions{TTL: time.Minute}, nil)
+
+		send(router, "/users/1", nil, nil)
+		send(router, "/users/2", nil, nil)
+
+		assert.Nil(t, cache.Invalidate(context.Background(), "/users/1"))
+		send(router, "/users/1", nil, nil)
+		send(router, "/users/2", nil, nil)
+		assert.Equal(t, 3, *calls)
+
+		assert.Nil(t, cache.InvalidateRoutes(context.Background(), "user"))
+		send(router, "/users/1", nil, nil)
+		send(router, "/users/2", nil, nil)
+		assert.Equal(t, 5, *calls)
+	})
+}
+
+func TestMemoryCacheStore(t *testing.T) {
+	ctx := context.Background()
+	entry := func(body string) *CacheEntry {
+		return &CacheEntry{Response: &util.Response{StatusCode: http.StatusOK, Body: body}}
+	}
+
+	t.Run("expiry", func(t *testing.T) {
+		now := time.Now()
+		store := NewMemoryCacheStore(0)
+		store.now = func() time.Time { return now }
+
+		assert.Nil(t, store.Set(ctx, "a", entry("a"), time.Minute, nil))
+		got, err := store.Get(ctx, "a")
+		assert.Nil(t, err)
+		assert.Equal(t, "a", got.Response.Body)
+
+		now = now.Add(time.Minute)
+		got, err = store.Get(ctx, "a")
+		assert.Nil(t, err)
+		assert.Nil(t, got)
+		assert.Equal(t, 0, store.Len())
+	})
+
+	t.Run("least recently used are evicted", func(t *testing.T) {
+		store := NewMemoryCacheStore(2)
+
+		store.Set(ctx, "a", entry("a"), time.Minute, []string{"tag"})
+		store.Set(ctx, "b", entry("b"), time.Minute, nil)
+		store.Get(ctx, "a")
+		store.Set(ctx, "c", entry("c"), time.Minute, nil)
+
+		assert.Equal(t, 2, store.Len())
+		got, _ := store.Get(ctx, "b")
+		assert.Nil(t, got)
+		got, _ = store.Get(ctx, "a")
+		assert.NotNil(t, got)
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		store := NewMemoryCacheStore(0)
+
+		store.Set(ctx, "a", entry("a"), time.Minute, []string{"users", "user:1"})
+		store.Set(ctx, "b", entry("b"), time.Minute, []string{"users"})
+		store.Set(ctx, "c", entry("c"), time.Minute, []string{"orders"})
+
+		assert.Nil(t, store.Invalidate(ctx, "user:1"))
+		assert.Equal(t, 2, store.Len())
+
+		assert.Nil(t, store.Invalidate(ctx, "users", "missing"))
+		assert.Equal(t, 1, store.Len())
+		assert.Empty(t, store.tags["users"])
+	})
+}
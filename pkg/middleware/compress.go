@@ -0,0 +1,331 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+const (
+	// DefaultCompressMinSize is the minimum size of a response
+	// in bytes before it is compressed when not configured
+	DefaultCompressMinSize int = 1024
+)
+
+// DefaultCompressContentTypes is the list of content types
+// compressed when not configured. Entries ending with "/"
+// match any subtype
+var DefaultCompressContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/xml",
+	"application/javascript",
+	"application/x-javascript",
+	"application/msgpack",
+	"application/cbor",
+	"application/x-protobuf",
+	"image/svg+xml",
+}
+
+// compressEncodings are the supported content
+// codings in order of preference
+var compressEncodings = []string{"br", "zstd", "gzip", "deflate"}
+
+// CompressOptions configures the response compression
+// MinSize - minimum response size in bytes to compress. Defaults to 1KB.
+// Streamed responses are compressed regardless of their size
+// ContentTypes - content types allowed to be compressed. Entries
+// ending with "/" match any subtype. Defaults to DefaultCompressContentTypes
+type CompressOptions struct {
+	MinSize      int
+	ContentTypes []string
+}
+
+// Compress compresses responses using brotli, zstd, gzip or deflate
+// based on the Accept-Encoding header of the request. Responses smaller than
+// the minimum size, of a content type not in the allowlist or already
+// encoded are sent as they are
+func Compress(opts CompressOptions) func(http.Handler) http.Handler {
+	if opts.MinSize <= 0 {
+		opts.MinSize = DefaultCompressMinSize
+	}
+	if len(opts.ContentTypes) == 0 {
+		opts.ContentTypes = DefaultCompressContentTypes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead || isUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				opts:           opts,
+				encoding:       encoding,
+			}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressWriter buffers the start of a response until it can tell
+// whether the response should be compressed, then either compresses
+// or passes through everything written afterwards
+type compressWriter struct {
+	http.ResponseWriter
+
+	opts     CompressOptions
+	encoding string
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         bytes.Buffer
+	encoder     io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+
+	cw.wroteHeader = true
+	cw.status = status
+
+	if !bodyAllowed(status) {
+		cw.decided = true
+		cw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	n, _ := cw.buf.Write(p)
+	if cw.buf.Len() >= cw.opts.MinSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// Flush sends everything written so far to the client.
+// Flushing marks the response as streamed, so it is
+// compressed even when smaller than the minimum size
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if !cw.decided {
+		cw.decide(true)
+	}
+
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades take over the connection
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
+// Close writes any buffered data and finishes the compressed stream
+func (cw *compressWriter) Close() error {
+	if !cw.wroteHeader {
+		// nothing was written by the handler
+		return nil
+	}
+
+	if !cw.decided {
+		if err := cw.decide(false); err != nil {
+			return err
+		}
+	}
+
+	if cw.encoder != nil {
+		err := cw.encoder.Close()
+		putEncoder(cw.encoding, cw.encoder)
+		cw.encoder = nil
+		return err
+	}
+	return nil
+}
+
+// decide writes the header and the buffered data, compressing them when
+// the response qualifies. large reports whether the response reached the
+// minimum size or is streamed
+func (cw *compressWriter) decide(large bool) error {
+	cw.decided = true
+
+	header := cw.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", http.DetectContentType(cw.buf.Bytes()))
+	}
+
+	// partial content is left alone as the ranges refer to the unencoded body
+	compress := large &&
+		cw.status != http.StatusPartialContent &&
+		header.Get("Content-Encoding") == "" &&
+		allowedContentType(header.Get("Content-Type"), cw.opts.ContentTypes)
+
+	if compress {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		// the representation changed, so weaken any validator
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		cw.encoder = getEncoder(cw.encoding, cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(cw.buf.Bytes())
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf.Bytes())
+	}
+	cw.buf.Reset()
+	return err
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+func isUpgrade(r *http.Request) bool {
+	return r.Header.Get("Upgrade") != ""
+}
+
+func allowedContentType(contentType string, allowed []string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for _, prefix := range allowed {
+		if strings.HasSuffix(prefix, "/") && strings.HasPrefix(mediaType, prefix) || mediaType == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding returns the supported content coding with the
+// highest q value in the Accept-Encoding header, preferring the order
+// of compressEncodings when equal. Returns an empty string when none match
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qualities := map[string]float64{}
+	for _, qv := range util.ParseQualityList(acceptEncoding) {
+		qualities[qv.Value] = qv.Q
+	}
+
+	candidates := make([]string, 0, len(compressEncodings))
+	for _, encoding := range compressEncodings {
+		q, ok := qualities[encoding]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > 0 {
+			candidates = append(candidates, encoding)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return encodingQuality(qualities, candidates[i]) > encodingQuality(qualities, candidates[j])
+	})
+
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[0]
+}
+
+func encodingQuality(qualities map[string]float64, encoding string) float64 {
+	if q, ok := qualities[encoding]; ok {
+		return q
+	}
+	return qualities["*"]
+}
+
+var encoderPools = map[string]*sync.Pool{
+	"br":      {},
+	"zstd":    {},
+	"gzip":    {},
+	"deflate": {},
+}
+
+func getEncoder(encoding string, w io.Writer) io.WriteCloser {
+	if pooled := encoderPools[encoding].Get(); pooled != nil {
+		switch enc := pooled.(type) {
+		case *brotli.Writer:
+			enc.Reset(w)
+		case *zstd.Encoder:
+			enc.Reset(w)
+		case *gzip.Writer:
+			enc.Reset(w)
+		case *zlib.Writer:
+			enc.Reset(w)
+		}
+		return pooled.(io.WriteCloser)
+	}
+
+	switch encoding {
+	case "br":
+		return brotli.NewWriterLevel(w, brotli.DefaultCompression)
+	case "zstd":
+		enc, _ := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		return enc
+	case "gzip":
+		return gzip.NewWriter(w)
+	default:
+		return zlib.NewWriter(w)
+	}
+}
+
+func putEncoder(encoding string, enc io.WriteCloser) {
+	encoderPools[encoding].Put(enc)
+}
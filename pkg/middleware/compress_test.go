@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	var (
+		r   io.Reader
+		err error
+	)
+
+	switch encoding {
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(body))
+	case "zstd":
+		dec, decErr := zstd.NewReader(bytes.NewReader(body))
+		assert.Nil(t, decErr)
+		defer dec.Close()
+		r = dec
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	default:
+		return string(body)
+	}
+	assert.Nil(t, err)
+
+	out, err := ioutil.ReadAll(r)
+	assert.Nil(t, err)
+	return string(out)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := map[string]string{
+		"":                          "",
+		"identity":                  "",
+		"gzip":                      "gzip",
+		"gzip, deflate, br":         "br",
+		"gzip;q=1, br;q=0.5":        "gzip",
+		"*":                         "br",
+		"*, br;q=0":                 "zstd",
+		"deflate, gzip;q=0":         "deflate",
+		"compress, x-gzip, unknown": "",
+	}
+
+	for acceptEncoding, expected := range cases {
+		assert.Equal(t, expected, negotiateEncoding(acceptEncoding), acceptEncoding)
+	}
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"hello":"world"}`, 100)
+
+	respond := func(contentType, body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			w.Header().Set("Content-Length", "1")
+			w.Write([]byte(body))
+		})
+	}
+
+	serve := func(handler http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+
+		rr := httptest.NewRecorder()
+		Compress(CompressOptions{})(handler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, encoding := range compressEncodings {
+		t.Run(encoding, func(t *testing.T) {
+			rr := serve(respond("application/json", large), encoding)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, encoding, rr.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+			assert.Empty(t, rr.Header().Get("Content-Length"))
+			assert.Less(t, rr.Body.Len(), len(large))
+			assert.Equal(t, large, decompress(t, encoding, rr.Body.Bytes()))
+		})
+	}
+
+	t.Run("below minimum size", func(t *testing.T) {
+		rr := serve(respond("application/json", `{"hello":"world"}`), "gzip")
+
+		assert.Empty(t, rr.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+		assert.Equal(t, `{"hello":"world"}`, rr.Body.String())
+	})
+
+	t.Run("content type not allowed", func(t *testing.T) {
+		rr := serve(respond("image/png", large), "gzip")
+
+		assert.Empty(t, rr.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, rr.Body.String())
+	})
+
+	t.Run("sniffs content type", func(t *testing.T) {
+		rr := serve(respond("", strings.Repeat("plain text ", 200)), "gzip")
+
+		assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+		assert.Contains(t, rr.Header().Get("Content-Type"), "text/plain")
+	})
+
+	t.Run("already encoded", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte(large))
+		})
+		rr := serve(handler, "gzip")
+
+		assert.Equal(t, "br", rr.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, rr.Body.String())
+	})
+
+	t.Run("client does not accept encoding", func(t *testing.T) {
+		rr := serve(respond("application/json", large), "")
+
+		assert.Empty(t, rr.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+		assert.Equal(t, large, rr.Body.String())
+	})
+
+	t.Run("no body", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotModified)
+		})
+		rr := serve(handler, "gzip")
+
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Header().Get("Content-Encoding"))
+		assert.Equal(t, 0, rr.Body.Len())
+	})
+
+	t.Run("weakens etag", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("ETag", `"abc"`)
+			w.Write([]byte(large))
+		})
+		rr := serve(handler, "gzip")
+
+		assert.Equal(t, `W/"abc"`, rr.Header().Get("ETag"))
+	})
+
+	t.Run("streamed responses", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: 1\n\n"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("data: 2\n\n"))
+			w.(http.Flusher).Flush()
+		})
+		rr := serve(handler, "gzip")
+
+		assert.True(t, rr.Flushed)
+		assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+		assert.Equal(t, "data: 1\n\ndata: 2\n\n", decompress(t, "gzip", rr.Body.Bytes()))
+	})
+}
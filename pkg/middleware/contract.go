@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+	"github.com/riyadhalnur/godi/v2/pkg/openapi"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+// DefaultContractMaxBodyBytes is the largest response
+// body validated when not configured
+const DefaultContractMaxBodyBytes int64 = 1 << 20
+
+// ContractOptions configures the Contract middleware
+//
+// Validator (required) - checks the requests and responses e.g.
+// openapi.NewValidator(srv.OpenAPI(info))
+// Report - called with the violations of a request and its response.
+// Defaults to logging them as warnings
+// MaxBodyBytes - largest response body validated. Only the status of
+// larger and streamed responses is validated. Defaults to 1MB
+type ContractOptions struct {
+	Validator    *openapi.Validator
+	Report       func(r *http.Request, violations []openapi.Violation)
+	MaxBodyBytes int64
+}
+
+// Contract validates the requests and the responses of the routes against
+// the operations of their OpenAPI document, reporting the violations without
+// changing the responses. Meant for tests and staging environments
+func Contract(opts ContractOptions) func(http.Handler) http.Handler {
+	if opts.Report == nil {
+		opts.Report = LogViolations
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultContractMaxBodyBytes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// WebSocket connections are not documented
+			if r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				// the handler still gets the error e.g. of the body limit
+				r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{err}))
+				next.ServeHTTP(w, r)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			violations := opts.Validator.ValidateRequest(r, body)
+
+			rec := newResponseRecorder(w, opts.MaxBodyBytes)
+			next.ServeHTTP(rec, r)
+
+			// only the status of streamed responses is validated
+			resBody := rec.body.Bytes()
+			if rec.omitted || rec.flushed {
+				resBody = nil
+			}
+			violations = append(violations, opts.Validator.ValidateResponse(r, rec.status, rec.Header(), resBody)...)
+
+			if len(violations) > 0 {
+				opts.Report(r, violations)
+			}
+		})
+	}
+}
+
+// LogViolations logs each violation as a warning
+func LogViolations(r *http.Request, violations []openapi.Violation) {
+	requestID, _ := r.Context().Value(util.RequestIDKey).(string)
+	for _, v := range violations {
+		logger.Warn("Contract violation", "operation", v.Operation, "in", v.In,
+			"field", v.Field, "message", v.Message, "requestId", requestID)
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
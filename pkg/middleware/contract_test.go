@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/openapi"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+func TestContract(t *testing.T) {
+	validator := openapi.NewValidator(openapi.Generate(openapi.Info{}, []util.Route{
+		{
+			Name:    "createItem",
+			Path:    "/items",
+			Method:  http.MethodPost,
+			Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) { return nil, nil },
+			Doc: &util.RouteDoc{
+				Request:  &struct{ Name string }{},
+				Response: struct{ ID int }{},
+				Status:   http.StatusCreated,
+			},
+		},
+	}))
+
+	serve := func(opts ContractOptions, handler http.HandlerFunc, body string) (*httptest.ResponseRecorder, []openapi.Violation) {
+		var reported []openapi.Violation
+		opts.Validator = validator
+		opts.Report = func(r *http.Request, violations []openapi.Violation) {
+			reported = violations
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		Contract(opts)(handler).ServeHTTP(rr, req)
+		return rr, reported
+	}
+
+	created := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(body))
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		rr, violations := serve(ContractOptions{}, created(`{"ID":1}`), `{"Name":"pen"}`)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, `{"ID":1}`, rr.Body.String())
+		assert.Nil(t, violations)
+	})
+
+	t.Run("restores the request body", func(t *testing.T) {
+		echo := func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			created(`{"ID":`+strconv.Itoa(len(body))+`}`)(w, r)
+		}
+		rr, violations := serve(ContractOptions{}, echo, `{"Name":"pen"}`)
+
+		assert.Equal(t, `{"ID":14}`, rr.Body.String())
+		assert.Nil(t, violations)
+	})
+
+	t.Run("violations", func(t *testing.T) {
+		rr, violations := serve(ContractOptions{}, created(`{"ID":"1"}`), `{}`)
+
+		// the response is left unchanged
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, `{"ID":"1"}`, rr.Body.String())
+		assert.Equal(t, []openapi.Violation{
+			{Operation: "createItem", In: "request", Field: "body.Name", Message: "is required"},
+			{Operation: "createItem", In: "response", Field: "body.ID", Message: "is string, expected integer"},
+		}, violations)
+	})
+
+	t.Run("undocumented status", func(t *testing.T) {
+		ok := func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"ID":1}`))
+		}
+		_, violations := serve(ContractOptions{}, ok, `{"Name":"pen"}`)
+
+		assert.Equal(t, []openapi.Violation{
+			{Operation: "createItem", In: "response", Field: "status", Message: "200 is not documented"},
+		}, violations)
+	})
+
+	t.Run("large response", func(t *testing.T) {
+		rr, violations := serve(ContractOptions{MaxBodyBytes: 4}, created(`{"ID":"1"}`), `{"Name":"pen"}`)
+
+		assert.Equal(t, `{"ID":"1"}`, rr.Body.String())
+		assert.Nil(t, violations)
+	})
+
+	t.Run("WebSocket", func(t *testing.T) {
+		var reported bool
+		handler := Contract(ContractOptions{
+			Validator: validator,
+			Report:    func(*http.Request, []openapi.Violation) { reported = true },
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusSwitchingProtocols)
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set("Upgrade", "websocket")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.False(t, reported)
+	})
+}
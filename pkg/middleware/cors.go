@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	corsAllowedMethods string = "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsMaxAge         string = "600"
+)
+
+// CORS handles cross-origin requests for a list of
+// allowed origins. The origins can be changed while
+// the server is running using SetOrigins
+type CORS struct {
+	origins atomic.Value
+}
+
+// NewCORS returns a new instance of CORS
+// allowing the passed in origins. Use "*" to allow any origin
+func NewCORS(origins ...string) *CORS {
+	c := &CORS{}
+	c.SetOrigins(origins...)
+	return c
+}
+
+// SetOrigins replaces the list of allowed origins
+func (c *CORS) SetOrigins(origins ...string) {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+	c.origins.Store(allowed)
+}
+
+// Handler adds the CORS headers to responses for allowed origins
+// and answers preflight requests. Requests from other origins
+// are passed through untouched
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed := c.origins.Load().(map[string]bool)
+		w.Header().Add("Vary", "Origin")
+		if !allowed["*"] && !allowed[origin] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	const (
+		origin string = "https://example.com"
+	)
+
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("allowed origin", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		req.Header.Set("Origin", origin)
+
+		rr := httptest.NewRecorder()
+		NewCORS(origin).Handler(testHandler).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, origin, rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Origin", rr.Header().Get("Vary"))
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		req.Header.Set("Origin", "https://evil.com")
+
+		rr := httptest.NewRecorder()
+		NewCORS(origin).Handler(testHandler).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("preflight", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodOptions, "/", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+
+		rr := httptest.NewRecorder()
+		NewCORS("*").Handler(testHandler).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, origin, rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Content-Type", rr.Header().Get("Access-Control-Allow-Headers"))
+		assert.Contains(t, rr.Header().Get("Access-Control-Allow-Methods"), http.MethodPost)
+	})
+
+	t.Run("replace origins", func(t *testing.T) {
+		cors := NewCORS(origin)
+		cors.SetOrigins("https://other.com")
+
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		req.Header.Set("Origin", origin)
+
+		rr := httptest.NewRecorder()
+		cors.Handler(testHandler).ServeHTTP(rr, req)
+
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
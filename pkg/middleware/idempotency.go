@@ -0,0 +1,314 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+const (
+	// IdempotencyKeyHeader is the header clients
+	// set to make unsafe requests safe to retry
+	IdempotencyKeyHeader string = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed for retries
+	IdempotentReplayedHeader string = "Idempotent-Replayed"
+
+	// DefaultIdempotencyTTL is how long responses
+	// are kept for retries when not configured
+	DefaultIdempotencyTTL time.Duration = 24 * time.Hour
+
+	// maximum length of an idempotency key
+	maxIdempotencyKeyLength int = 255
+)
+
+// headers that belong to a single response
+// and are never replayed
+var unreplayedHeaders = map[string]bool{
+	"Content-Length": true,
+	"Date":           true,
+	"X-Request-Id":   true,
+}
+
+// IdempotencyRecord is what is stored for an idempotency key
+// Fingerprint - identifies the payload of the first request, to detect
+// keys reused for other requests
+// Response - the response of the first request. Nil while it is in flight
+type IdempotencyRecord struct {
+	Fingerprint string
+	Response    *IdempotentResponse
+}
+
+// IdempotentResponse is a stored response. Header keeps every
+// value of the headers e.g. of several Set-Cookie headers
+type IdempotentResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+}
+
+// IdempotencyStore stores the responses of requests by idempotency key.
+// Implementations must be safe for concurrent use
+type IdempotencyStore interface {
+	// Reserve claims the key for a request with the fingerprint for ttl.
+	// Returns the existing record and false when the key is already claimed
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, bool, error)
+	// Complete stores the response of the request that claimed the key for ttl
+	Complete(ctx context.Context, key string, res *IdempotentResponse, ttl time.Duration) error
+	// Release drops the claim on the key so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyOptions configures the idempotency middleware
+// Store - where responses are kept. Defaults to a MemoryIdempotencyStore
+// TTL - how long responses are kept for retries. Defaults to DefaultIdempotencyTTL
+type IdempotencyOptions struct {
+	Store IdempotencyStore
+	TTL   time.Duration
+}
+
+// Idempotency honors the Idempotency-Key header of POST and PATCH requests.
+// The response of the first request with a key is stored, scoped to the
+// principal making the request and the route, and replayed for retries.
+// Retries while the first request is in flight are rejected with a 409
+// and requests reusing a key for another payload with a 422. Responses
+// with a server error, or that fail to be stored, are released so the
+// request can be retried
+func Idempotency(opts IdempotencyOptions) func(http.Handler) http.Handler {
+	if opts.Store == nil {
+		opts.Store = NewMemoryIdempotencyStore()
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultIdempotencyTTL
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				util.RespondErr(w, r, godierr.InvalidArgsError(IdempotencyKeyHeader))
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				var godiErr *godierr.Error
+				if !errors.As(err, &godiErr) {
+					godiErr = godierr.InvalidArgsError("body")
+				}
+				util.RespondErr(w, r, godiErr)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			scopedKey := idempotencyScope(r, key)
+			fingerprint := idempotencyFingerprint(r, body)
+
+			record, reserved, err := opts.Store.Reserve(ctx, scopedKey, fingerprint, opts.TTL)
+			if err != nil {
+				util.RespondErr(w, r, godierr.UnavailableError(err))
+				return
+			}
+
+			if !reserved {
+				switch {
+				case record.Fingerprint != fingerprint:
+					util.RespondErr(w, r, godierr.UnprocessableError(IdempotencyKeyHeader))
+				case record.Response == nil:
+					util.RespondErr(w, r, godierr.ConflictError(IdempotencyKeyHeader))
+				default:
+					replay(w, record.Response)
+				}
+				return
+			}
+
+			rec := newResponseRecorder(w, 0)
+			completed := false
+			defer func() {
+				// the claim is dropped when the handler panics
+				if !completed {
+					releaseIdempotent(ctx, opts.Store, scopedKey)
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				releaseIdempotent(ctx, opts.Store, scopedKey)
+			} else if err := opts.Store.Complete(ctx, scopedKey, idempotentResponse(rec), opts.TTL); err != nil {
+				// retries would be rejected as in flight until the claim expires
+				logger.Warn("Unable to store idempotent response", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+				releaseIdempotent(ctx, opts.Store, scopedKey)
+			}
+			completed = true
+		})
+	}
+}
+
+// releaseIdempotent drops the claim on the key, logging failures
+// as the response is already sent
+func releaseIdempotent(ctx context.Context, store IdempotencyStore, key string) {
+	if err := store.Release(ctx, key); err != nil {
+		logger.Warn("Unable to release idempotency key", "error", err.Error())
+	}
+}
+
+// idempotencyScope scopes the key to the principal
+// making the request and to the route
+func idempotencyScope(r *http.Request, key string) string {
+	route := r.URL.Path
+	if current := mux.CurrentRoute(r); current != nil {
+		if tpl, err := current.GetPathTemplate(); err == nil {
+			route = tpl
+		}
+	}
+
+	principal, _ := r.Context().Value(util.PrincipalKey).(string)
+
+	h := sha256.New()
+	for _, part := range []string{principal, r.Method, route, key} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotencyFingerprint identifies the payload of the request
+func idempotencyFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay writes the stored response of a request
+func replay(w http.ResponseWriter, res *IdempotentResponse) {
+	for key, values := range res.Header {
+		if http.CanonicalHeaderKey(key) == "Vary" {
+			util.AddVary(w.Header(), values...)
+			continue
+		}
+		w.Header()[key] = append([]string(nil), values...)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+
+	w.WriteHeader(res.StatusCode)
+	w.Write(res.Body)
+}
+
+// idempotentResponse returns the response of the handler to store
+func idempotentResponse(rec *responseRecorder) *IdempotentResponse {
+	header := rec.header()
+	for key := range header {
+		if unreplayedHeaders[http.CanonicalHeaderKey(key)] {
+			delete(header, key)
+		}
+	}
+
+	return &IdempotentResponse{
+		StatusCode: rec.status,
+		Header:     header,
+		Body:       append([]byte(nil), rec.body.Bytes()...),
+	}
+}
+
+// MemoryIdempotencyStore keeps the responses in memory.
+// Suited to a single instance of the server
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]*memoryIdempotencyRecord
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryIdempotencyRecord struct {
+	IdempotencyRecord
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore returns a new instance of MemoryIdempotencyStore
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[string]*memoryIdempotencyRecord),
+		now:     time.Now,
+	}
+}
+
+// Reserve claims the key unless it is claimed and not expired
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now, ttl)
+
+	if record, ok := s.records[key]; ok && now.Before(record.expires) {
+		existing := record.IdempotencyRecord
+		return &existing, false, nil
+	}
+
+	s.records[key] = &memoryIdempotencyRecord{
+		IdempotencyRecord: IdempotencyRecord{
+			Fingerprint: fingerprint,
+		},
+		expires: now.Add(ttl),
+	}
+	return nil, true, nil
+}
+
+// Complete stores the response for the key
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key string, res *IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return godierr.NotFoundError(IdempotencyKeyHeader)
+	}
+	record.Response = res
+	record.expires = s.now().Add(ttl)
+
+	return nil
+}
+
+// Release drops the key
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// sweep drops expired records at most once per ttl.
+// Must be called with the lock held
+func (s *MemoryIdempotencyStore) sweep(now time.Time, ttl time.Duration) {
+	if now.Sub(s.lastSweep) < ttl {
+		return
+	}
+
+	for key, record := range s.records {
+		if !now.Before(record.expires) {
+			delete(s.records, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency(t *testing.T) {
+	newHandler := func(status int) (http.Handler, *int32) {
+		calls := new(int32)
+		handler := Idempotency(IdempotencyOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(calls, 1)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Location", "/orders/1")
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"call":%d}`, n)
+		}))
+		return handler, calls
+	}
+
+	send := func(handler http.Handler, method, key, body string, ctx context.Context) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/orders", strings.NewReader(body))
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		if ctx != nil {
+			req = req.WithContext(ctx)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("replays the first response", func(t *testing.T) {
+		handler, calls := newHandler(http.StatusCreated)
+
+		first := send(handler, http.MethodPost, "key-1", `{"item":"book"}`, nil)
+		retry := send(handler, http.MethodPost, "key-1", `{"item":"book"}`, nil)
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "/orders/1", retry.Header().Get("Location"))
+		assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+		assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+	})
+
+	t.Run("mismatched payload", func(t *testing.T) {
+		handler, calls := newHandler(http.StatusCreated)
+
+		send(handler, http.MethodPost, "key-1", `{"item":"book"}`, nil)
+		rr := send(handler, http.MethodPost, "key-1", `{"item":"pen"}`, nil)
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.JSONEq(t, `{"code":422,"type":"UNPROCESSABLE","message":"unable to process argument(s): Idempotency-Key"}`, rr.Body.String())
+	})
+
+	t.Run("concurrent duplicate", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		handler := Idempotency(IdempotencyOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			done <- send(handler, http.MethodPost, "key-1", `{}`, nil)
+		}()
+		<-started
+
+		rr := send(handler, http.MethodPost, "key-1", `{}`, nil)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.JSONEq(t, `{"code":409,"type":"CONFLICT","message":"conflict with the current state of resource(s): Idempotency-Key"}`, rr.Body.String())
+
+		close(release)
+		assert.Equal(t, http.StatusCreated, (<-done).Code)
+	})
+
+	t.Run("server errors are not stored", func(t *testing.T) {
+		handler, calls := newHandler(http.StatusInternalServerError)
+
+		send(handler, http.MethodPost, "key-1", `{}`, nil)
+		rr := send(handler, http.MethodPost, "key-1", `{}`, nil)
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+		assert.Equal(t, `{"call":2}`, rr.Body.String())
+	})
+
+	t.Run("replays every header value", func(t *testing.T) {
+		handler := Idempotency(IdempotencyOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Set-Cookie", "session=1")
+			w.Header().Add("Set-Cookie", "theme=dark")
+			w.Header().Add("Vary", "Accept")
+			w.Header().Add("Vary", "Accept-Language")
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		first := send(handler, http.MethodPost, "key-1", `{}`, nil)
+		retry := send(handler, http.MethodPost, "key-1", `{}`, nil)
+
+		assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+		assert.Equal(t, first.Header().Values("Set-Cookie"), retry.Header().Values("Set-Cookie"))
+		assert.Equal(t, []string{"Accept", "Accept-Language"}, retry.Header().Values("Vary"))
+	})
+
+	t.Run("stores the headers of the handler", func(t *testing.T) {
+		handler := Idempotency(IdempotencyOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			w.Header().Set("Location", "/orders/1")
+			w.WriteHeader(http.StatusCreated)
+		}))
+		// set by the CORS middleware for the origin of the request
+		cors := func(origin string) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Add("Vary", "Origin")
+				handler.ServeHTTP(w, r)
+			})
+		}
+
+		send(cors("https://a.example"), http.MethodPost, "key-1", `{}`, nil)
+		retry := send(cors("https://b.example"), http.MethodPost, "key-1", `{}`, nil)
+
+		assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+		assert.Equal(t, "https://b.example", retry.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, []string{"Origin", "Accept"}, retry.Header().Values("Vary"))
+		assert.Equal(t, "/orders/1", retry.Header().Get("Location"))
+	})
+
+	t.Run("released when the response is not stored", func(t *testing.T) {
+		calls := new(int32)
+		store := &failingIdempotencyStore{MemoryIdempotencyStore: NewMemoryIdempotencyStore()}
+		handler := Idempotency(IdempotencyOptions{Store: store})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(calls, 1)
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		first := send(handler, http.MethodPost, "key-1", `{}`, nil)
+		retry := send(handler, http.MethodPost, "key-1", `{}`, nil)
+
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	})
+
+	t.Run("scoped to the principal", func(t *testing.T) {
+		handler, calls := newHandler(http.StatusCreated)
+
+		send(handler, http.MethodPost, "key-1", `{}`, context.WithValue(context.Background(), util.PrincipalKey, "user-1"))
+		send(handler, http.MethodPost, "key-1", `{}`, context.WithValue(context.Background(), util.PrincipalKey, "user-2"))
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	})
+
+	t.Run("ignored", func(t *testing.T) {
+		cases := []struct {
+			name   string
+			method string
+			key    string
+		}{
+			{"without key", http.MethodPost, ""},
+			{"safe method", http.MethodGet, "key-1"},
+			{"put", http.MethodPut, "key-1"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				handler, calls := newHandler(http.StatusOK)
+
+				send(handler, c.method, c.key, ``, nil)
+				send(handler, c.method, c.key, ``, nil)
+
+				assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+			})
+		}
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		handler, calls := newHandler(http.StatusCreated)
+
+		rr := send(handler, http.MethodPost, strings.Repeat("k", 256), `{}`, nil)
+
+		assert.Equal(t, int32(0), atomic.LoadInt32(calls))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryIdempotencyStore()
+	store.now = func() time.Time { return now }
+
+	_, reserved, err := store.Reserve(ctx, "key", "fingerprint", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, reserved)
+
+	record, reserved, err := store.Reserve(ctx, "key", "fingerprint", time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, reserved)
+	assert.Nil(t, record.Response)
+
+	err = store.Complete(ctx, "key", &IdempotentResponse{StatusCode: http.StatusCreated}, time.Minute)
+	assert.Nil(t, err)
+
+	record, _, _ = store.Reserve(ctx, "key", "fingerprint", time.Minute)
+	assert.Equal(t, http.StatusCreated, record.Response.StatusCode)
+
+	now = now.Add(time.Minute)
+	_, reserved, _ = store.Reserve(ctx, "key", "fingerprint", time.Minute)
+	assert.True(t, reserved)
+
+	assert.Nil(t, store.Release(ctx, "key"))
+	_, reserved, _ = store.Reserve(ctx, "key", "fingerprint", time.Minute)
+	assert.True(t, reserved)
+
+	assert.NotNil(t, store.Complete(ctx, "missing", &IdempotentResponse{}, time.Minute))
+}
+
+// failingIdempotencyStore fails to store responses
+type failingIdempotencyStore struct {
+	*MemoryIdempotencyStore
+}
+
+func (s *failingIdempotencyStore) Complete(ctx context.Context, key string, res *IdempotentResponse, ttl time.Duration) error {
+	return errors.New("store unavailable")
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+const (
+	// buckets not used for this long are dropped
+	bucketIdleTTL time.Duration = 10 * time.Minute
+)
+
+// RateLimiter limits the number of requests each client
+// can make using a token bucket per remote address.
+// The limit can be changed while the server is running using SetLimit
+type RateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     int
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a new instance of RateLimiter allowing
+// rate requests per second with bursts of up to burst requests.
+// A rate of 0 disables rate limiting
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	l := &RateLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+	l.SetLimit(rate, burst)
+	return l
+}
+
+// SetLimit replaces the rate and burst of the limiter.
+// Existing clients keep their remaining tokens
+func (l *RateLimiter) SetLimit(rate float64, burst int) {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rate = rate
+	l.burst = burst
+}
+
+// Allow reports whether the client identified by key
+// can make a request now
+func (l *RateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return true
+	}
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// Handler rejects requests from clients that exceeded
+// the rate limit with a 429 error
+func (l *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.Allow(clientIP(r)) {
+			err := godierr.RateLimitedError()
+
+			w.Header().Set("Retry-After", strconv.Itoa(l.retryAfter()))
+			util.RespondErr(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *RateLimiter) retryAfter() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return 1
+	}
+	return int(math.Max(1, math.Ceil(1/l.rate)))
+}
+
+// sweep drops idle buckets. Must be called with the lock held
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketIdleTTL {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= bucketIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("allows bursts and refills", func(t *testing.T) {
+		now := time.Now()
+		limiter := NewRateLimiter(1, 2)
+		limiter.now = func() time.Time { return now }
+
+		assert.True(t, limiter.Allow("client"))
+		assert.True(t, limiter.Allow("client"))
+		assert.False(t, limiter.Allow("client"))
+		assert.True(t, limiter.Allow("other"))
+
+		now = now.Add(time.Second)
+		assert.True(t, limiter.Allow("client"))
+		assert.False(t, limiter.Allow("client"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		limiter := NewRateLimiter(0, 0)
+
+		for i := 0; i < 100; i++ {
+			assert.True(t, limiter.Allow("client"))
+		}
+	})
+
+	t.Run("change limit", func(t *testing.T) {
+		limiter := NewRateLimiter(0, 0)
+		limiter.SetLimit(1, 1)
+
+		assert.True(t, limiter.Allow("client"))
+		assert.False(t, limiter.Allow("client"))
+	})
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := NewRateLimiter(1, 1).Handler(testHandler)
+
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	req.RemoteAddr = "10.0.0.1:1234"
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"code":429,"type":"RATE_LIMITED","message":"too many requests"}`, rr.Body.String())
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+	"github.com/riyadhalnur/godi/v2/pkg/traffic"
+)
+
+// DefaultRecordMaxBodyBytes is the largest request
+// or response body recorded when not configured
+const DefaultRecordMaxBodyBytes int64 = 64 << 10
+
+// RecordOptions configures the Record middleware
+//
+// Writer (required) - writes the exchanges e.g. traffic.NewWriter(file)
+// Redactor - masks headers and fields before the exchanges are written.
+// Defaults to masking traffic.DefaultRedactHeaders
+// MaxBodyBytes - largest body recorded. Larger bodies are omitted. Defaults to 64KB
+// Skip - reports whether the request should not be recorded e.g. to sample traffic
+type RecordOptions struct {
+	Writer       *traffic.Writer
+	Redactor     *traffic.Redactor
+	MaxBodyBytes int64
+	Skip         func(r *http.Request) bool
+}
+
+// Record writes the requests and the responses of the routes, once
+// served, to be replayed against another version of the server with
+// traffic.Replayer. WebSocket connections are not recorded
+func Record(opts RecordOptions) func(http.Handler) http.Handler {
+	if opts.Redactor == nil {
+		opts.Redactor = traffic.NewRedactor(nil, nil)
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultRecordMaxBodyBytes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Upgrade") != "" || (opts.Skip != nil && opts.Skip(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			exchange := &traffic.Exchange{
+				Time: start,
+				Request: traffic.Request{
+					Method: r.Method,
+					URL:    r.URL.RequestURI(),
+					Header: r.Header.Clone(),
+				},
+			}
+			if route := mux.CurrentRoute(r); route != nil {
+				exchange.Route = route.GetName()
+			}
+
+			// reads one byte more than the limit to tell whether the body fits
+			body, err := ioutil.ReadAll(io.LimitReader(r.Body, opts.MaxBodyBytes+1))
+			switch {
+			case err != nil:
+				// the handler still gets the error e.g. of the body limit
+				r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{err}))
+				exchange.Request.Body.Omitted = true
+			case int64(len(body)) > opts.MaxBodyBytes:
+				r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+				exchange.Request.Body.Omitted = true
+			default:
+				r.Body = ioutil.NopCloser(bytes.NewReader(body))
+				exchange.Request.Body.Data = body
+			}
+
+			rec := newResponseRecorder(w, opts.MaxBodyBytes)
+			next.ServeHTTP(rec, r)
+
+			exchange.Duration = traffic.Duration(time.Since(start))
+			exchange.Response = traffic.Response{
+				Status: rec.status,
+				Header: rec.Header().Clone(),
+				Body:   traffic.Body{Data: rec.body.Bytes(), Omitted: rec.omitted},
+			}
+			if rec.omitted {
+				exchange.Response.Body.Data = nil
+			}
+
+			opts.Redactor.Redact(exchange)
+			if err := opts.Writer.Write(exchange); err != nil {
+				logger.Warn("Unable to record exchange", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/traffic"
+)
+
+func TestRecord(t *testing.T) {
+	// echoes the name of the body back with the id of the path
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"` + mux.Vars(r)["id"] + `","body":` + strconv.Quote(string(body)) + `}`))
+	}
+
+	record := func(opts RecordOptions, handler http.HandlerFunc) (http.Handler, *bytes.Buffer) {
+		var buf bytes.Buffer
+		opts.Writer = traffic.NewWriter(&buf)
+
+		router := mux.NewRouter()
+		router.Name("createItem").Path("/items/{id}").Handler(Record(opts)(handler))
+		return router, &buf
+	}
+
+	read := func(buf *bytes.Buffer) []*traffic.Exchange {
+		var exchanges []*traffic.Exchange
+		r := traffic.NewReader(buf)
+		for {
+			e, err := r.Next()
+			if err == io.EOF {
+				return exchanges
+			}
+			assert.Nil(t, err)
+			exchanges = append(exchanges, e)
+		}
+	}
+
+	t.Run("records and replays", func(t *testing.T) {
+		handler, buf := record(RecordOptions{
+			Redactor: traffic.NewRedactor(nil, []string{"secret"}),
+		}, echo)
+
+		req := httptest.NewRequest(http.MethodPost, "/items/1?secret=s3cr3t", strings.NewReader(`pen`))
+		req.Header.Set("Authorization", "Bearer s3cr3t")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, `{"id":"1","body":"pen"}`, rr.Body.String())
+		assert.NotContains(t, buf.String(), "s3cr3t")
+
+		exchanges := read(buf)
+		assert.Len(t, exchanges, 1)
+		e := exchanges[0]
+		assert.Equal(t, "createItem", e.Route)
+		assert.Equal(t, http.MethodPost, e.Request.Method)
+		assert.Equal(t, "/items/1?secret=%5BREDACTED%5D", e.Request.URL)
+		assert.Equal(t, traffic.Redacted, e.Request.Header.Get("Authorization"))
+		assert.Equal(t, "pen", string(e.Request.Body.Data))
+		assert.Equal(t, http.StatusCreated, e.Response.Status)
+		assert.Equal(t, `{"id":"1","body":"pen"}`, string(e.Response.Body.Data))
+
+		ts := httptest.NewServer(handler)
+		defer ts.Close()
+		replayer, err := traffic.NewReplayer(traffic.ReplayOptions{Target: ts.URL})
+		assert.Nil(t, err)
+
+		result, err := replayer.Replay(context.Background(), e)
+		assert.Nil(t, err)
+		assert.Empty(t, result.Diffs)
+	})
+
+	t.Run("large bodies", func(t *testing.T) {
+		handler, buf := record(RecordOptions{MaxBodyBytes: 8}, echo)
+
+		req := httptest.NewRequest(http.MethodPost, "/items/1", strings.NewReader(`a large body`))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		// the handler still reads the whole body
+		assert.Equal(t, `{"id":"1","body":"a large body"}`, rr.Body.String())
+
+		e := read(buf)[0]
+		assert.True(t, e.Request.Body.Omitted)
+		assert.Nil(t, e.Request.Body.Data)
+		assert.True(t, e.Response.Body.Omitted)
+		assert.Nil(t, e.Response.Body.Data)
+	})
+
+	t.Run("skipped", func(t *testing.T) {
+		handler, buf := record(RecordOptions{
+			Skip: func(r *http.Request) bool { return r.URL.Query().Get("skip") != "" },
+		}, echo)
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/items/1?skip=1", nil))
+
+		req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+		req.Header.Set("Upgrade", "websocket")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Empty(t, buf.String())
+	})
+}
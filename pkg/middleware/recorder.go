@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// responseRecorder writes the response through while keeping a copy
+// of its status and body. Bodies over maxBytes, when set, are omitted.
+// When buffered, the response is held back until it is sent, and
+// flushing, e.g. by streamed responses, sends what is buffered
+// and passes the rest through
+type responseRecorder struct {
+	http.ResponseWriter
+
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	maxBytes    int64
+	omitted     bool
+	buffered    bool
+	flushed     bool
+	// headers set before the handler ran e.g. by CORS
+	before http.Header
+}
+
+// newResponseRecorder returns a recorder of the response written to w
+// keeping up to maxBytes of the body. The body is not limited when 0
+func newResponseRecorder(w http.ResponseWriter, maxBytes int64) *responseRecorder {
+	return &responseRecorder{
+		ResponseWriter: w,
+		status:         http.StatusOK,
+		maxBytes:       maxBytes,
+		before:         w.Header().Clone(),
+	}
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	if !rec.holding() {
+		rec.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.holding() {
+		return rec.body.Write(b)
+	}
+	// what is passed through once flushed is not kept
+	if !rec.buffered {
+		rec.keep(b)
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// Flush sends the buffered response to the client
+func (rec *responseRecorder) Flush() {
+	if rec.holding() {
+		rec.send()
+	}
+	rec.flushed = true
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// holding reports whether the response is held back until sent
+func (rec *responseRecorder) holding() bool {
+	return rec.buffered && !rec.flushed
+}
+
+func (rec *responseRecorder) keep(b []byte) {
+	if rec.omitted {
+		return
+	}
+	if rec.maxBytes > 0 && int64(rec.body.Len()+len(b)) > rec.maxBytes {
+		rec.omitted = true
+		rec.body.Reset()
+		return
+	}
+	rec.body.Write(b)
+}
+
+// send writes the buffered response
+func (rec *responseRecorder) send() {
+	rec.ResponseWriter.WriteHeader(rec.status)
+	rec.ResponseWriter.Write(rec.body.Bytes())
+}
+
+// header returns the headers set since the recorder was created.
+// Headers set before keep only the values added to them
+func (rec *responseRecorder) header() http.Header {
+	header := make(http.Header)
+	for key, values := range rec.Header() {
+		if before := rec.before[key]; len(before) <= len(values) && equalValues(before, values[:len(before)]) {
+			values = values[len(before):]
+		}
+		if len(values) != 0 {
+			header[key] = append([]string(nil), values...)
+		}
+	}
+	return header
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	t.Run("writes through", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rec := newResponseRecorder(w, 0)
+
+		rec.WriteHeader(http.StatusCreated)
+		rec.Write([]byte("hello"))
+
+		assert.Equal(t, http.StatusCreated, rec.status)
+		assert.Equal(t, "hello", rec.body.String())
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "hello", w.Body.String())
+	})
+
+	t.Run("omits bodies over the limit", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rec := newResponseRecorder(w, 4)
+
+		rec.Write([]byte("hel"))
+		rec.Write([]byte("lo"))
+
+		assert.True(t, rec.omitted)
+		assert.Empty(t, rec.body.Bytes())
+		assert.Equal(t, "hello", w.Body.String())
+	})
+
+	t.Run("buffered", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rec := newResponseRecorder(w, 0)
+		rec.buffered = true
+
+		rec.WriteHeader(http.StatusAccepted)
+		rec.Write([]byte("hel"))
+		assert.Empty(t, w.Body.String())
+
+		rec.Flush()
+		rec.Write([]byte("lo"))
+
+		assert.True(t, rec.flushed)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "hello", w.Body.String())
+	})
+
+	t.Run("headers of the handler", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Add("Vary", "Origin")
+		rec := newResponseRecorder(w, 0)
+
+		rec.Header().Add("Vary", "Accept")
+		rec.Header().Set("Location", "/orders/1")
+
+		assert.Equal(t, http.Header{
+			"Vary":     {"Accept"},
+			"Location": {"/orders/1"},
+		}, rec.header())
+	})
+}
@@ -3,22 +3,44 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/riyadhalnur/godi/v2/pkg/server/util"
 
 	"github.com/gofrs/uuid"
 )
 
+// requestIDGenerator holds the func() string generating request IDs
+var requestIDGenerator atomic.Value
+
+func init() {
+	requestIDGenerator.Store(func() string {
+		requestID, _ := uuid.NewV4()
+		return requestID.String()
+	})
+}
+
+// SetRequestIDGenerator replaces the uuids of requests with the IDs
+// returned by generate e.g. to get predictable IDs in tests.
+// Returns a function restoring the previous generator
+func SetRequestIDGenerator(generate func() string) func() {
+	previous := requestIDGenerator.Load()
+	requestIDGenerator.Store(generate)
+	return func() {
+		requestIDGenerator.Store(previous)
+	}
+}
+
 // RequestID adds a uuid to all incoming requests
 // and attaches it to the context
 func RequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID, _ := uuid.NewV4()
+		requestID := requestIDGenerator.Load().(func() string)()
 
 		ctx := r.Context()
-		ctx = context.WithValue(r.Context(), util.RequestIDKey, requestID.String())
+		ctx = context.WithValue(r.Context(), util.RequestIDKey, requestID)
 
-		w.Header().Set("X-Request-ID", requestID.String())
+		w.Header().Set("X-Request-ID", requestID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -28,3 +28,22 @@ func TestRequestIDMiddleware(t *testing.T) {
 
 	assert.NotEmpty(t, rr.Header().Get("X-Request-ID"))
 }
+
+func TestSetRequestIDGenerator(t *testing.T) {
+	restore := SetRequestIDGenerator(func() string {
+		return "request-1"
+	})
+
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "request-1", r.Context().Value(util.RequestIDKey))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "request-1", rr.Header().Get("X-Request-ID"))
+
+	restore()
+	rr = httptest.NewRecorder()
+	RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Len(t, rr.Header().Get("X-Request-ID"), 36)
+}
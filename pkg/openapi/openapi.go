@@ -0,0 +1,308 @@
+// Package openapi generates an OpenAPI 3 document from the routes
+// of a server. Operations are documented using util.RouteDoc and
+// errors using the definitions of the godierr registry
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+const (
+	// Version is the version of the OpenAPI specification generated
+	Version string = "3.0.3"
+
+	jsonMediaType    string = "application/json"
+	errorSchemaRef   string = "#/components/schemas/Error"
+	errorResponseRef string = "#/components/responses/Error"
+)
+
+// Document is the root of an OpenAPI document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components *Components          `json:"components,omitempty"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem holds the operations of a path, keyed
+// by their lowercase method e.g. get
+type PathItem map[string]*Operation
+
+// Operation is a method of a path
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path or query parameter of an operation
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody is the body of the requests of an operation
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+// Response is a response of an operation, or
+// a reference to a response of the components
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a body in a media type
+type MediaType struct {
+	Schema  *Schema     `json:"schema,omitempty"`
+	Example interface{} `json:"example,omitempty"`
+}
+
+// Components holds the schemas and responses
+// referenced across the document
+type Components struct {
+	Schemas   map[string]*Schema   `json:"schemas,omitempty"`
+	Responses map[string]*Response `json:"responses,omitempty"`
+}
+
+// Generate returns the document of the routes. WebSocket
+// routes are left out, as OpenAPI cannot describe them
+func Generate(info Info, routes []util.Route) *Document {
+	definitions := godierr.Definitions()
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      map[string]*PathItem{},
+		Components: components(definitions),
+	}
+
+	byType := make(map[string]godierr.Definition, len(definitions))
+	for _, def := range definitions {
+		byType[def.Type] = def
+	}
+
+	for _, route := range routes {
+		if route.WebSocket != nil || route.Method == "" {
+			continue
+		}
+
+		path, params := convertPath(route.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(route.Method)] = operation(route, params, byType)
+	}
+
+	return doc
+}
+
+// operation documents the route
+func operation(route util.Route, params []*Parameter, byType map[string]godierr.Definition) *Operation {
+	op := &Operation{
+		OperationID: route.Name,
+		Parameters:  params,
+		Responses: map[string]*Response{
+			"default": {Ref: errorResponseRef},
+		},
+	}
+
+	routeDoc := route.Doc
+	if routeDoc == nil {
+		routeDoc = &util.RouteDoc{}
+	}
+	op.Summary = routeDoc.Summary
+	op.Description = routeDoc.Description
+	op.Tags = routeDoc.Tags
+
+	for _, q := range routeDoc.Query {
+		t := q.Type
+		if t == "" {
+			t = "string"
+		}
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:        q.Name,
+			In:          "query",
+			Description: q.Description,
+			Required:    q.Required,
+			Schema:      &Schema{Type: t},
+		})
+	}
+
+	if routeDoc.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				jsonMediaType: {Schema: SchemaOf(routeDoc.Request)},
+			},
+		}
+	}
+
+	status := routeDoc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if routeDoc.Response != nil {
+		success.Content = map[string]*MediaType{
+			jsonMediaType: {Schema: SchemaOf(routeDoc.Response)},
+		}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	// errors sharing a status are documented together
+	byStatus := map[int][]godierr.Definition{}
+	for _, t := range routeDoc.Errors {
+		if def, ok := byType[t]; ok {
+			byStatus[def.HTTPStatus] = append(byStatus[def.HTTPStatus], def)
+		}
+	}
+	for status, defs := range byStatus {
+		op.Responses[strconv.Itoa(status)] = errorResponse(defs)
+	}
+
+	return op
+}
+
+// errorResponse documents the errors returned with the same status
+func errorResponse(defs []godierr.Definition) *Response {
+	if len(defs) == 1 {
+		return &Response{Ref: "#/components/responses/" + defs[0].Type}
+	}
+
+	descriptions := make([]string, len(defs))
+	for i, def := range defs {
+		descriptions[i] = def.Type + ": " + def.Description
+	}
+	return &Response{
+		Description: strings.Join(descriptions, "; "),
+		Content: map[string]*MediaType{
+			jsonMediaType: {Schema: &Schema{Ref: errorSchemaRef}},
+		},
+	}
+}
+
+// components returns the error schema and a response per error type
+func components(definitions []godierr.Definition) *Components {
+	types := make([]interface{}, len(definitions))
+	for i, def := range definitions {
+		types[i] = def.Type
+	}
+
+	c := &Components{
+		Schemas: map[string]*Schema{
+			"Error": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"code":    {Type: "integer"},
+					"type":    {Type: "string", Enum: types},
+					"message": {Type: "string"},
+					"id":      {Type: "string", Description: "ID of server errors in the logs"},
+					"causes":  {Type: "array", Items: &Schema{Type: "string"}, Description: "Errors that caused the error, in debug mode"},
+				},
+				// type and message are omitted when empty
+				Required: []string{"code"},
+			},
+		},
+		Responses: map[string]*Response{
+			"Error": {
+				Description: "Error",
+				Content: map[string]*MediaType{
+					jsonMediaType: {Schema: &Schema{Ref: errorSchemaRef}},
+				},
+			},
+		},
+	}
+
+	for _, def := range definitions {
+		c.Responses[def.Type] = &Response{
+			Description: def.Description,
+			Content: map[string]*MediaType{
+				jsonMediaType: {
+					Schema: &Schema{Ref: errorSchemaRef},
+					Example: map[string]interface{}{
+						"code":    def.HTTPStatus,
+						"type":    def.Type,
+						"message": def.Message,
+					},
+				},
+			},
+		}
+	}
+
+	return c
+}
+
+// convertPath turns the variables of a mux path template into OpenAPI
+// path parameters e.g. /users/{id:[0-9]+} into /users/{id}
+func convertPath(template string) (string, []*Parameter) {
+	var (
+		path   strings.Builder
+		params []*Parameter
+	)
+
+	for i := 0; i < len(template); i++ {
+		if template[i] != '{' {
+			path.WriteByte(template[i])
+			continue
+		}
+
+		// patterns can hold braces e.g. {code:[a-z]{2}}
+		depth, end := 0, i
+		for ; end < len(template); end++ {
+			if template[end] == '{' {
+				depth++
+			} else if template[end] == '}' {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+
+		variable := template[i+1 : end]
+		name, pattern := variable, ""
+		if idx := strings.Index(variable, ":"); idx != -1 {
+			name, pattern = variable[:idx], variable[idx+1:]
+		}
+
+		schema := &Schema{Type: "string"}
+		if pattern != "" {
+			schema.Pattern = "^" + pattern + "$"
+		}
+		params = append(params, &Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+		path.WriteString("{" + name + "}")
+		i = end
+	}
+
+	return path.String(), params
+}
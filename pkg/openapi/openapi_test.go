@@ -0,0 +1,128 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type address struct {
+	City string `json:"city"`
+}
+
+type user struct {
+	address
+	ID        int64           `json:"id"`
+	Name      string          `json:"name"`
+	Email     *string         `json:"email"`
+	Tags      []string        `json:"tags,omitempty"`
+	Meta      map[string]int  `json:"meta,omitempty"`
+	Avatar    []byte          `json:"avatar,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Friends   []user          `json:"friends,omitempty"`
+	Extra     interface{}     `json:"extra,omitempty"`
+	Raw       json.RawMessage `json:"raw,omitempty"`
+	Internal  string          `json:"-"`
+	Untagged  bool
+	secret    string
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+func handler(ctx context.Context, req *util.Request) (*util.Response, error) {
+	return nil, nil
+}
+
+func TestGenerate(t *testing.T) {
+	doc := Generate(Info{Title: "Users", Version: "1.0.0"}, []util.Route{
+		{
+			Name:    "getUser",
+			Path:    "/users/{id:[0-9]+}",
+			Method:  http.MethodGet,
+			Handler: handler,
+			Doc: &util.RouteDoc{
+				Summary:  "Get a user",
+				Tags:     []string{"users"},
+				Query:    []util.QueryParam{{Name: "fields", Description: "fields to include"}, {Name: "limit", Type: "integer", Required: true}},
+				Response: user{},
+				Errors:   []string{godierr.NotFoundType, godierr.RequiredArgType, godierr.InvalidArgType},
+			},
+		},
+		{
+			Name:    "createUser",
+			Path:    "/users",
+			Method:  http.MethodPost,
+			Handler: handler,
+			Doc: &util.RouteDoc{
+				Request:  &user{},
+				Response: user{},
+				Status:   http.StatusCreated,
+			},
+		},
+		{
+			Name:      "events",
+			Path:      "/events",
+			WebSocket: &util.WebSocket{},
+		},
+	})
+
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Len(t, doc.Paths, 2)
+
+	get := (*doc.Paths["/users/{id}"])["get"]
+	assert.Equal(t, "getUser", get.OperationID)
+	assert.Equal(t, "Get a user", get.Summary)
+	assert.Equal(t, []*Parameter{
+		{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string", Pattern: "^[0-9]+$"}},
+		{Name: "fields", In: "query", Description: "fields to include", Schema: &Schema{Type: "string"}},
+		{Name: "limit", In: "query", Required: true, Schema: &Schema{Type: "integer"}},
+	}, get.Parameters)
+	assert.Nil(t, get.RequestBody)
+	assert.Equal(t, "OK", get.Responses["200"].Description)
+	assert.Equal(t, &Response{Ref: "#/components/responses/NOT_FOUND"}, get.Responses["404"])
+	assert.Equal(t, "REQUIRED_ARGUMENT: A required argument is missing; INVALID_ARGUMENT: An argument is malformed or out of range", get.Responses["400"].Description)
+	assert.Equal(t, &Response{Ref: "#/components/responses/Error"}, get.Responses["default"])
+
+	create := (*doc.Paths["/users"])["post"]
+	assert.True(t, create.RequestBody.Required)
+	assert.True(t, create.RequestBody.Content["application/json"].Schema.Nullable)
+	assert.Equal(t, "Created", create.Responses["201"].Description)
+
+	notFound := doc.Components.Responses[godierr.NotFoundType]
+	assert.Equal(t, "The requested resource does not exist", notFound.Description)
+	assert.Equal(t, godierr.NotFoundType, notFound.Content["application/json"].Example.(map[string]interface{})["type"])
+	assert.Contains(t, doc.Components.Schemas["Error"].Properties["type"].Enum, godierr.UnavailableType)
+
+	_, err := json.Marshal(doc)
+	assert.Nil(t, err)
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := SchemaOf(user{})
+
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, []string{"city", "id", "name", "createdAt", "Untagged"}, s.Required)
+	assert.Equal(t, &Schema{Type: "string"}, s.Properties["city"])
+	assert.Equal(t, &Schema{Type: "integer", Format: "int64"}, s.Properties["id"])
+	assert.Equal(t, &Schema{Type: "string", Nullable: true}, s.Properties["email"])
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}, Nullable: true}, s.Properties["tags"])
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer", Format: "int32"}, Nullable: true}, s.Properties["meta"])
+	assert.Equal(t, &Schema{Type: "string", Format: "byte", Nullable: true}, s.Properties["avatar"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, s.Properties["createdAt"])
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "object"}, Nullable: true}, s.Properties["friends"])
+	assert.Equal(t, &Schema{}, s.Properties["extra"])
+	assert.Equal(t, &Schema{}, s.Properties["raw"])
+	assert.Equal(t, &Schema{Type: "boolean"}, s.Properties["Untagged"])
+	assert.NotContains(t, s.Properties, "Internal")
+	assert.NotContains(t, s.Properties, "secret")
+
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "number", Format: "double"}, Nullable: true}, SchemaOf([]float64{}))
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "integer", Format: "int32"}}, SchemaOf([2]int{}))
+	assert.Equal(t, &Schema{}, SchemaOf(nil))
+}
@@ -0,0 +1,374 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+const (
+	schemaRefPrefix   string = "#/components/schemas/"
+	responseRefPrefix string = "#/components/responses/"
+)
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Violation is a difference between a request
+// or a response and the operation documenting it
+//
+// In - request or response
+// Field - what differs e.g. path, query.limit, status, body.name
+type Violation struct {
+	Operation string
+	In        string
+	Field     string
+	Message   string
+}
+
+func (v Violation) Error() string {
+	if v.Operation == "" {
+		return fmt.Sprintf("%s %s: %s", v.In, v.Field, v.Message)
+	}
+	return fmt.Sprintf("%s: %s %s: %s", v.Operation, v.In, v.Field, v.Message)
+}
+
+// Validator checks requests and responses against the
+// operations of a document, e.g. to catch handlers
+// drifting from the published contract in tests
+type Validator struct {
+	doc   *Document
+	paths []*pathMatcher
+}
+
+type pathMatcher struct {
+	template string
+	pattern  *regexp.Regexp
+	params   []string
+	item     *PathItem
+}
+
+// NewValidator returns the validator of the document
+func NewValidator(doc *Document) *Validator {
+	v := &Validator{doc: doc}
+	for template, item := range doc.Paths {
+		m := &pathMatcher{template: template, item: item}
+
+		var pattern strings.Builder
+		pattern.WriteString("^")
+		last := 0
+		for _, loc := range pathParam.FindAllStringSubmatchIndex(template, -1) {
+			pattern.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+			pattern.WriteString("([^/]+)")
+			m.params = append(m.params, template[loc[2]:loc[3]])
+			last = loc[1]
+		}
+		pattern.WriteString(regexp.QuoteMeta(template[last:]))
+		pattern.WriteString("/?$")
+
+		m.pattern = regexp.MustCompile(pattern.String())
+		v.paths = append(v.paths, m)
+	}
+
+	// static paths take precedence e.g. /users/me over /users/{id}
+	sort.Slice(v.paths, func(i, j int) bool {
+		if len(v.paths[i].params) != len(v.paths[j].params) {
+			return len(v.paths[i].params) < len(v.paths[j].params)
+		}
+		return v.paths[i].template < v.paths[j].template
+	})
+	return v
+}
+
+// match returns the operation of the request and its path parameters
+func (v *Validator) match(method, path string) (*Operation, map[string]string, bool) {
+	for _, m := range v.paths {
+		values := m.pattern.FindStringSubmatch(path)
+		if values == nil {
+			continue
+		}
+
+		op, ok := (*m.item)[strings.ToLower(method)]
+		if !ok {
+			continue
+		}
+		params := make(map[string]string, len(m.params))
+		for i, name := range m.params {
+			params[name] = values[i+1]
+		}
+		return op, params, true
+	}
+	return nil, nil, false
+}
+
+// ValidateRequest checks the path, the parameters and the body of the
+// request against its operation. The body is passed in, as the one
+// of the request may have been read already
+func (v *Validator) ValidateRequest(r *http.Request, body []byte) []Violation {
+	op, params, ok := v.match(r.Method, r.URL.Path)
+	if !ok {
+		return []Violation{{In: "request", Field: "path", Message: fmt.Sprintf("no operation documents %s %s", r.Method, r.URL.Path)}}
+	}
+
+	violations := []Violation{}
+	add := func(field, format string, args ...interface{}) {
+		violations = append(violations, Violation{Operation: op.OperationID, In: "request", Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	query := r.URL.Query()
+	for _, param := range op.Parameters {
+		var (
+			value   string
+			present bool
+		)
+		switch param.In {
+		case "path":
+			value, present = params[param.Name]
+		case "query":
+			_, present = query[param.Name]
+			value = query.Get(param.Name)
+		default:
+			continue
+		}
+
+		field := param.In + "." + param.Name
+		if !present {
+			if param.Required {
+				add(field, "is required")
+			}
+			continue
+		}
+		if param.Schema != nil {
+			if msg := checkParam(param.Schema, value); msg != "" {
+				add(field, msg)
+			}
+		}
+	}
+
+	if op.RequestBody == nil {
+		return violations
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			add("body", "is required")
+		}
+		return violations
+	}
+
+	media, ok := op.RequestBody.Content[jsonMediaType]
+	if !ok || media.Schema == nil || !util.IsJSON(r.Header.Get("Content-Type")) {
+		return violations
+	}
+	for _, violation := range v.validateBody(media.Schema, body) {
+		add(violation.Field, violation.Message)
+	}
+	return violations
+}
+
+// ValidateResponse checks the status and the body of the response to the
+// request against its operation. Errors use the default response when
+// their status is not documented
+func (v *Validator) ValidateResponse(r *http.Request, status int, header http.Header, body []byte) []Violation {
+	op, _, ok := v.match(r.Method, r.URL.Path)
+	if !ok {
+		return []Violation{{In: "response", Field: "path", Message: fmt.Sprintf("no operation documents %s %s", r.Method, r.URL.Path)}}
+	}
+
+	violations := []Violation{}
+	add := func(field, format string, args ...interface{}) {
+		violations = append(violations, Violation{Operation: op.OperationID, In: "response", Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	res, ok := op.Responses[strconv.Itoa(status)]
+	if !ok && status >= http.StatusBadRequest {
+		res, ok = op.Responses["default"]
+	}
+	if !ok {
+		add("status", "%d is not documented", status)
+		return violations
+	}
+	res = v.resolveResponse(res)
+
+	media, ok := res.Content[jsonMediaType]
+	if !ok || media.Schema == nil || len(bytes.TrimSpace(body)) == 0 || !util.IsJSON(header.Get("Content-Type")) {
+		return violations
+	}
+	for _, violation := range v.validateBody(media.Schema, body) {
+		add(violation.Field, violation.Message)
+	}
+	return violations
+}
+
+func (v *Validator) resolveResponse(res *Response) *Response {
+	if res.Ref == "" || v.doc.Components == nil {
+		return res
+	}
+	if resolved, ok := v.doc.Components.Responses[strings.TrimPrefix(res.Ref, responseRefPrefix)]; ok {
+		return resolved
+	}
+	return res
+}
+
+func (v *Validator) resolveSchema(s *Schema) *Schema {
+	if s.Ref == "" || v.doc.Components == nil {
+		return s
+	}
+	if resolved, ok := v.doc.Components.Schemas[strings.TrimPrefix(s.Ref, schemaRefPrefix)]; ok {
+		return resolved
+	}
+	return s
+}
+
+// validateBody decodes the JSON body and checks it against the schema
+func (v *Validator) validateBody(schema *Schema, body []byte) []Violation {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return []Violation{{Field: "body", Message: "is not valid JSON: " + err.Error()}}
+	}
+
+	violations := []Violation{}
+	v.validateValue(schema, value, "body", &violations)
+	return violations
+}
+
+func (v *Validator) validateValue(schema *Schema, value interface{}, field string, violations *[]Violation) {
+	schema = v.resolveSchema(schema)
+	add := func(format string, args ...interface{}) {
+		*violations = append(*violations, Violation{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if value == nil {
+		if !schema.Nullable && schema.Type != "" {
+			add("is null, expected %s", schema.Type)
+		}
+		return
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		add("%v is not one of %v", value, schema.Enum)
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			add("is %s, expected object", jsonType(value))
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				*violations = append(*violations, Violation{Field: field + "." + name, Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := schema.Properties[name]; ok {
+				v.validateValue(prop, obj[name], field+"."+name, violations)
+			} else if schema.AdditionalProperties != nil {
+				v.validateValue(schema.AdditionalProperties, obj[name], field+"."+name, violations)
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			add("is %s, expected array", jsonType(value))
+			return
+		}
+		if schema.Items != nil {
+			for i, item := range arr {
+				v.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", field, i), violations)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			add("is %s, expected string", jsonType(value))
+			return
+		}
+		if schema.Pattern != "" {
+			if matched, err := regexp.MatchString(schema.Pattern, str); err == nil && !matched {
+				add("%q does not match %s", str, schema.Pattern)
+			}
+		}
+	case "integer":
+		num, ok := value.(json.Number)
+		if !ok {
+			add("is %s, expected integer", jsonType(value))
+			return
+		}
+		if _, err := num.Int64(); err != nil {
+			add("%s is not an integer", num)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			add("is %s, expected number", jsonType(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			add("is %s, expected boolean", jsonType(value))
+		}
+	}
+}
+
+// checkParam checks a path or query parameter against
+// its schema, returning the violation if any
+func checkParam(schema *Schema, value string) string {
+	switch schema.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Sprintf("%q is not an integer", value)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Sprintf("%q is not a number", value)
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Sprintf("%q is not a boolean", value)
+		}
+	}
+	if schema.Pattern != "" {
+		if matched, err := regexp.MatchString(schema.Pattern, value); err == nil && !matched {
+			return fmt.Sprintf("%q does not match %s", value, schema.Pattern)
+		}
+	}
+	return ""
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
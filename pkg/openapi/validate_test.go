@@ -0,0 +1,148 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type profile struct {
+	ID    int64   `json:"id"`
+	Name  string  `json:"name"`
+	Email *string `json:"email"`
+	Tags  []int   `json:"tags,omitempty"`
+}
+
+func validator() *Validator {
+	return NewValidator(Generate(Info{Title: "Users", Version: "1.0.0"}, []util.Route{
+		{
+			Name:    "getUser",
+			Path:    "/users/{id:[0-9]+}",
+			Method:  http.MethodGet,
+			Handler: handler,
+			Doc: &util.RouteDoc{
+				Query:    []util.QueryParam{{Name: "limit", Type: "integer", Required: true}},
+				Response: profile{},
+				Errors:   []string{godierr.NotFoundType},
+			},
+		},
+		{
+			Name:    "getMe",
+			Path:    "/users/me",
+			Method:  http.MethodGet,
+			Handler: handler,
+		},
+		{
+			Name:    "createUser",
+			Path:    "/users",
+			Method:  http.MethodPost,
+			Handler: handler,
+			Doc: &util.RouteDoc{
+				Request:  &profile{},
+				Response: profile{},
+				Status:   http.StatusCreated,
+			},
+		},
+	}))
+}
+
+func messages(violations []Violation) []string {
+	msgs := make([]string, len(violations))
+	for i, v := range violations {
+		msgs[i] = v.Error()
+	}
+	return msgs
+}
+
+func TestValidateRequest(t *testing.T) {
+	v := validator()
+
+	cases := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		expected []string
+	}{
+		{"valid", http.MethodGet, "/users/1?limit=10", "", []string{}},
+		{"static path first", http.MethodGet, "/users/me", "", []string{}},
+		{"undocumented path", http.MethodGet, "/posts", "", []string{"request path: no operation documents GET /posts"}},
+		{"undocumented method", http.MethodDelete, "/users/1", "", []string{"request path: no operation documents DELETE /users/1"}},
+		{"missing query", http.MethodGet, "/users/1", "", []string{"getUser: request query.limit: is required"}},
+		{"invalid query", http.MethodGet, "/users/1?limit=ten", "", []string{`getUser: request query.limit: "ten" is not an integer`}},
+		{"valid body", http.MethodPost, "/users", `{"id":1,"name":"Jane","email":null}`, []string{}},
+		{"missing body", http.MethodPost, "/users", "", []string{"createUser: request body: is required"}},
+		{"invalid JSON", http.MethodPost, "/users", `{"id":`, []string{"createUser: request body: is not valid JSON: unexpected EOF"}},
+		{"invalid body", http.MethodPost, "/users", `{"id":1.5,"tags":["a"]}`, []string{
+			"createUser: request body.name: is required",
+			"createUser: request body.id: 1.5 is not an integer",
+			"createUser: request body.tags[0]: is string, expected integer",
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+			assert.Equal(t, c.expected, messages(v.ValidateRequest(req, []byte(c.body))))
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	v := validator()
+
+	cases := []struct {
+		name        string
+		target      string
+		status      int
+		contentType string
+		body        string
+		expected    []string
+	}{
+		{"valid", "/users/1", http.StatusOK, "application/json", `{"id":1,"name":"Jane","email":"jane@example.com"}`, []string{}},
+		{"null list", "/users/1", http.StatusOK, "application/json", `{"id":1,"name":"Jane","email":null,"tags":null}`, []string{}},
+		{"invalid body", "/users/1", http.StatusOK, "application/json", `{"id":"1","name":"Jane","email":null}`, []string{
+			"getUser: response body.id: is string, expected integer",
+		}},
+		{"not JSON", "/users/1", http.StatusOK, "text/plain", `id: 1`, []string{}},
+		{"undocumented status", "/users/1", http.StatusAccepted, "application/json", `{}`, []string{
+			"getUser: response status: 202 is not documented",
+		}},
+		{"documented error", "/users/1", http.StatusNotFound, "application/json", `{"code":404,"type":"NOT_FOUND","message":"Not found"}`, []string{}},
+		{"error without message", "/users/1", http.StatusNotFound, "application/json", `{"code":404}`, []string{}},
+		{"invalid error", "/users/1", http.StatusNotFound, "application/json", `{"code":"404","type":"NOT_FOUND"}`, []string{
+			"getUser: response body.code: is string, expected integer",
+		}},
+		{"error without code", "/users/1", http.StatusNotFound, "application/json", `{"type":"NOT_FOUND"}`, []string{
+			"getUser: response body.code: is required",
+		}},
+		{"default error", "/users/1", http.StatusServiceUnavailable, "application/json", `{"code":503,"type":"UNAVAILABLE","message":"Unavailable"}`, []string{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.target, nil)
+			header := http.Header{"Content-Type": []string{c.contentType}}
+
+			assert.Equal(t, c.expected, messages(v.ValidateResponse(req, c.status, header, []byte(c.body))))
+		})
+	}
+
+	t.Run("error type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		header := http.Header{"Content-Type": []string{"application/json"}}
+
+		violations := v.ValidateResponse(req, http.StatusNotFound, header, []byte(`{"code":404,"type":"MISSING","message":"Missing"}`))
+		assert.Len(t, violations, 1)
+		assert.Equal(t, "body.type", violations[0].Field)
+		assert.Contains(t, violations[0].Message, "MISSING is not one of")
+	})
+}
@@ -0,0 +1,302 @@
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DefaultHandlerDir holds the handlers of services created using New
+	DefaultHandlerDir string = "pkg/handlers"
+	// RoutesMarker is the comment of the routes file
+	// that generated routes are registered above
+	RoutesMarker string = "// godi:routes"
+)
+
+var (
+	handlerName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+	methods = map[string]string{
+		http.MethodGet:     "Get",
+		http.MethodHead:    "Head",
+		http.MethodPost:    "Post",
+		http.MethodPut:     "Put",
+		http.MethodPatch:   "Patch",
+		http.MethodDelete:  "Delete",
+		http.MethodOptions: "Options",
+	}
+)
+
+// Handler describes a handler to generate
+//
+// Name (required) - name of the handler, in any case e.g. get-user, get_user or getUser
+// Dir - package directory of the handler. Defaults to pkg/handlers
+// Method - HTTP method of the route. Defaults to GET
+// Path - path of the route. Defaults to the name in kebab case e.g. /get-user
+// RoutesFile - file holding the routes marker, relative
+// to the directory. Defaults to routes.go
+type Handler struct {
+	Name       string
+	Dir        string
+	Method     string
+	Path       string
+	RoutesFile string
+}
+
+// handlerData is passed to the handler templates
+type handlerData struct {
+	Package     string
+	Func        string
+	Route       string
+	Method      string
+	MethodConst string
+	Path        string
+}
+
+// GenerateHandler writes an util.APIHandlerFunc and its test to
+// the package directory and registers its route above the marker
+// of the routes file. Returns the paths of the files written
+func GenerateHandler(h Handler) ([]string, error) {
+	if !handlerName.MatchString(h.Name) {
+		return nil, fmt.Errorf("scaffold: invalid handler name %q", h.Name)
+	}
+	words := splitWords(h.Name)
+	if h.Dir == "" {
+		h.Dir = filepath.FromSlash(DefaultHandlerDir)
+	}
+	if h.Method == "" {
+		h.Method = http.MethodGet
+	}
+	if h.Path == "" {
+		h.Path = "/" + strings.Join(words, "-")
+	}
+	if h.RoutesFile == "" {
+		h.RoutesFile = "routes.go"
+	}
+
+	method := strings.ToUpper(h.Method)
+	methodConst, ok := methods[method]
+	if !ok {
+		return nil, fmt.Errorf("scaffold: unsupported method %q", h.Method)
+	}
+	if !strings.HasPrefix(h.Path, "/") {
+		return nil, fmt.Errorf("scaffold: path %q does not start with /", h.Path)
+	}
+	// the path is written in a comment of the handler as well
+	if strings.IndexFunc(h.Path, func(r rune) bool { return unicode.IsSpace(r) || !unicode.IsPrint(r) }) != -1 {
+		return nil, fmt.Errorf("scaffold: path %q has spaces or control characters", h.Path)
+	}
+
+	routesFile := filepath.Join(h.Dir, h.RoutesFile)
+	routes, err := os.ReadFile(routesFile)
+	if err != nil {
+		return nil, fmt.Errorf("scaffold: %w", err)
+	}
+	if !bytes.Contains(routes, []byte(RoutesMarker)) {
+		return nil, fmt.Errorf("scaffold: %s has no %s marker to register the route above", routesFile, RoutesMarker)
+	}
+
+	pkg, err := parser.ParseFile(token.NewFileSet(), routesFile, routes, parser.PackageClauseOnly)
+	if err != nil {
+		return nil, fmt.Errorf("scaffold: %w", err)
+	}
+
+	data := handlerData{
+		Package:     pkg.Name.Name,
+		Func:        camelCase(words, true),
+		Route:       camelCase(words, false),
+		Method:      method,
+		MethodConst: methodConst,
+		Path:        h.Path,
+	}
+	base := filepath.Join(h.Dir, strings.Join(words, "_"))
+	files := []struct{ name, template string }{
+		{name: base + ".go", template: "templates/handler/handler.go.tmpl"},
+		{name: base + "_test.go", template: "templates/handler/handler_test.go.tmpl"},
+	}
+
+	// nothing is written when any of the files exist
+	for _, f := range files {
+		if _, err := os.Stat(f.name); err == nil {
+			return nil, fmt.Errorf("scaffold: %s already exists", f.name)
+		}
+	}
+
+	route, err := renderFile("templates/handler/route.tmpl", data)
+	if err != nil {
+		return nil, err
+	}
+	routes, err = register(routes, route)
+	if err != nil {
+		return nil, fmt.Errorf("scaffold: registering the route in %s: %w", routesFile, err)
+	}
+
+	written := []string{}
+	for _, f := range files {
+		content, err := renderFile(f.template, data)
+		if err != nil {
+			return written, err
+		}
+		if err := writeFile(f.name, content); err != nil {
+			return written, err
+		}
+		written = append(written, f.name)
+	}
+
+	if err := os.WriteFile(routesFile, routes, 0644); err != nil {
+		return written, fmt.Errorf("scaffold: %w", err)
+	}
+	return append(written, routesFile), nil
+}
+
+// register inserts the route above the marker, importing net/http
+// for the method constant, and formats the file
+func register(src, route []byte) ([]byte, error) {
+	idx := bytes.Index(src, []byte(RoutesMarker))
+	lineStart := bytes.LastIndexByte(src[:idx], '\n') + 1
+	indent := src[lineStart:idx]
+
+	var buf bytes.Buffer
+	buf.Write(src[:lineStart])
+	for _, line := range bytes.Split(bytes.TrimSpace(route), []byte("\n")) {
+		buf.Write(indent)
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.Write(src[lineStart:])
+
+	out, err := addImport(buf.Bytes(), "net/http")
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(out)
+}
+
+// addImport adds the import to the file unless it is imported already
+func addImport(src []byte, importPath string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	quoted := strconv.Quote(importPath)
+	for _, spec := range file.Imports {
+		if spec.Path.Value == quoted {
+			return src, nil
+		}
+	}
+
+	// after the package clause when there are no imports, in
+	// the group of the standard library imports otherwise
+	offset, line := fset.Position(file.Name.End()).Offset, "\n\nimport "+quoted
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		// a single import becomes a block
+		if !gen.Lparen.IsValid() {
+			start, end := fset.Position(gen.Pos()).Offset, fset.Position(gen.End()).Offset
+			spec := gen.Specs[0].(*ast.ImportSpec)
+			separator := "\n\n\t"
+			if isStdlib(spec) {
+				separator = "\n\t"
+			}
+			block := "import (\n\t" + quoted + separator + string(src[fset.Position(spec.Pos()).Offset:end]) + "\n)"
+			return splice(src, start, end, block), nil
+		}
+
+		offset, line = fset.Position(gen.Lparen).Offset+1, "\n\t"+quoted+"\n"
+		for _, spec := range gen.Specs {
+			if isStdlib(spec.(*ast.ImportSpec)) {
+				offset, line = fset.Position(spec.Pos()).Offset, quoted+"\n\t"
+				break
+			}
+		}
+		break
+	}
+
+	return splice(src, offset, offset, line), nil
+}
+
+// isStdlib reports whether the import is of the standard
+// library, whose paths have no dot in their first element
+func isStdlib(spec *ast.ImportSpec) bool {
+	imported, _ := strconv.Unquote(spec.Path.Value)
+	return !strings.Contains(strings.Split(imported, "/")[0], ".")
+}
+
+// splice replaces src[start:end] with the text
+func splice(src []byte, start, end int, text string) []byte {
+	out := make([]byte, 0, len(src)+len(text))
+	out = append(out, src[:start]...)
+	out = append(out, text...)
+	return append(out, src[end:]...)
+}
+
+// splitWords splits a name written in camel, kebab or snake
+// case into lowercase words e.g. getUserID into get, user, id
+func splitWords(name string) []string {
+	words := []string{}
+	runes := []rune(name)
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start != -1 {
+				words = append(words, strings.ToLower(string(runes[start:i])))
+				start = -1
+			}
+			continue
+		}
+		if start == -1 {
+			start = i
+			continue
+		}
+		// a new word starts at an uppercase letter following a lowercase
+		// one, or preceding a lowercase one in an acronym e.g. HTTPServer
+		if unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+			words = append(words, strings.ToLower(string(runes[start:i])))
+			start = i
+		}
+	}
+	if start != -1 {
+		words = append(words, strings.ToLower(string(runes[start:])))
+	}
+	return words
+}
+
+// camelCase joins the words, capitalizing the first
+// one when exported. Common initialisms are uppercased
+func camelCase(words []string, exported bool) string {
+	var b strings.Builder
+	for i, word := range words {
+		if i == 0 && !exported {
+			b.WriteString(word)
+			continue
+		}
+		if initialisms[word] {
+			b.WriteString(strings.ToUpper(word))
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+var initialisms = map[string]bool{
+	"api": true, "html": true, "http": true, "id": true,
+	"json": true, "sql": true, "url": true, "uuid": true,
+}
@@ -0,0 +1,149 @@
+package scaffold
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRoutes = `package api
+
+import "github.com/riyadhalnur/godi/v2/pkg/server/util"
+
+func Routes() []util.Route {
+	return []util.Route{
+		// godi:routes
+	}
+}
+`
+
+func TestGenerateHandler(t *testing.T) {
+	dir := t.TempDir()
+	routesFile := filepath.Join(dir, "routes.go")
+	assert.Nil(t, ioutil.WriteFile(routesFile, []byte(testRoutes), 0644))
+
+	files, err := GenerateHandler(Handler{
+		Name:   "getUserID",
+		Dir:    dir,
+		Method: "post",
+		Path:   "/users/{id}",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "get_user_id.go"),
+		filepath.Join(dir, "get_user_id_test.go"),
+		routesFile,
+	}, files)
+
+	handler, err := ioutil.ReadFile(files[0])
+	assert.Nil(t, err)
+	assert.Contains(t, string(handler), "package api")
+	assert.Contains(t, string(handler), "// GetUserID handles POST /users/{id}\nfunc GetUserID(ctx context.Context, req *util.Request) (*util.Response, error) {")
+
+	test, err := ioutil.ReadFile(files[1])
+	assert.Nil(t, err)
+	assert.Contains(t, string(test), "func TestGetUserID(t *testing.T) {")
+
+	routes, err := ioutil.ReadFile(routesFile)
+	assert.Nil(t, err)
+	assert.Equal(t, `package api
+
+import (
+	"net/http"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+func Routes() []util.Route {
+	return []util.Route{
+		{
+			Name:    "getUserID",
+			Path:    "/users/{id}",
+			Method:  http.MethodPost,
+			Handler: GetUserID,
+		},
+		// godi:routes
+	}
+}
+`, string(routes))
+
+	cases := []struct {
+		name    string
+		handler Handler
+		err     string
+	}{
+		{
+			name:    "existing handler",
+			handler: Handler{Name: "get-user-id", Dir: dir},
+			err:     "scaffold: " + filepath.Join(dir, "get_user_id") + ".go already exists",
+		},
+		{
+			name:    "invalid name",
+			handler: Handler{Name: "1user", Dir: dir},
+			err:     `scaffold: invalid handler name "1user"`,
+		},
+		{
+			name:    "unsupported method",
+			handler: Handler{Name: "user", Dir: dir, Method: "FETCH"},
+			err:     `scaffold: unsupported method "FETCH"`,
+		},
+		{
+			name:    "path with a newline",
+			handler: Handler{Name: "user", Dir: dir, Path: "/users\n/{id}"},
+			err:     `scaffold: path "/users\n/{id}" has spaces or control characters`,
+		},
+		{
+			name:    "missing marker",
+			handler: Handler{Name: "user", Dir: dir, RoutesFile: "get_user_id.go"},
+			err:     "scaffold: " + files[0] + " has no // godi:routes marker to register the route above",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := GenerateHandler(c.handler)
+			assert.EqualError(t, err, c.err)
+		})
+	}
+
+	t.Run("quoted path", func(t *testing.T) {
+		dir := t.TempDir()
+		routesFile := filepath.Join(dir, "routes.go")
+		assert.Nil(t, ioutil.WriteFile(routesFile, []byte(testRoutes), 0644))
+
+		files, err := GenerateHandler(Handler{Name: "search", Dir: dir, Path: `/search/"q"\{term}`})
+		assert.Nil(t, err)
+
+		test, err := ioutil.ReadFile(files[1])
+		assert.Nil(t, err)
+		assert.Contains(t, string(test), `http.NewRequest(http.MethodGet, "/search/\"q\"\\{term}", nil)`)
+
+		routes, err := ioutil.ReadFile(routesFile)
+		assert.Nil(t, err)
+		assert.Contains(t, string(routes), `Path:    "/search/\"q\"\\{term}",`)
+	})
+}
+
+func TestSplitWords(t *testing.T) {
+	cases := []struct {
+		name     string
+		words    []string
+		exported string
+	}{
+		{name: "getUser", words: []string{"get", "user"}, exported: "GetUser"},
+		{name: "get-user", words: []string{"get", "user"}, exported: "GetUser"},
+		{name: "get_user_id", words: []string{"get", "user", "id"}, exported: "GetUserID"},
+		{name: "HTTPServer", words: []string{"http", "server"}, exported: "HTTPServer"},
+		{name: "listUsersV2", words: []string{"list", "users", "v2"}, exported: "ListUsersV2"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			words := splitWords(c.name)
+			assert.Equal(t, c.words, words)
+			assert.Equal(t, c.exported, camelCase(words, true))
+		})
+	}
+}
@@ -0,0 +1,185 @@
+// Package scaffold generates new services built on godi and the
+// handlers of existing ones, from templates embedded in the package.
+// Generated code follows the conventions of the boilerplate, so it
+// reads like the rest of the service
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// DefaultPort is the port of new services when not set
+const DefaultPort string = "3001"
+
+var (
+	//go:embed templates
+	templates embed.FS
+
+	modulePath = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._~-]*(/[A-Za-z0-9._~-]+)*$`)
+	majorPath  = regexp.MustCompile(`^v[0-9]+$`)
+
+	// files that cannot be embedded under their own name
+	renames = map[string]string{
+		"gitignore": ".gitignore",
+	}
+)
+
+// Project describes a new service
+//
+// Module (required) - module path of the service e.g. github.com/acme/orders
+// Dir - directory to create the service in. Defaults to the name of the service
+// Name - name of the binary, image and Kubernetes resources.
+// Defaults to the last element of the module path
+// Image - Docker image of the service. Defaults to <name>:latest
+// Port - port the service listens on. Defaults to 3001
+// GodiVersion - version of godi required in go.mod. Left for
+// go mod tidy to resolve when not set
+type Project struct {
+	Module      string
+	Dir         string
+	Name        string
+	Image       string
+	Port        string
+	GodiVersion string
+}
+
+// New creates the service in an empty or missing directory and
+// returns the paths of the files created. The service comes with
+// a ping handler registered the same way godi gen handler does
+func New(p Project) ([]string, error) {
+	if !modulePath.MatchString(p.Module) {
+		return nil, fmt.Errorf("scaffold: invalid module path %q", p.Module)
+	}
+	if p.Name == "" {
+		p.Name = moduleName(p.Module)
+	}
+	if p.Dir == "" {
+		p.Dir = p.Name
+	}
+	if p.Image == "" {
+		p.Image = p.Name + ":latest"
+	}
+	if p.Port == "" {
+		p.Port = DefaultPort
+	}
+
+	entries, err := os.ReadDir(p.Dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("scaffold: %w", err)
+	}
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("scaffold: %s is not empty", p.Dir)
+	}
+
+	root := "templates/project"
+	created := []string{}
+	err = fs.WalkDir(templates, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := render(strings.TrimSuffix(strings.TrimPrefix(name, root+"/"), ".tmpl"), p)
+		if err != nil {
+			return err
+		}
+		if renamed, ok := renames[path.Base(rel)]; ok {
+			rel = path.Join(path.Dir(rel), renamed)
+		}
+
+		content, err := renderFile(name, p)
+		if err != nil {
+			return err
+		}
+
+		dst := filepath.Join(p.Dir, filepath.FromSlash(rel))
+		if err := writeFile(dst, content); err != nil {
+			return err
+		}
+		created = append(created, dst)
+		return nil
+	})
+	if err != nil {
+		return created, err
+	}
+
+	handlers, err := GenerateHandler(Handler{
+		Name: "ping",
+		Dir:  filepath.Join(p.Dir, "pkg", "handlers"),
+	})
+	return append(created, handlers...), err
+}
+
+// moduleName returns the last element of the module
+// path, skipping the major version e.g. orders for
+// github.com/acme/orders/v2
+func moduleName(module string) string {
+	elems := strings.Split(module, "/")
+	name := elems[len(elems)-1]
+	if majorPath.MatchString(name) && len(elems) > 1 {
+		name = elems[len(elems)-2]
+	}
+	return name
+}
+
+// renderFile executes the embedded template. Go files are formatted
+func renderFile(name string, data interface{}) ([]byte, error) {
+	b, err := templates.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := render(string(b), data)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(name, ".go.tmpl") {
+		formatted, err := format.Source([]byte(content))
+		if err != nil {
+			return nil, fmt.Errorf("scaffold: formatting %s: %w", name, err)
+		}
+		return formatted, nil
+	}
+	return []byte(content), nil
+}
+
+func render(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// writeFile creates the file, failing if it exists
+// so generated code never overwrites changes
+func writeFile(name string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return fmt.Errorf("scaffold: %w", err)
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("scaffold: %w", err)
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return fmt.Errorf("scaffold: %w", err)
+	}
+	return f.Close()
+}
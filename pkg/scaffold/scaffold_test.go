@@ -0,0 +1,76 @@
+package scaffold
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "service")
+
+	files, err := New(Project{
+		Module:      "github.com/acme/orders/v2",
+		Dir:         dir,
+		Port:        "8080",
+		GodiVersion: "v2.1.0",
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, files, filepath.Join(dir, "cmd", "orders", "main.go"))
+	assert.Contains(t, files, filepath.Join(dir, ".gitignore"))
+
+	gomod, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	assert.Nil(t, err)
+	assert.Equal(t, "module github.com/acme/orders/v2\n\ngo 1.16\n\nrequire github.com/riyadhalnur/godi/v2 v2.1.0\n", string(gomod))
+
+	deployment, err := ioutil.ReadFile(filepath.Join(dir, "deploy", "base", "deployment.yml"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(deployment), "image: orders:latest")
+	assert.Contains(t, string(deployment), "containerPort: 8080")
+	assert.NotContains(t, string(deployment), "godi")
+
+	// generated Go files parse and import the packages of the module
+	for _, name := range files {
+		if !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		_, err := parser.ParseFile(token.NewFileSet(), name, nil, parser.AllErrors)
+		assert.Nil(t, err, name)
+	}
+	main, err := ioutil.ReadFile(filepath.Join(dir, "cmd", "orders", "main.go"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(main), `"github.com/acme/orders/v2/pkg/handlers"`)
+
+	routes, err := ioutil.ReadFile(filepath.Join(dir, "pkg", "handlers", "routes.go"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(routes), "Handler: Ping,")
+
+	t.Run("directory not empty", func(t *testing.T) {
+		_, err := New(Project{Module: "github.com/acme/orders", Dir: dir})
+		assert.EqualError(t, err, "scaffold: "+dir+" is not empty")
+	})
+
+	t.Run("invalid module path", func(t *testing.T) {
+		_, err := New(Project{Module: "github.com/acme/my orders"})
+		assert.EqualError(t, err, `scaffold: invalid module path "github.com/acme/my orders"`)
+	})
+
+	t.Run("go.mod without version", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := New(Project{Module: "orders", Dir: dir})
+		assert.Nil(t, err)
+
+		gomod, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		assert.Nil(t, err)
+		assert.Equal(t, "module orders\n\ngo 1.16\n", string(gomod))
+
+		_, err = os.Stat(filepath.Join(dir, "cmd", "orders", "main.go"))
+		assert.Nil(t, err)
+	})
+}
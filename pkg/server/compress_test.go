@@ -0,0 +1,104 @@
+package server
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+func TestCompression(t *testing.T) {
+	body := strings.Repeat("compress me ", 200)
+
+	staticDir, err := ioutil.TempDir("", "godi-static")
+	assert.Nil(t, err)
+	defer os.RemoveAll(staticDir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(staticDir, "app.js"), []byte(body), 0644))
+
+	testHandler := func(ctx context.Context, req *util.Request) (*util.Response, error) {
+		return &util.Response{
+			StatusCode: http.StatusOK,
+			Body:       body,
+		}, nil
+	}
+
+	srv := Server{
+		config: &Config{
+			StaticDir: staticDir,
+			Compress:  true,
+		},
+	}
+	srv.AddRoutes(util.Route{
+		Name:    "test",
+		Path:    "/test",
+		Method:  http.MethodGet,
+		Handler: testHandler,
+	})
+	handler := srv.handler()
+
+	for _, path := range []string{"/test", "/static/app.js"} {
+		t.Run(path, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+			if err != nil {
+				assert.Nil(t, err)
+			}
+			req.Header.Set("Accept-Encoding", "gzip")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+
+			gr, err := gzip.NewReader(rr.Body)
+			assert.Nil(t, err)
+			decoded, err := ioutil.ReadAll(gr)
+			assert.Nil(t, err)
+			assert.Equal(t, body, string(decoded))
+		})
+	}
+}
+
+func TestCompressionVary(t *testing.T) {
+	srv := Server{
+		config: &Config{
+			Compress:    true,
+			CORSOrigins: []string{"*"},
+		},
+	}
+	srv.AddRoutes(util.Route{
+		Name:   "test",
+		Path:   "/test",
+		Method: http.MethodGet,
+		Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			return &util.Response{
+				StatusCode: http.StatusOK,
+				Value:      map[string]string{"body": strings.Repeat("compress me ", 200)},
+			}, nil
+		},
+	})
+	handler := srv.handler()
+
+	req, err := http.NewRequest(http.MethodGet, "/test", nil)
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Origin", "https://example.com")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	assert.ElementsMatch(t, []string{"Origin", "Accept-Encoding", "Accept"}, rr.Header().Values("Vary"))
+}
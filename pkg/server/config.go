@@ -1,13 +1,447 @@
 package server
 
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/i18n"
+	"github.com/riyadhalnur/godi/v2/pkg/middleware"
+)
+
 // Config specifies the parameters
 // that can be passed in to a Server instance
 //
 // Port (required) - tcp port the server will listen on
-// Timeout (required) - the write/read/idle timeout in seconds
-// StaticDir - the server from static files will be served
+// Timeout (required) - the default timeout in seconds. Used for
+// any of the read/write/idle/shutdown timeouts that are not set
+// ReadTimeout - maximum duration for reading the entire request
+// ReadHeaderTimeout - maximum duration for reading the request headers.
+// Falls back to ReadTimeout when not set
+// WriteTimeout - maximum duration before timing out writes of the response
+// IdleTimeout - maximum duration to wait for the next request on keep-alive connections
+// ShutdownTimeout - maximum duration to wait for active connections to finish on shutdown
+// HandlerTimeout - deadline for an API handler to return a response. Disabled when not set
+// MaxHeaderBytes - maximum size of the request headers. Uses the net/http default when not set
+// MaxBodyBytes - maximum size of request bodies. Defaults to 1MB, not limited when negative.
+// Routes can override it using util.Route.MaxBodyBytes
+// MaxDecompressedBodyBytes - maximum size of gzip or zstd request bodies
+// once decompressed. Defaults to the maximum body size, or to 1MB when
+// request bodies are not limited
+// StaticDir - the directory static files will be served from
+// StaticFS - file system to serve static files from instead of StaticDir e.g. an embed.FS
+// StaticPrefix - path prefix static files are served at. Defaults to /static
+// StaticListing - list the files of directories without an index.html. Disabled by default
+// StaticCacheControl - Cache-Control policy per file extension e.g. ".css".
+// Applied on top of DefaultStaticCacheControl
+// TemplateDir - the directory HTML templates are loaded from
+// TemplateFS - file system to load templates from instead of TemplateDir e.g. an embed.FS
+// TemplateLayout - layout pages are rendered in unless the view sets one
+// TemplateReload - parse the templates on every render. Meant for development
+// SPAFallback - serve the index.html of the static files for unknown GET paths
+// requested by browsers, so single-page applications can handle their own routes
+// SPAExclude - path prefixes never falling back to index.html e.g. /api
+// LocaleDir - the directory message catalogs used to translate error messages are loaded from
+// Locales - file system to load message catalogs from instead of LocaleDir e.g. an embed.FS
+// DefaultLanguage - language of error messages when the client accepts none of the catalogs. Defaults to en
+// ExposeServerErrors - send the messages of 5xx errors to clients. They get the default message of the error type otherwise
+// DebugErrors - include the chain of errors that caused an error in responses to requests from DebugNetworks
+// DebugNetworks - networks (CIDR) allowed to see the cause of errors in debug mode. Defaults to loopback addresses
+// Idempotency - honor the Idempotency-Key header of POST and PATCH routes
+// IdempotencyTTL - how long responses are kept for retries. Defaults to 24h
+// IdempotencyStore - where responses are kept. Defaults to memory
+// Cache - add ETags to the responses of GET routes and answer conditional requests with 304
+// CacheTTL - how long full responses of GET routes are stored. Only ETags are used when not set
+// CacheSize - number of responses kept in memory. Defaults to middleware.DefaultCacheSize
+// CacheStore - where responses are stored instead of memory e.g. middleware.NewRedisCacheStore
+// LogLevel - minimum level to log e.g. debug, info. Uses DEBUG mode when not set
+// CORSOrigins - origins allowed to make cross-origin requests. Use "*" to allow any
+// RateLimit - requests per second allowed per client. Disabled when not set
+// RateLimitBurst - maximum burst of requests per client
+// Features - feature flags exposed to handlers through util.FeatureEnabled
+// Compress - compress responses, including static files, based on Accept-Encoding
+// CompressMinSize - minimum size of responses to compress in bytes. Defaults to 1KB
+// CompressContentTypes - content types to compress. Defaults to middleware.DefaultCompressContentTypes
+// ContractValidation - validate the requests and responses of the routes against the
+// OpenAPI document of the server, logging the violations. Meant for tests and staging
+// RecordFile - file the requests and responses of the routes are appended to, to replay them
+// RecordRedactHeaders - headers masked in recorded exchanges, on top of traffic.DefaultRedactHeaders
+// RecordRedactFields - query parameters, form and JSON fields masked in recorded exchanges e.g. password
+//
+// LogLevel, CORSOrigins, RateLimit, RateLimitBurst, Features
+// and StaticDir can be changed without a restart using Server.Reload
 type Config struct {
-	Port      string
-	Timeout   int
-	StaticDir string
+	Port                     string
+	Timeout                  int
+	ReadTimeout              time.Duration
+	ReadHeaderTimeout        time.Duration
+	WriteTimeout             time.Duration
+	IdleTimeout              time.Duration
+	ShutdownTimeout          time.Duration
+	HandlerTimeout           time.Duration
+	MaxHeaderBytes           int
+	MaxBodyBytes             int64
+	MaxDecompressedBodyBytes int64
+	StaticDir                string
+	StaticFS                 fs.FS
+	StaticPrefix             string
+	StaticListing            bool
+	StaticCacheControl       map[string]string
+	TemplateDir              string
+	TemplateFS               fs.FS
+	TemplateLayout           string
+	TemplateReload           bool
+	SPAFallback              bool
+	SPAExclude               []string
+	LocaleDir                string
+	Locales                  fs.FS
+	DefaultLanguage          string
+	ExposeServerErrors       bool
+	DebugErrors              bool
+	DebugNetworks            []string
+	Idempotency              bool
+	IdempotencyTTL           time.Duration
+	IdempotencyStore         middleware.IdempotencyStore
+	Cache                    bool
+	CacheTTL                 time.Duration
+	CacheSize                int
+	CacheStore               middleware.CacheStore
+	LogLevel                 string
+	CORSOrigins              []string
+	RateLimit                float64
+	RateLimitBurst           int
+	Features                 map[string]bool
+	Compress                 bool
+	CompressMinSize          int
+	CompressContentTypes     []string
+	ContractValidation       bool
+	RecordFile               string
+	RecordRedactHeaders      []string
+	RecordRedactFields       []string
+}
+
+// fileConfig is the JSON representation of Config.
+// Fields not present in the file are left untouched
+type fileConfig struct {
+	Port                     *string           `json:"port"`
+	Timeout                  *int              `json:"timeout"`
+	ReadTimeout              *duration         `json:"readTimeout"`
+	ReadHeaderTimeout        *duration         `json:"readHeaderTimeout"`
+	WriteTimeout             *duration         `json:"writeTimeout"`
+	IdleTimeout              *duration         `json:"idleTimeout"`
+	ShutdownTimeout          *duration         `json:"shutdownTimeout"`
+	HandlerTimeout           *duration         `json:"handlerTimeout"`
+	MaxHeaderBytes           *int              `json:"maxHeaderBytes"`
+	MaxBodyBytes             *int64            `json:"maxBodyBytes"`
+	MaxDecompressedBodyBytes *int64            `json:"maxDecompressedBodyBytes"`
+	StaticDir                *string           `json:"staticDir"`
+	StaticPrefix             *string           `json:"staticPrefix"`
+	StaticListing            *bool             `json:"staticListing"`
+	StaticCacheControl       map[string]string `json:"staticCacheControl"`
+	TemplateDir              *string           `json:"templateDir"`
+	TemplateLayout           *string           `json:"templateLayout"`
+	TemplateReload           *bool             `json:"templateReload"`
+	SPAFallback              *bool             `json:"spaFallback"`
+	SPAExclude               []string          `json:"spaExclude"`
+	LocaleDir                *string           `json:"localeDir"`
+	DefaultLanguage          *string           `json:"defaultLanguage"`
+	ExposeServerErrors       *bool             `json:"exposeServerErrors"`
+	DebugErrors              *bool             `json:"debugErrors"`
+	DebugNetworks            []string          `json:"debugNetworks"`
+	Idempotency              *bool             `json:"idempotency"`
+	IdempotencyTTL           *duration         `json:"idempotencyTTL"`
+	Cache                    *bool             `json:"cache"`
+	CacheTTL                 *duration         `json:"cacheTTL"`
+	CacheSize                *int              `json:"cacheSize"`
+	LogLevel                 *string           `json:"logLevel"`
+	CORSOrigins              []string          `json:"corsOrigins"`
+	RateLimit                *float64          `json:"rateLimit"`
+	RateLimitBurst           *int              `json:"rateLimitBurst"`
+	Features                 map[string]bool   `json:"features"`
+	Compress                 *bool             `json:"compress"`
+	CompressMinSize          *int              `json:"compressMinSize"`
+	CompressContentTypes     []string          `json:"compressContentTypes"`
+	ContractValidation       *bool             `json:"contractValidation"`
+	RecordFile               *string           `json:"recordFile"`
+	RecordRedactHeaders      []string          `json:"recordRedactHeaders"`
+	RecordRedactFields       []string          `json:"recordRedactFields"`
+}
+
+// duration reads durations written as strings e.g. "10s"
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+
+	*d = duration(parsed)
+	return nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadConfigFile reads a JSON configuration file and applies it
+// on top of a copy of base. Durations are written as strings e.g. "10s".
+// Base can be nil
+func LoadConfigFile(path string, base *Config) (*Config, error) {
+	cfg := &Config{}
+	if base != nil {
+		*cfg = *base
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var fc fileConfig
+	if err := json.NewDecoder(f).Decode(&fc); err != nil {
+		return nil, err
+	}
+
+	setString(&cfg.Port, fc.Port)
+	setInt(&cfg.Timeout, fc.Timeout)
+	setDuration(&cfg.ReadTimeout, fc.ReadTimeout)
+	setDuration(&cfg.ReadHeaderTimeout, fc.ReadHeaderTimeout)
+	setDuration(&cfg.WriteTimeout, fc.WriteTimeout)
+	setDuration(&cfg.IdleTimeout, fc.IdleTimeout)
+	setDuration(&cfg.ShutdownTimeout, fc.ShutdownTimeout)
+	setDuration(&cfg.HandlerTimeout, fc.HandlerTimeout)
+	setInt(&cfg.MaxHeaderBytes, fc.MaxHeaderBytes)
+	setInt64(&cfg.MaxBodyBytes, fc.MaxBodyBytes)
+	setInt64(&cfg.MaxDecompressedBodyBytes, fc.MaxDecompressedBodyBytes)
+	setString(&cfg.StaticDir, fc.StaticDir)
+	setString(&cfg.StaticPrefix, fc.StaticPrefix)
+	setString(&cfg.LogLevel, fc.LogLevel)
+	setInt(&cfg.RateLimitBurst, fc.RateLimitBurst)
+
+	if fc.CORSOrigins != nil {
+		cfg.CORSOrigins = fc.CORSOrigins
+	}
+	if fc.RateLimit != nil {
+		cfg.RateLimit = *fc.RateLimit
+	}
+	if fc.Features != nil {
+		cfg.Features = fc.Features
+	}
+	if fc.StaticListing != nil {
+		cfg.StaticListing = *fc.StaticListing
+	}
+	if fc.StaticCacheControl != nil {
+		cfg.StaticCacheControl = fc.StaticCacheControl
+	}
+	setString(&cfg.TemplateDir, fc.TemplateDir)
+	setString(&cfg.TemplateLayout, fc.TemplateLayout)
+	if fc.TemplateReload != nil {
+		cfg.TemplateReload = *fc.TemplateReload
+	}
+	if fc.SPAFallback != nil {
+		cfg.SPAFallback = *fc.SPAFallback
+	}
+	if fc.SPAExclude != nil {
+		cfg.SPAExclude = fc.SPAExclude
+	}
+	setString(&cfg.LocaleDir, fc.LocaleDir)
+	setString(&cfg.DefaultLanguage, fc.DefaultLanguage)
+	if fc.ExposeServerErrors != nil {
+		cfg.ExposeServerErrors = *fc.ExposeServerErrors
+	}
+	if fc.DebugErrors != nil {
+		cfg.DebugErrors = *fc.DebugErrors
+	}
+	if fc.DebugNetworks != nil {
+		cfg.DebugNetworks = fc.DebugNetworks
+	}
+	if fc.Idempotency != nil {
+		cfg.Idempotency = *fc.Idempotency
+	}
+	setDuration(&cfg.IdempotencyTTL, fc.IdempotencyTTL)
+	if fc.Cache != nil {
+		cfg.Cache = *fc.Cache
+	}
+	setDuration(&cfg.CacheTTL, fc.CacheTTL)
+	setInt(&cfg.CacheSize, fc.CacheSize)
+	if fc.Compress != nil {
+		cfg.Compress = *fc.Compress
+	}
+	setInt(&cfg.CompressMinSize, fc.CompressMinSize)
+	if fc.CompressContentTypes != nil {
+		cfg.CompressContentTypes = fc.CompressContentTypes
+	}
+	if fc.ContractValidation != nil {
+		cfg.ContractValidation = *fc.ContractValidation
+	}
+	if fc.RecordFile != nil {
+		cfg.RecordFile = *fc.RecordFile
+	}
+	if fc.RecordRedactHeaders != nil {
+		cfg.RecordRedactHeaders = fc.RecordRedactHeaders
+	}
+	if fc.RecordRedactFields != nil {
+		cfg.RecordRedactFields = fc.RecordRedactFields
+	}
+
+	return cfg, nil
+}
+
+// Effective returns a copy of the config with the
+// defaults applied to the fields that are not set
+func (c *Config) Effective() *Config {
+	cfg := *c
+	cfg.ReadTimeout = c.readTimeout()
+	cfg.WriteTimeout = c.writeTimeout()
+	cfg.IdleTimeout = c.idleTimeout()
+	cfg.ShutdownTimeout = c.shutdownTimeout()
+	if cfg.ReadHeaderTimeout == 0 {
+		cfg.ReadHeaderTimeout = cfg.ReadTimeout
+	}
+	if cfg.MaxHeaderBytes == 0 {
+		cfg.MaxHeaderBytes = http.DefaultMaxHeaderBytes
+	}
+	if cfg.MaxBodyBytes == 0 {
+		cfg.MaxBodyBytes = middleware.DefaultMaxBodyBytes
+	}
+	if cfg.MaxDecompressedBodyBytes <= 0 {
+		cfg.MaxDecompressedBodyBytes = cfg.MaxBodyBytes
+		if cfg.MaxBodyBytes < 0 {
+			cfg.MaxDecompressedBodyBytes = middleware.DefaultMaxBodyBytes
+		}
+	}
+	if cfg.StaticPrefix == "" {
+		cfg.StaticPrefix = defaultStaticPrefix
+	}
+	if cfg.DefaultLanguage == "" {
+		cfg.DefaultLanguage = i18n.DefaultLanguage
+	}
+	if cfg.DebugNetworks == nil {
+		cfg.DebugNetworks = defaultDebugNetworks
+	}
+	if cfg.IdempotencyTTL == 0 {
+		cfg.IdempotencyTTL = middleware.DefaultIdempotencyTTL
+	}
+	if cfg.CacheSize == 0 {
+		cfg.CacheSize = middleware.DefaultCacheSize
+	}
+	if cfg.CompressMinSize == 0 {
+		cfg.CompressMinSize = middleware.DefaultCompressMinSize
+	}
+	if cfg.CompressContentTypes == nil {
+		cfg.CompressContentTypes = middleware.DefaultCompressContentTypes
+	}
+	return &cfg
+}
+
+// MarshalJSON writes the config in the format of config files.
+// File systems and stores are left out
+func (c *Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&fileConfig{
+		Port:                     &c.Port,
+		Timeout:                  &c.Timeout,
+		ReadTimeout:              (*duration)(&c.ReadTimeout),
+		ReadHeaderTimeout:        (*duration)(&c.ReadHeaderTimeout),
+		WriteTimeout:             (*duration)(&c.WriteTimeout),
+		IdleTimeout:              (*duration)(&c.IdleTimeout),
+		ShutdownTimeout:          (*duration)(&c.ShutdownTimeout),
+		HandlerTimeout:           (*duration)(&c.HandlerTimeout),
+		MaxHeaderBytes:           &c.MaxHeaderBytes,
+		MaxBodyBytes:             &c.MaxBodyBytes,
+		MaxDecompressedBodyBytes: &c.MaxDecompressedBodyBytes,
+		StaticDir:                &c.StaticDir,
+		StaticPrefix:             &c.StaticPrefix,
+		StaticListing:            &c.StaticListing,
+		StaticCacheControl:       c.StaticCacheControl,
+		TemplateDir:              &c.TemplateDir,
+		TemplateLayout:           &c.TemplateLayout,
+		TemplateReload:           &c.TemplateReload,
+		SPAFallback:              &c.SPAFallback,
+		SPAExclude:               c.SPAExclude,
+		LocaleDir:                &c.LocaleDir,
+		DefaultLanguage:          &c.DefaultLanguage,
+		ExposeServerErrors:       &c.ExposeServerErrors,
+		DebugErrors:              &c.DebugErrors,
+		DebugNetworks:            c.DebugNetworks,
+		Idempotency:              &c.Idempotency,
+		IdempotencyTTL:           (*duration)(&c.IdempotencyTTL),
+		Cache:                    &c.Cache,
+		CacheTTL:                 (*duration)(&c.CacheTTL),
+		CacheSize:                &c.CacheSize,
+		LogLevel:                 &c.LogLevel,
+		CORSOrigins:              c.CORSOrigins,
+		RateLimit:                &c.RateLimit,
+		RateLimitBurst:           &c.RateLimitBurst,
+		Features:                 c.Features,
+		Compress:                 &c.Compress,
+		CompressMinSize:          &c.CompressMinSize,
+		CompressContentTypes:     c.CompressContentTypes,
+		ContractValidation:       &c.ContractValidation,
+		RecordFile:               &c.RecordFile,
+		RecordRedactHeaders:      c.RecordRedactHeaders,
+		RecordRedactFields:       c.RecordRedactFields,
+	})
+}
+
+func setString(dst *string, src *string) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setInt(dst *int, src *int) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setInt64(dst *int64, src *int64) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setDuration(dst *time.Duration, src *duration) {
+	if src != nil {
+		*dst = time.Duration(*src)
+	}
+}
+
+func (c *Config) hasTimeouts() bool {
+	return c.ReadTimeout != 0 &&
+		c.WriteTimeout != 0 &&
+		c.IdleTimeout != 0 &&
+		c.ShutdownTimeout != 0
+}
+
+func (c *Config) readTimeout() time.Duration {
+	return c.durationOrDefault(c.ReadTimeout)
+}
+
+func (c *Config) writeTimeout() time.Duration {
+	return c.durationOrDefault(c.WriteTimeout)
+}
+
+func (c *Config) idleTimeout() time.Duration {
+	return c.durationOrDefault(c.IdleTimeout)
+}
+
+func (c *Config) shutdownTimeout() time.Duration {
+	return c.durationOrDefault(c.ShutdownTimeout)
+}
+
+func (c *Config) durationOrDefault(d time.Duration) time.Duration {
+	if d != 0 {
+		return d
+	}
+	return time.Duration(c.Timeout) * time.Second
 }
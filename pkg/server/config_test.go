@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/middleware"
+)
+
+func TestConfigTimeouts(t *testing.T) {
+	t.Run("falls back to timeout", func(t *testing.T) {
+		cfg := &Config{
+			Timeout: 30,
+		}
+
+		assert.False(t, cfg.hasTimeouts())
+		assert.Equal(t, 30*time.Second, cfg.readTimeout())
+		assert.Equal(t, 30*time.Second, cfg.writeTimeout())
+		assert.Equal(t, 30*time.Second, cfg.idleTimeout())
+		assert.Equal(t, 30*time.Second, cfg.shutdownTimeout())
+	})
+
+	t.Run("explicit timeouts", func(t *testing.T) {
+		cfg := &Config{
+			Timeout:         30,
+			ReadTimeout:     5 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     time.Minute,
+			ShutdownTimeout: 15 * time.Second,
+		}
+
+		assert.True(t, cfg.hasTimeouts())
+		assert.Equal(t, 5*time.Second, cfg.readTimeout())
+		assert.Equal(t, 10*time.Second, cfg.writeTimeout())
+		assert.Equal(t, time.Minute, cfg.idleTimeout())
+		assert.Equal(t, 15*time.Second, cfg.shutdownTimeout())
+	})
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(path, []byte(`{
+		"readTimeout": "5s",
+		"logLevel": "debug",
+		"corsOrigins": ["https://example.com"],
+		"rateLimit": 2.5,
+		"features": {"beta": true}
+	}`), 0644)
+	assert.Nil(t, err)
+
+	t.Run("applies on top of base", func(t *testing.T) {
+		cfg, err := LoadConfigFile(path, &Config{
+			Port:    "3001",
+			Timeout: 30,
+		})
+		assert.Nil(t, err)
+
+		assert.Equal(t, "3001", cfg.Port)
+		assert.Equal(t, 30, cfg.Timeout)
+		assert.Equal(t, 5*time.Second, cfg.ReadTimeout)
+		assert.Equal(t, "debug", cfg.LogLevel)
+		assert.Equal(t, []string{"https://example.com"}, cfg.CORSOrigins)
+		assert.Equal(t, 2.5, cfg.RateLimit)
+		assert.True(t, cfg.Features["beta"])
+	})
+
+	t.Run("invalid duration", func(t *testing.T) {
+		invalid := filepath.Join(dir, "invalid.json")
+		err := ioutil.WriteFile(invalid, []byte(`{"readTimeout": "soon"}`), 0644)
+		assert.Nil(t, err)
+
+		_, err = LoadConfigFile(invalid, nil)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadConfigFile(filepath.Join(dir, "missing.json"), nil)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestConfigEffective(t *testing.T) {
+	cfg := &Config{
+		Port:         "3000",
+		Timeout:      30,
+		ReadTimeout:  5 * time.Second,
+		MaxBodyBytes: 2048,
+	}
+	effective := cfg.Effective()
+
+	assert.Equal(t, 5*time.Second, effective.ReadTimeout)
+	assert.Equal(t, 5*time.Second, effective.ReadHeaderTimeout)
+	assert.Equal(t, 30*time.Second, effective.WriteTimeout)
+	assert.Equal(t, int64(2048), effective.MaxBodyBytes)
+	assert.Equal(t, int64(2048), effective.MaxDecompressedBodyBytes)
+	assert.Equal(t, "/static", effective.StaticPrefix)
+	assert.Equal(t, "en", effective.DefaultLanguage)
+	assert.Equal(t, 24*time.Hour, effective.IdempotencyTTL)
+	// the config itself is left as is
+	assert.Zero(t, cfg.WriteTimeout)
+	assert.Empty(t, cfg.StaticPrefix)
+
+	t.Run("unlimited body", func(t *testing.T) {
+		effective := (&Config{MaxBodyBytes: -1}).Effective()
+
+		assert.Equal(t, int64(-1), effective.MaxBodyBytes)
+		assert.Equal(t, middleware.DefaultMaxBodyBytes, effective.MaxDecompressedBodyBytes)
+	})
+
+	t.Run("marshals as a config file", func(t *testing.T) {
+		b, err := json.Marshal(effective)
+		assert.Nil(t, err)
+
+		var fields map[string]interface{}
+		assert.Nil(t, json.Unmarshal(b, &fields))
+		assert.Equal(t, "3000", fields["port"])
+		assert.Equal(t, "5s", fields["readTimeout"])
+		assert.Equal(t, "24h0m0s", fields["idempotencyTTL"])
+
+		dir, err := ioutil.TempDir("", "config")
+		assert.Nil(t, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "config.json")
+		assert.Nil(t, ioutil.WriteFile(path, b, 0644))
+		loaded, err := LoadConfigFile(path, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, effective.WriteTimeout, loaded.WriteTimeout)
+		assert.Equal(t, effective.CompressContentTypes, loaded.CompressContentTypes)
+	})
+}
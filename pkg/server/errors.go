@@ -0,0 +1,96 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+
+	"github.com/gofrs/uuid"
+)
+
+// defaultDebugNetworks are the networks allowed to see
+// the cause of errors when DebugNetworks is not set
+var defaultDebugNetworks = []string{"127.0.0.0/8", "::1/128"}
+
+// loadDebugNetworks parses the networks allowed to see the cause of
+// errors in debug mode. Defaults to loopback addresses only. Fails
+// naming the first entry that is not a network
+func (s *Server) loadDebugNetworks() error {
+	cidrs := s.config.DebugNetworks
+	if len(cidrs) == 0 {
+		cidrs = defaultDebugNetworks
+	}
+
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return godierr.InvalidArgsError(fmt.Sprintf("debug networks %q", cidr))
+		}
+		networks = append(networks, network)
+	}
+
+	s.debugNetworks = networks
+	return nil
+}
+
+// debugErrors reports whether the cause of errors can be
+// sent in the response to the request. Only the address
+// of the connection is checked, as forwarding headers
+// can be set by anyone
+func (s *Server) debugErrors(r *http.Request) bool {
+	if !s.config.DebugErrors {
+		return false
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range s.debugNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactError returns the error sent to clients in place of server
+// errors, whose message can have internals e.g. hostnames. It has the
+// default message of the error type. Unregistered types are internal
+func redactError(err *godierr.Error) *godierr.Error {
+	if err.Code() < http.StatusInternalServerError {
+		return err
+	}
+
+	def, ok := godierr.Lookup(err.Type())
+	if !ok {
+		def, _ = godierr.Lookup(godierr.InternalType)
+	}
+	return godierr.New(err.Code(), def.Type, def.Message, nil).WithMessageKey(def.Type, nil)
+}
+
+// errorID returns the public ID of an error, sent to clients and
+// logged so the response can be correlated to the log entry
+func errorID() string {
+	id, _ := uuid.NewV4()
+	return id.String()
+}
+
+// errorCauses returns the messages of the
+// chain of errors, outermost first
+func errorCauses(err error) []string {
+	var causes []string
+	for ; err != nil; err = errors.Unwrap(err) {
+		causes = append(causes, err.Error())
+	}
+	return causes
+}
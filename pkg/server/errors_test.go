@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorPolicy(t *testing.T) {
+	const (
+		endpoint string = "/test"
+	)
+
+	respond := func(t *testing.T, cfg *Config, remoteAddr string, handlerErr error) *util.ErrorResponse {
+		srv := Server{
+			config: cfg,
+		}
+		srv.AddRoutes(util.Route{
+			Name:   "test",
+			Path:   endpoint,
+			Method: http.MethodGet,
+			Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+				return nil, handlerErr
+			},
+		})
+		router := srv.mountRoutes()
+
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		req.RemoteAddr = remoteAddr
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var res util.ErrorResponse
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		assert.Equal(t, res.Code, rr.Code)
+		return &res
+	}
+
+	t.Run("redaction", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			expose  bool
+			err     error
+			code    int
+			errType string
+			message string
+		}{
+			{
+				"server error",
+				false,
+				godierr.New(500, godierr.InternalType, "unable to reach db at 10.0.0.1", nil),
+				500,
+				godierr.InternalType,
+				godierr.InternalMsg,
+			},
+			{
+				"unregistered type",
+				false,
+				godierr.New(502, "UPSTREAM", "upstream 10.0.0.2 returned 500", nil),
+				502,
+				godierr.InternalType,
+				godierr.InternalMsg,
+			},
+			{
+				"wrapped server error",
+				false,
+				godierr.Wrap(godierr.New(503, godierr.UnavailableType, "replica 10.0.0.3 is down", nil), "listing users"),
+				503,
+				godierr.UnavailableType,
+				godierr.UnavailableMsg,
+			},
+			{
+				"client error",
+				false,
+				godierr.NotFoundError("user"),
+				404,
+				godierr.NotFoundType,
+				"resource(s) not found: user",
+			},
+			{
+				"exposed server error",
+				true,
+				godierr.New(500, godierr.InternalType, "unable to reach db at 10.0.0.1", nil),
+				500,
+				godierr.InternalType,
+				"unable to reach db at 10.0.0.1",
+			},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				res := respond(t, &Config{ExposeServerErrors: c.expose}, "127.0.0.1:1234", c.err)
+
+				assert.Equal(t, c.code, res.Code)
+				assert.Equal(t, c.errType, res.Type)
+				assert.Equal(t, c.message, res.Message)
+				assert.Empty(t, res.Causes)
+			})
+		}
+	})
+
+	t.Run("error id", func(t *testing.T) {
+		first := respond(t, &Config{}, "127.0.0.1:1234", errors.New("connection refused"))
+		second := respond(t, &Config{}, "127.0.0.1:1234", errors.New("connection refused"))
+		assert.NotEmpty(t, first.ID)
+		assert.NotEqual(t, first.ID, second.ID)
+
+		res := respond(t, &Config{}, "127.0.0.1:1234", godierr.InvalidArgsError("id"))
+		assert.Empty(t, res.ID)
+	})
+
+	t.Run("debug mode", func(t *testing.T) {
+		err := godierr.Wrap(errors.New("connection refused"), "loading user")
+
+		cases := []struct {
+			name       string
+			cfg        *Config
+			remoteAddr string
+			causes     []string
+		}{
+			{
+				"loopback",
+				&Config{DebugErrors: true},
+				"127.0.0.1:1234",
+				[]string{"loading user: connection refused", "connection refused"},
+			},
+			{
+				"loopback ipv6",
+				&Config{DebugErrors: true},
+				"[::1]:1234",
+				[]string{"loading user: connection refused", "connection refused"},
+			},
+			{
+				"allowlisted network",
+				&Config{DebugErrors: true, DebugNetworks: []string{"10.0.0.0/8"}},
+				"10.1.2.3:1234",
+				[]string{"loading user: connection refused", "connection refused"},
+			},
+			{
+				"other network",
+				&Config{DebugErrors: true},
+				"203.0.113.7:1234",
+				nil,
+			},
+			{
+				"disabled",
+				&Config{},
+				"127.0.0.1:1234",
+				nil,
+			},
+			{
+				"invalid networks",
+				&Config{DebugErrors: true, DebugNetworks: []string{"10.0.0.0/8", "localhost"}},
+				"10.1.2.3:1234",
+				nil,
+			},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				res := respond(t, c.cfg, c.remoteAddr, err)
+
+				assert.Equal(t, godierr.InternalMsg, res.Message)
+				assert.Equal(t, c.causes, res.Causes)
+			})
+		}
+	})
+	t.Run("invalid debug networks fail to load", func(t *testing.T) {
+		srv := NewServer(&Config{DebugErrors: true, DebugNetworks: []string{"10.0.0.0/8", "localhost"}})
+
+		handler, err := srv.Handler()
+		assert.Nil(t, handler)
+		assert.EqualError(t, err, `invalid argument(s) passed in: debug networks "localhost"`)
+	})
+}
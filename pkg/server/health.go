@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+// HealthChecker is implemented by dependencies the server
+// cannot serve requests without e.g. a database
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// AddHealthChecks appends the dependencies checked
+// by the health endpoint
+func (s *Server) AddHealthChecks(checks ...HealthChecker) {
+	s.healthChecks = append(s.healthChecks, checks...)
+}
+
+// healthCheckHandler responds with a 503 when any
+// of the health checks fails
+func (s *Server) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	for _, check := range s.healthChecks {
+		if err := check.CheckHealth(r.Context()); err != nil {
+			requestID, _ := r.Context().Value(util.RequestIDKey).(string)
+			logger.Error("Health check failed", "requestId", requestID, "error", err.Error())
+
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("unavailable"))
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
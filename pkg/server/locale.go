@@ -0,0 +1,62 @@
+package server
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/i18n"
+)
+
+// loadLocales loads the message catalogs in Locales or LocaleDir, if set
+func (s *Server) loadLocales() error {
+	var fsys fs.FS
+	switch {
+	case s.config.Locales != nil:
+		fsys = s.config.Locales
+	case s.config.LocaleDir != "":
+		dir, err := filepath.Abs(s.config.LocaleDir)
+		if err != nil {
+			return err
+		}
+		fsys = os.DirFS(dir)
+	default:
+		return nil
+	}
+
+	locales, err := i18n.Load(fsys, s.config.DefaultLanguage)
+	if err != nil {
+		return err
+	}
+
+	s.locales = locales
+	return nil
+}
+
+// localizeError returns the message of the error in the language
+// the client prefers, falling back to the default language. The
+// message is returned as is when no catalogs are loaded or the
+// message cannot be translated
+func (s *Server) localizeError(w http.ResponseWriter, r *http.Request, err *godierr.Error) string {
+	if s.locales == nil || err.MessageKey() == "" {
+		return err.Message()
+	}
+
+	// the message depends on the header even when it is not translated
+	w.Header().Add("Vary", "Accept-Language")
+
+	languages := []string{
+		s.locales.Match(r.Header.Get("Accept-Language")),
+		s.locales.Fallback(),
+	}
+	for _, lang := range languages {
+		if msg, ok := s.locales.Translate(lang, err.MessageKey(), err.MessageArgs()); ok {
+			w.Header().Set("Content-Language", lang)
+			return msg
+		}
+	}
+
+	return err.Message()
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+)
+
+const (
+	configPollInterval time.Duration = 2 * time.Second
+)
+
+// Reloader is implemented by application components
+// that need to pick up configuration changes
+// without restarting the server
+type Reloader interface {
+	Reload(cfg *Config) error
+}
+
+// ConfigLoader loads the latest configuration from its source
+type ConfigLoader func() (*Config, error)
+
+// ReloadReport lists the settings that changed during a reload
+//
+// Applied - settings applied to the running server
+// RequiresRestart - settings that changed but only take effect after a restart
+type ReloadReport struct {
+	Applied         []string
+	RequiresRestart []string
+}
+
+type configField struct {
+	name       string
+	reloadable bool
+	value      func(c *Config) interface{}
+}
+
+var configFields = []configField{
+	{"Port", false, func(c *Config) interface{} { return c.Port }},
+	{"Timeout", false, func(c *Config) interface{} { return c.Timeout }},
+	{"ReadTimeout", false, func(c *Config) interface{} { return c.ReadTimeout }},
+	{"ReadHeaderTimeout", false, func(c *Config) interface{} { return c.ReadHeaderTimeout }},
+	{"WriteTimeout", false, func(c *Config) interface{} { return c.WriteTimeout }},
+	{"IdleTimeout", false, func(c *Config) interface{} { return c.IdleTimeout }},
+	{"ShutdownTimeout", false, func(c *Config) interface{} { return c.ShutdownTimeout }},
+	{"HandlerTimeout", false, func(c *Config) interface{} { return c.HandlerTimeout }},
+	{"MaxHeaderBytes", false, func(c *Config) interface{} { return c.MaxHeaderBytes }},
+	{"MaxBodyBytes", false, func(c *Config) interface{} { return c.MaxBodyBytes }},
+	{"MaxDecompressedBodyBytes", false, func(c *Config) interface{} { return c.MaxDecompressedBodyBytes }},
+	{"StaticDir", true, func(c *Config) interface{} { return c.StaticDir }},
+	{"StaticFS", false, func(c *Config) interface{} { return c.StaticFS }},
+	{"StaticPrefix", false, func(c *Config) interface{} { return c.StaticPrefix }},
+	{"StaticListing", false, func(c *Config) interface{} { return c.StaticListing }},
+	{"StaticCacheControl", false, func(c *Config) interface{} { return c.StaticCacheControl }},
+	{"TemplateDir", false, func(c *Config) interface{} { return c.TemplateDir }},
+	{"TemplateFS", false, func(c *Config) interface{} { return c.TemplateFS }},
+	{"TemplateLayout", false, func(c *Config) interface{} { return c.TemplateLayout }},
+	{"TemplateReload", false, func(c *Config) interface{} { return c.TemplateReload }},
+	{"SPAFallback", false, func(c *Config) interface{} { return c.SPAFallback }},
+	{"SPAExclude", false, func(c *Config) interface{} { return c.SPAExclude }},
+	{"LocaleDir", false, func(c *Config) interface{} { return c.LocaleDir }},
+	{"Locales", false, func(c *Config) interface{} { return c.Locales }},
+	{"DefaultLanguage", false, func(c *Config) interface{} { return c.DefaultLanguage }},
+	{"ExposeServerErrors", false, func(c *Config) interface{} { return c.ExposeServerErrors }},
+	{"DebugErrors", false, func(c *Config) interface{} { return c.DebugErrors }},
+	{"DebugNetworks", false, func(c *Config) interface{} { return c.DebugNetworks }},
+	{"Idempotency", false, func(c *Config) interface{} { return c.Idempotency }},
+	{"IdempotencyTTL", false, func(c *Config) interface{} { return c.IdempotencyTTL }},
+	{"IdempotencyStore", false, func(c *Config) interface{} { return c.IdempotencyStore }},
+	{"Cache", false, func(c *Config) interface{} { return c.Cache }},
+	{"CacheTTL", false, func(c *Config) interface{} { return c.CacheTTL }},
+	{"CacheSize", false, func(c *Config) interface{} { return c.CacheSize }},
+	{"CacheStore", false, func(c *Config) interface{} { return c.CacheStore }},
+	{"LogLevel", true, func(c *Config) interface{} { return c.LogLevel }},
+	{"CORSOrigins", true, func(c *Config) interface{} { return c.CORSOrigins }},
+	{"RateLimit", true, func(c *Config) interface{} { return c.RateLimit }},
+	{"RateLimitBurst", true, func(c *Config) interface{} { return c.RateLimitBurst }},
+	{"Features", true, func(c *Config) interface{} { return c.Features }},
+	{"Compress", false, func(c *Config) interface{} { return c.Compress }},
+	{"CompressMinSize", false, func(c *Config) interface{} { return c.CompressMinSize }},
+	{"CompressContentTypes", false, func(c *Config) interface{} { return c.CompressContentTypes }},
+	{"ContractValidation", false, func(c *Config) interface{} { return c.ContractValidation }},
+	{"RecordFile", false, func(c *Config) interface{} { return c.RecordFile }},
+	{"RecordRedactHeaders", false, func(c *Config) interface{} { return c.RecordRedactHeaders }},
+	{"RecordRedactFields", false, func(c *Config) interface{} { return c.RecordRedactFields }},
+}
+
+// AddReloaders appends the component(s) to notify
+// after the configuration is reloaded
+func (s *Server) AddReloaders(reloaders ...Reloader) {
+	s.reloaders = append(s.reloaders, reloaders...)
+}
+
+// WatchConfig reloads the configuration using load whenever
+// the process receives a SIGHUP or any of the files change.
+// Watching starts with Listen and stops on shutdown
+func (s *Server) WatchConfig(load ConfigLoader, files ...string) {
+	s.loader = load
+	s.configFiles = files
+}
+
+// Reload applies the reloadable settings of cfg to the running server.
+// The settings are validated first, so invalid ones leave the server
+// unchanged. Reloaders are notified once the settings are applied: their
+// errors are returned along with the report but do not revert the settings.
+// Changes to any other setting are listed in the report as requiring a restart
+func (s *Server) Reload(cfg *Config) (*ReloadReport, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	current := s.currentConfig()
+	report := &ReloadReport{}
+
+	for _, field := range configFields {
+		if reflect.DeepEqual(field.value(current), field.value(cfg)) {
+			continue
+		}
+
+		if field.reloadable && (field.name != "StaticDir" || s.staticDirReloadable(cfg)) {
+			report.Applied = append(report.Applied, field.name)
+			continue
+		}
+		report.RequiresRestart = append(report.RequiresRestart, field.name)
+	}
+
+	next := *current
+	next.LogLevel = cfg.LogLevel
+	next.CORSOrigins = cfg.CORSOrigins
+	next.RateLimit = cfg.RateLimit
+	next.RateLimitBurst = cfg.RateLimitBurst
+	next.Features = cfg.Features
+	if s.staticDirReloadable(cfg) {
+		next.StaticDir = cfg.StaticDir
+	}
+
+	// validate everything that can fail before changing anything
+	staticAbsPath, err := filepath.Abs(next.StaticDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := logger.SetLevel(next.LogLevel); err != nil {
+		return nil, err
+	}
+
+	if s.staticDirReloadable(cfg) {
+		s.static.setDir(staticAbsPath)
+	}
+	if s.cors != nil {
+		s.cors.SetOrigins(next.CORSOrigins...)
+	}
+	if s.limiter != nil {
+		s.limiter.SetLimit(next.RateLimit, next.RateLimitBurst)
+	}
+	s.live.Store(&next)
+
+	var failed []string
+	for _, reloader := range s.reloaders {
+		if err := reloader.Reload(&next); err != nil {
+			logger.Error("Reload hook returned an error", "error", err.Error())
+			failed = append(failed, err.Error())
+		}
+	}
+
+	if len(failed) != 0 {
+		return report, fmt.Errorf("settings applied but reload hooks failed: %s", strings.Join(failed, "; "))
+	}
+
+	return report, nil
+}
+
+// staticDirReloadable reports whether the static directory
+// can be swapped, which requires static files to be served
+// from a directory in the first place
+func (s *Server) staticDirReloadable(cfg *Config) bool {
+	return s.static != nil && s.config.StaticFS == nil && cfg.StaticDir != ""
+}
+
+// currentConfig returns the configuration
+// in effect including any reloaded settings
+func (s *Server) currentConfig() *Config {
+	if cfg, ok := s.live.Load().(*Config); ok {
+		return cfg
+	}
+	return s.config
+}
+
+// watchConfig blocks until the context is done,
+// reloading the configuration on SIGHUP or when
+// any of the watched files change
+func (s *Server) watchConfig(ctx context.Context, interval time.Duration) {
+	if s.loader == nil {
+		return
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	versions := fileVersions(s.configFiles)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logger.Info("SIGHUP received. Reloading configuration")
+			s.reloadFromSource()
+		case <-ticker.C:
+			latest := fileVersions(s.configFiles)
+			if reflect.DeepEqual(versions, latest) {
+				continue
+			}
+			versions = latest
+
+			logger.Info("Configuration file changed. Reloading configuration")
+			s.reloadFromSource()
+		}
+	}
+}
+
+func (s *Server) reloadFromSource() {
+	cfg, err := s.loader()
+	if err != nil {
+		logger.Error("Unable to load configuration", "error", err.Error())
+		return
+	}
+
+	report, err := s.Reload(cfg)
+	if err != nil {
+		logger.Error("Unable to reload configuration", "error", err.Error())
+		if report == nil {
+			return
+		}
+	}
+
+	logger.Info("Configuration reloaded",
+		"applied",
+		report.Applied,
+		"requiresRestart",
+		report.RequiresRestart,
+	)
+}
+
+// fileVersions identifies the version of each file
+// by its modification time and size
+func fileVersions(files []string) map[string]string {
+	versions := make(map[string]string, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			versions[file] = ""
+			continue
+		}
+		versions[file] = fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+	}
+	return versions
+}
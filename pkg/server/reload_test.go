@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+type testReloader struct {
+	cfg *Config
+	err error
+}
+
+func (r *testReloader) Reload(cfg *Config) error {
+	r.cfg = cfg
+	return r.err
+}
+
+func TestReload(t *testing.T) {
+	defer logger.SetLevel("")
+
+	t.Run("applies reloadable settings", func(t *testing.T) {
+		testHandler := func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			body := "off"
+			if util.FeatureEnabled(ctx, "beta") {
+				body = "on"
+			}
+
+			return &util.Response{
+				StatusCode: http.StatusOK,
+				Body:       body,
+			}, nil
+		}
+
+		reloader := &testReloader{}
+		srv := Server{
+			config: &Config{
+				Port:    "3001",
+				Timeout: 30,
+			},
+		}
+		srv.AddReloaders(reloader)
+		srv.AddRoutes(util.Route{
+			Name:    "test",
+			Path:    "/test",
+			Method:  http.MethodGet,
+			Handler: testHandler,
+		})
+		handler := srv.handler()
+
+		report, err := srv.Reload(&Config{
+			Port:        "3002",
+			Timeout:     30,
+			LogLevel:    "warn",
+			CORSOrigins: []string{"https://example.com"},
+			RateLimit:   1,
+			Features: map[string]bool{
+				"beta": true,
+			},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"LogLevel", "CORSOrigins", "RateLimit", "Features"}, report.Applied)
+		assert.Equal(t, []string{"Port"}, report.RequiresRestart)
+		assert.Equal(t, "3001", srv.currentConfig().Port)
+		assert.Equal(t, "warn", reloader.cfg.LogLevel)
+
+		req, err := http.NewRequest(http.MethodGet, "/test", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		req.Header.Set("Origin", "https://example.com")
+		req.RemoteAddr = "10.0.0.1:1234"
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "on", rr.Body.String())
+		assert.Equal(t, "https://example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	})
+
+	t.Run("swaps static directory", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "static")
+		assert.Nil(t, err)
+		defer os.RemoveAll(dir)
+
+		err = ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("reloaded"), 0644)
+		assert.Nil(t, err)
+
+		srv := Server{
+			config: &Config{
+				StaticDir: "./../../static",
+			},
+		}
+		router := srv.mountRoutes()
+
+		report, err := srv.Reload(&Config{
+			StaticDir: dir,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"StaticDir"}, report.Applied)
+
+		req, err := http.NewRequest(http.MethodGet, "/static/", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "reloaded", rr.Body.String())
+	})
+
+	t.Run("static directory not mounted", func(t *testing.T) {
+		srv := Server{
+			config: &Config{},
+		}
+		srv.mountRoutes()
+
+		report, err := srv.Reload(&Config{
+			StaticDir: "./../../static",
+		})
+		assert.Nil(t, err)
+		assert.Empty(t, report.Applied)
+		assert.Equal(t, []string{"StaticDir"}, report.RequiresRestart)
+	})
+
+	t.Run("invalid settings are not applied", func(t *testing.T) {
+		srv := Server{
+			config: &Config{},
+		}
+		srv.handler()
+
+		_, err := srv.Reload(&Config{
+			LogLevel:  "loud",
+			RateLimit: 1,
+		})
+		assert.NotNil(t, err)
+		assert.Equal(t, float64(0), srv.currentConfig().RateLimit)
+		assert.True(t, srv.limiter.Allow("client"))
+		assert.True(t, srv.limiter.Allow("client"))
+	})
+
+	t.Run("concurrent with the handler", func(t *testing.T) {
+		srv := Server{
+			config: &Config{
+				StaticDir: "./../../static",
+			},
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			srv.Reload(&Config{
+				StaticDir:   "./../../static",
+				CORSOrigins: []string{"https://example.com"},
+				RateLimit:   1,
+			})
+		}()
+		srv.handler()
+		<-done
+	})
+
+	t.Run("failing hooks", func(t *testing.T) {
+		srv := Server{
+			config: &Config{},
+		}
+		srv.AddReloaders(&testReloader{err: errors.New("hook failed")})
+
+		report, err := srv.Reload(&Config{
+			RateLimit: 1,
+		})
+		assert.EqualError(t, err, "settings applied but reload hooks failed: hook failed")
+		assert.Equal(t, []string{"RateLimit"}, report.Applied)
+		assert.Equal(t, float64(1), srv.currentConfig().RateLimit)
+	})
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(path, []byte(`{"features":{"beta":false}}`), 0644)
+	assert.Nil(t, err)
+
+	srv := Server{
+		config: &Config{},
+	}
+	srv.WatchConfig(func() (*Config, error) {
+		return LoadConfigFile(path, nil)
+	}, path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.watchConfig(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	// give the watcher time to record the current version of the file
+	time.Sleep(50 * time.Millisecond)
+
+	err = ioutil.WriteFile(path, []byte(`{"features":{"beta":true}}`), 0644)
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		return srv.currentConfig().Features["beta"]
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
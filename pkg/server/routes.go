@@ -0,0 +1,188 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/riyadhalnur/godi/v2/pkg/middleware"
+	"github.com/riyadhalnur/godi/v2/pkg/openapi"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+	"github.com/riyadhalnur/godi/v2/pkg/traffic"
+)
+
+// RouteInfo describes a route mounted by the server
+type RouteInfo struct {
+	Name   string
+	Method string
+	Path   string
+	// Middlewares wrapping the handler, outermost first
+	Middlewares []string
+}
+
+// Routes lists the routes of the server, including the
+// built-in health and static routes, in the order they are matched
+func (s *Server) Routes() []RouteInfo {
+	cfg := s.config.Effective()
+
+	global := []string{}
+	if len(cfg.CORSOrigins) > 0 {
+		global = append(global, "middleware.CORS")
+	}
+	if cfg.RateLimit > 0 {
+		global = append(global, "middleware.RateLimiter")
+	}
+	if cfg.Compress {
+		global = append(global, "middleware.Compress")
+	}
+	global = append(global, "middleware.RequestID")
+
+	routes := []RouteInfo{}
+	if cfg.StaticFS != nil || cfg.StaticDir != "" {
+		routes = append(routes, RouteInfo{
+			Name:        "static",
+			Method:      "*",
+			Path:        strings.TrimSuffix(cfg.StaticPrefix, "/") + "/",
+			Middlewares: global,
+		})
+	}
+	routes = append(routes, RouteInfo{
+		Name:        "health",
+		Method:      http.MethodGet,
+		Path:        "/health",
+		Middlewares: global,
+	})
+
+	app := append([]string{}, global...)
+	for _, mw := range s.middlewares {
+		app = append(app, funcName(mw))
+	}
+
+	middlewares := s.routeMiddlewares()
+	for _, route := range s.routers {
+		info := RouteInfo{
+			Name:        route.Name,
+			Method:      route.Method,
+			Path:        route.Path,
+			Middlewares: append([]string{}, app...),
+		}
+		if route.WebSocket != nil {
+			info.Method = http.MethodGet
+			routes = append(routes, info)
+			continue
+		}
+
+		for _, mw := range middlewares {
+			if mw.applies(route) {
+				info.Middlewares = append(info.Middlewares, mw.name)
+			}
+		}
+		routes = append(routes, info)
+	}
+
+	return routes
+}
+
+// routeMiddleware is a built-in middleware wrapping the handlers of routes
+// name - listed by Routes e.g. middleware.Cache
+// applies - reports whether the middleware wraps the handler of the route
+// wrap - returns the middleware for the route
+type routeMiddleware struct {
+	name    string
+	applies func(route util.Route) bool
+	wrap    func(route util.Route) func(http.Handler) http.Handler
+}
+
+// routeMiddlewares returns the built-in middlewares of the HTTP routes,
+// outermost first. Used to mount the routes and to list them so
+// that both always agree
+func (s *Server) routeMiddlewares() []routeMiddleware {
+	unsafe := func(route util.Route) bool {
+		return route.Method == http.MethodPost || route.Method == http.MethodPatch
+	}
+
+	return []routeMiddleware{
+		{
+			name:    "middleware.BodyLimit",
+			applies: func(util.Route) bool { return true },
+			wrap: func(route util.Route) func(http.Handler) http.Handler {
+				return middleware.BodyLimit(middleware.BodyLimitOptions{
+					MaxBytes:             s.maxBodyBytes(route),
+					MaxDecompressedBytes: s.config.MaxDecompressedBodyBytes,
+				})
+			},
+		},
+		// recorded once decompressed, as the client of the route sees it
+		{
+			name:    "middleware.Record",
+			applies: func(util.Route) bool { return s.config.RecordFile != "" },
+			wrap: shared(func() func(http.Handler) http.Handler {
+				return middleware.Record(middleware.RecordOptions{
+					Writer:   s.recorder,
+					Redactor: traffic.NewRedactor(s.config.RecordRedactHeaders, s.config.RecordRedactFields),
+				})
+			}),
+		},
+		// responses are stored once the body is limited and decompressed
+		{
+			name:    "middleware.Idempotency",
+			applies: func(route util.Route) bool { return s.config.Idempotency && unsafe(route) },
+			wrap: shared(func() func(http.Handler) http.Handler {
+				return middleware.Idempotency(middleware.IdempotencyOptions{
+					Store: s.idempotencyStore(),
+					TTL:   s.config.IdempotencyTTL,
+				})
+			}),
+		},
+		{
+			name: "middleware.Cache",
+			applies: func(route util.Route) bool {
+				if !s.config.Cache || route.Method != http.MethodGet {
+					return false
+				}
+				_, ok := s.cacheOptions(route)
+				return ok
+			},
+			wrap: func(route util.Route) func(http.Handler) http.Handler {
+				opts, _ := s.cacheOptions(route)
+				return s.responseCache().Handler(opts)
+			},
+		},
+		{
+			name:    "middleware.Contract",
+			applies: func(util.Route) bool { return s.config.ContractValidation },
+			wrap: shared(func() func(http.Handler) http.Handler {
+				return middleware.Contract(middleware.ContractOptions{
+					Validator: openapi.NewValidator(s.OpenAPI(openapi.Info{})),
+				})
+			}),
+		},
+	}
+}
+
+// shared returns a wrap creating the middleware
+// once and using it for all the routes
+func shared(create func() func(http.Handler) http.Handler) func(util.Route) func(http.Handler) http.Handler {
+	var mw func(http.Handler) http.Handler
+	return func(util.Route) func(http.Handler) http.Handler {
+		if mw == nil {
+			mw = create()
+		}
+		return mw
+	}
+}
+
+// OpenAPI returns the OpenAPI document of the routes added to the server
+func (s *Server) OpenAPI(info openapi.Info) *openapi.Document {
+	return openapi.Generate(info, s.routers)
+}
+
+// funcName returns the package qualified name of a function e.g. middleware.Logger
+func funcName(fn interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	if idx := strings.LastIndex(name, "/"); idx != -1 {
+		name = name[idx+1:]
+	}
+	return strings.TrimSuffix(name, "-fm")
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/openapi"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+func logRequests(next http.Handler) http.Handler {
+	return next
+}
+
+func TestRoutes(t *testing.T) {
+	handler := func(ctx context.Context, req *util.Request) (*util.Response, error) {
+		return &util.Response{StatusCode: http.StatusOK}, nil
+	}
+
+	srv := Server{config: &Config{
+		Port:               "3000",
+		Timeout:            30,
+		StaticDir:          "static",
+		CORSOrigins:        []string{"https://example.com"},
+		Idempotency:        true,
+		Cache:              true,
+		ContractValidation: true,
+		RecordFile:         "traffic.jsonl",
+	}}
+	srv.AddMiddlewares(logRequests)
+	srv.AddRoutes(
+		util.Route{Name: "getUser", Path: "/users/{id}", Method: http.MethodGet, Handler: handler},
+		util.Route{Name: "createUser", Path: "/users", Method: http.MethodPost, Handler: handler},
+		util.Route{Name: "report", Path: "/report", Method: http.MethodGet, Handler: handler, Cache: &util.CachePolicy{TTL: -1}},
+		util.Route{Name: "events", Path: "/events", WebSocket: &util.WebSocket{}},
+	)
+
+	global := []string{"middleware.CORS", "middleware.RequestID"}
+	app := append(global, "server.logRequests")
+	assert.Equal(t, []RouteInfo{
+		{Name: "static", Method: "*", Path: "/static/", Middlewares: global},
+		{Name: "health", Method: http.MethodGet, Path: "/health", Middlewares: global},
+		{Name: "getUser", Method: http.MethodGet, Path: "/users/{id}", Middlewares: append(app, "middleware.BodyLimit", "middleware.Record", "middleware.Cache", "middleware.Contract")},
+		{Name: "createUser", Method: http.MethodPost, Path: "/users", Middlewares: append(app, "middleware.BodyLimit", "middleware.Record", "middleware.Idempotency", "middleware.Contract")},
+		{Name: "report", Method: http.MethodGet, Path: "/report", Middlewares: append(app, "middleware.BodyLimit", "middleware.Record", "middleware.Contract")},
+		{Name: "events", Method: http.MethodGet, Path: "/events", Middlewares: app},
+	}, srv.Routes())
+
+	t.Run("openapi", func(t *testing.T) {
+		doc := srv.OpenAPI(openapi.Info{Title: "Users", Version: "1.0.0"})
+
+		assert.Equal(t, "Users", doc.Info.Title)
+		assert.Len(t, doc.Paths, 3)
+		assert.Equal(t, "createUser", (*doc.Paths["/users"])["post"].OperationID)
+	})
+}
@@ -2,28 +2,33 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"html/template"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/riyadhalnur/godi/v2/pkg/di"
 	"github.com/riyadhalnur/godi/v2/pkg/godierr"
 
+	"github.com/riyadhalnur/godi/v2/pkg/i18n"
 	"github.com/riyadhalnur/godi/v2/pkg/logger"
 
 	"github.com/riyadhalnur/godi/v2/pkg/middleware"
 	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+	"github.com/riyadhalnur/godi/v2/pkg/traffic"
 
 	"github.com/gorilla/mux"
 )
 
-const (
-	staticPathPrefix string = "/static"
-)
-
 // Server holds the configurations,
 // routes, and the middlewares
 // to mount when an instance of Server starts
@@ -31,6 +36,38 @@ type Server struct {
 	config      *Config
 	routers     []util.Route
 	middlewares []mux.MiddlewareFunc
+
+	// reloadable state
+	reloadMu    sync.Mutex
+	live        atomic.Value
+	reloaders   []Reloader
+	loader      ConfigLoader
+	configFiles []string
+	static      *staticHandler
+	cors        *middleware.CORS
+	limiter     *middleware.RateLimiter
+
+	websockets wsHub
+
+	encodersOnce sync.Once
+	encoders     *util.Encoders
+
+	templateFuncs template.FuncMap
+	templates     *util.Templates
+
+	locales       *i18n.Catalogs
+	debugNetworks []*net.IPNet
+
+	idempotency middleware.IdempotencyStore
+	cacheOnce   sync.Once
+	cache       *middleware.Cache
+
+	recordFile *os.File
+	recorder   *traffic.Writer
+
+	container *di.Container
+
+	healthChecks []HealthChecker
 }
 
 // NewServer returns a new instance of Server
@@ -44,7 +81,7 @@ func NewServer(cfg *Config) *Server {
 // Listen will handle incoming HTTP requests
 // Blocks until an interrupt is received
 func (s *Server) Listen() error {
-	if s.config.Timeout == 0 {
+	if s.config.Timeout == 0 && !s.config.hasTimeouts() {
 		return godierr.RequiredArgsError("timeout")
 	}
 
@@ -52,14 +89,46 @@ func (s *Server) Listen() error {
 		return godierr.RequiredArgsError("port")
 	}
 
+	if err := logger.SetLevel(s.config.LogLevel); err != nil {
+		return godierr.InvalidArgsError("log level")
+	}
+
+	if s.config.StaticFS == nil && s.config.StaticDir != "" {
+		if _, err := filepath.Abs(s.config.StaticDir); err != nil {
+			return godierr.InvalidArgsError("static directory")
+		}
+	}
+
+	if s.config.SPAFallback && s.config.StaticFS == nil && s.config.StaticDir == "" {
+		return godierr.RequiredArgsError("static directory")
+	}
+
+	if err := s.prepare(); err != nil {
+		return err
+	}
+	if s.container != nil {
+		defer s.closeContainer()
+	}
+	if s.recordFile != nil {
+		defer s.closeRecording()
+	}
+
 	listenPort := s.config.Port
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", listenPort),
-		WriteTimeout: time.Duration(s.config.Timeout) * time.Second,
-		ReadTimeout:  time.Duration(s.config.Timeout) * time.Second,
-		IdleTimeout:  time.Duration(s.config.Timeout) * time.Second,
-		Handler:      s.mountRoutes(),
+		Addr:              fmt.Sprintf(":%s", listenPort),
+		WriteTimeout:      s.config.writeTimeout(),
+		ReadTimeout:       s.config.readTimeout(),
+		ReadHeaderTimeout: s.config.ReadHeaderTimeout,
+		IdleTimeout:       s.config.idleTimeout(),
+		MaxHeaderBytes:    s.config.MaxHeaderBytes,
+		Handler:           s.handler(),
 	}
+	// hijacked WebSocket connections are not tracked by Shutdown
+	srv.RegisterOnShutdown(s.websockets.closeAll)
+
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go s.watchConfig(watchCtx, configPollInterval)
 
 	go func() {
 		logger.Infof("Server listening on port %s", listenPort)
@@ -74,9 +143,10 @@ func (s *Server) Listen() error {
 	<-quit
 
 	logger.Debugf("Interrupt received. Starting shutdown")
+	stopWatching()
 
 	// wait for active connections to finish their jobs
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.config.Timeout)*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.shutdownTimeout())
 	defer cancel()
 
 	srv.SetKeepAlivesEnabled(false)
@@ -85,9 +155,70 @@ func (s *Server) Listen() error {
 		return err
 	}
 
+	if err := s.websockets.wait(ctx); err != nil {
+		logger.Debugf("Could not close WebSocket connections gracefully err=%v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Handler returns the handler serving the routes of the server
+// without listening e.g. to test the server in-process. Fails
+// like Listen when the templates, locales or dependencies do not load
+func (s *Server) Handler() (http.Handler, error) {
+	if err := s.prepare(); err != nil {
+		return nil, err
+	}
+	return s.handler(), nil
+}
+
+// prepare loads what the routes need before serving requests
+func (s *Server) prepare() error {
+	if err := s.loadTemplates(); err != nil {
+		return err
+	}
+
+	if err := s.loadLocales(); err != nil {
+		return err
+	}
+
+	if err := s.loadDebugNetworks(); err != nil {
+		return err
+	}
+
+	if err := s.openRecording(); err != nil {
+		return err
+	}
+
+	if s.container != nil {
+		return s.container.Build()
+	}
+	return nil
+}
+
+// openRecording opens the file exchanges are recorded to, if any
+func (s *Server) openRecording() error {
+	if s.config.RecordFile == "" || s.recorder != nil {
+		return nil
+	}
+
+	f, err := os.OpenFile(s.config.RecordFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	s.recordFile = f
+	s.recorder = traffic.NewWriter(f)
 	return nil
 }
 
+// closeRecording closes the file exchanges are recorded to
+func (s *Server) closeRecording() {
+	if err := s.recordFile.Close(); err != nil {
+		logger.Errorf("Unable to close record file err=%v", err.Error())
+	}
+}
+
 // Close shutdowns the server immediately
 func (s *Server) Close() error {
 	return s.Close()
@@ -98,6 +229,41 @@ func (s *Server) AddRoutes(routes ...util.Route) {
 	s.routers = append(s.routers, routes...)
 }
 
+// Container returns the dependency injection container of the server.
+// Providers must be registered before the server starts, which checks
+// the dependencies and creates the singletons
+func (s *Server) Container() *di.Container {
+	if s.container == nil {
+		s.container = di.New()
+	}
+	return s.container
+}
+
+// closeContainer closes the singletons of the container
+func (s *Server) closeContainer() {
+	if err := s.container.Close(); err != nil {
+		logger.Errorf("Unable to close dependencies err=%v", err.Error())
+	}
+}
+
+// AddEncoders registers the encoder(s) used for content negotiation
+// on top of the built-in ones. An encoder replaces any built-in
+// encoder for the same content type
+func (s *Server) AddEncoders(encoders ...util.Encoder) {
+	s.encoderRegistry().Register(encoders...)
+}
+
+// AddTemplateFuncs adds the function(s) available to the templates.
+// Must be called before the server starts
+func (s *Server) AddTemplateFuncs(funcs template.FuncMap) {
+	if s.templateFuncs == nil {
+		s.templateFuncs = template.FuncMap{}
+	}
+	for name, fn := range funcs {
+		s.templateFuncs[name] = fn
+	}
+}
+
 // AddMiddlewares appends the middleware(s) to mount
 // Middlewares should be ordered according to their functionality
 func (s *Server) AddMiddlewares(middleware ...mux.MiddlewareFunc) {
@@ -129,42 +295,50 @@ func (s *Server) handleHTTP(handler util.APIHandlerFunc) http.HandlerFunc {
 			req.RemoteAddr,
 		)
 
-		res, err := handler(ctx, req)
-		if err != nil {
-			if godiErr, ok := err.(*godierr.Error); ok {
-				logger.Error("HTTP handler returned an error",
-					"code",
-					godiErr.Code(),
-					"type",
-					godiErr.Type(),
-					"error",
-					godiErr.Error(),
-					"requestId",
-					ctx.Value(util.RequestIDKey).(string),
-					"latency",
-					time.Since(start).String(),
-				)
-
-				util.ErrorJSON(w, &util.ErrorResponse{
-					Code:    godiErr.Code(),
-					Type:    godiErr.Type(),
-					Message: godiErr.Message(),
-				})
-				return
+		cfg := s.currentConfig()
+		if cfg.Features != nil {
+			ctx = context.WithValue(ctx, util.FeaturesKey, cfg.Features)
+		}
+
+		// the handler is bound by the handler timeout while streamed
+		// responses, sharing the values of its context, are not
+		handlerCtx := ctx
+		if cfg.HandlerTimeout > 0 {
+			var cancel context.CancelFunc
+			handlerCtx, cancel = context.WithTimeout(ctx, cfg.HandlerTimeout)
+			defer cancel()
+		}
+
+		if s.container != nil {
+			scope := s.container.NewScope(handlerCtx, req)
+			// disposed once both the response is written and the handler
+			// returned, which is later when the handler timed out
+			pending := int32(2)
+			release := func() {
+				if atomic.AddInt32(&pending, -1) == 0 {
+					s.disposeScope(scope, ctx)
+				}
+			}
+			defer release()
+			next := handler
+			handler = func(ctx context.Context, req *util.Request) (*util.Response, error) {
+				defer release()
+				return next(ctx, req)
 			}
+			ctx = di.WithScope(ctx, scope)
+			handlerCtx = di.WithScope(handlerCtx, scope)
+		}
+
+		res, err := callHandler(handlerCtx, handler, req)
+		if err == nil && res.Value != nil {
+			err = util.EncodeValue(r.Header.Get("Accept"), s.encoderRegistry(), res)
+		}
+		if err == nil && res.View != nil {
+			err = s.renderView(res)
+		}
 
-			logger.Error("HTTP handler returned an error",
-				"error",
-				err.Error(),
-				"requestId",
-				ctx.Value(util.RequestIDKey).(string),
-				"latency",
-				time.Since(start).String(),
-			)
-
-			util.ErrorJSON(w, &util.ErrorResponse{
-				Code: http.StatusInternalServerError,
-			})
+		if err != nil {
+			s.respondError(w, r, err, start)
 			return
 		}
 
@@ -181,46 +355,387 @@ func (s *Server) handleHTTP(handler util.APIHandlerFunc) http.HandlerFunc {
 			time.Since(start).String(),
 		)
 
+		if res.Stream != nil {
+			s.stream(ctx, w, res, start)
+			return
+		}
+
 		util.RespondJSON(w, res)
 	}
 }
 
+// disposeScope disposes the per request dependencies
+// once the response is written and the handler returned
+func (s *Server) disposeScope(scope *di.RequestScope, ctx context.Context) {
+	if err := scope.Dispose(); err != nil {
+		logger.Error("Unable to dispose request dependencies",
+			"requestId",
+			ctx.Value(util.RequestIDKey).(string),
+			"error",
+			err.Error(),
+		)
+	}
+}
+
+// respondError logs the error returned while handling the request and
+// responds with it, encoded in the media type the client prefers.
+// Only godierr errors, including ones wrapped by other errors,
+// are passed on to the client, with the message translated in the
+// language the client accepts. Their fields are only logged.
+// Server errors get an ID to find them in the logs and, unless
+// ExposeServerErrors is set, the default message of their type
+func (s *Server) respondError(w http.ResponseWriter, r *http.Request, err error, start time.Time) {
+	ctx := r.Context()
+	accept := r.Header.Get("Accept")
+
+	res := &util.ErrorResponse{
+		Code: http.StatusInternalServerError,
+	}
+	if s.debugErrors(r) {
+		res.Causes = errorCauses(err)
+	}
+
+	var godiErr *godierr.Error
+	if errors.As(err, &godiErr) {
+		keysAndValues := []interface{}{
+			"code",
+			godiErr.Code(),
+			"type",
+			godiErr.Type(),
+			"error",
+			err.Error(),
+			"requestId",
+			ctx.Value(util.RequestIDKey).(string),
+			"latency",
+			time.Since(start).String(),
+		}
+		if fields := godiErr.Fields(); len(fields) != 0 {
+			keysAndValues = append(keysAndValues, "fields", fields)
+		}
+		// the stack only helps with errors on the server side
+		if godiErr.Code() >= http.StatusInternalServerError {
+			res.ID = errorID()
+			keysAndValues = append(keysAndValues, "errorId", res.ID, "stack", godiErr.Stack())
+		}
+		logger.Error("HTTP handler returned an error", keysAndValues...)
+
+		if !s.config.ExposeServerErrors {
+			godiErr = redactError(godiErr)
+		}
+		res.Code = godiErr.Code()
+		res.Type = godiErr.Type()
+		res.Message = s.localizeError(w, r, godiErr)
+
+		util.RespondError(w, accept, s.encoderRegistry(), res)
+		return
+	}
+
+	res.ID = errorID()
+	logger.Error("HTTP handler returned an error",
+		"error",
+		err.Error(),
+		"errorId",
+		res.ID,
+		"requestId",
+		ctx.Value(util.RequestIDKey).(string),
+		"latency",
+		time.Since(start).String(),
+	)
+
+	util.RespondError(w, accept, s.encoderRegistry(), res)
+}
+
+// rejectRequest returns the error of a middleware rejecting the request,
+// negotiated and localized as the errors returned by the handlers
+func (s *Server) rejectRequest(w http.ResponseWriter, r *http.Request, err *godierr.Error) {
+	if !s.config.ExposeServerErrors {
+		err = redactError(err)
+	}
+
+	util.RespondError(w, r.Header.Get("Accept"), s.encoderRegistry(), &util.ErrorResponse{
+		Code:    err.Code(),
+		Type:    err.Type(),
+		Message: s.localizeError(w, r, err),
+	})
+}
+
+// stream writes a streaming response. The stream is stopped when
+// the client disconnects or just before the write timeout is hit
+// so that the connection is not cut off in the middle of a write.
+// Takes the context of the handler without its timeout
+func (s *Server) stream(ctx context.Context, w http.ResponseWriter, res *util.Response, start time.Time) {
+	requestID := ctx.Value(util.RequestIDKey).(string)
+
+	if timeout := s.currentConfig().writeTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, start.Add(timeout*9/10))
+		defer cancel()
+	}
+
+	if err := util.RespondStream(ctx, w, res); err != nil && ctx.Err() == nil {
+		logger.Error("Unable to stream HTTP response",
+			"error",
+			err.Error(),
+			"requestId",
+			requestID,
+			"latency",
+			time.Since(start).String(),
+		)
+		return
+	}
+
+	logger.Info("Finished streaming HTTP response",
+		"requestId",
+		requestID,
+		"latency",
+		time.Since(start).String(),
+	)
+}
+
+// callHandler runs the handler and returns its result. When the context
+// carries a deadline, the handler is run in its own goroutine so that
+// the request can be answered as soon as the context is done even if
+// the handler does not respect cancellation
+func callHandler(ctx context.Context, handler util.APIHandlerFunc, req *util.Request) (*util.Response, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return handler(ctx, req)
+	}
+
+	type result struct {
+		res *util.Response
+		err error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		res, err := handler(ctx, req)
+		done <- result{res, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil && ctx.Err() != nil {
+			return nil, contextError(ctx)
+		}
+		return r.res, r.err
+	case <-ctx.Done():
+		return nil, contextError(ctx)
+	}
+}
+
+// contextError maps the reason a context is done
+// to the matching godierr error
+func contextError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return godierr.TimeoutError(ctx.Err())
+	}
+	return godierr.UnavailableError(ctx.Err())
+}
+
+// encoderRegistry returns the encoders used for content
+// negotiation, creating the registry on first use
+func (s *Server) encoderRegistry() *util.Encoders {
+	s.encodersOnce.Do(func() {
+		s.encoders = util.DefaultEncoders()
+	})
+	return s.encoders
+}
+
+// handler wraps the router with the built-in middlewares
+// that need to run for every request, including the
+// ones not matching any route e.g. CORS preflight requests
+func (s *Server) handler() http.Handler {
+	// the middlewares updated by Reload are created under its lock
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	cfg := s.currentConfig()
+	s.cors = middleware.NewCORS(cfg.CORSOrigins...)
+	s.limiter = middleware.NewRateLimiter(cfg.RateLimit, cfg.RateLimitBurst)
+
+	var handler http.Handler = s.mountRoutes()
+	if cfg.Compress {
+		handler = middleware.Compress(middleware.CompressOptions{
+			MinSize:      cfg.CompressMinSize,
+			ContentTypes: cfg.CompressContentTypes,
+		})(handler)
+	}
+
+	return s.withErrorResponder(s.cors.Handler(s.limiter.Handler(handler)))
+}
+
+// withErrorResponder sets the ErrorResponder
+// the middlewares reject requests with
+func (s *Server) withErrorResponder(next http.Handler) http.Handler {
+	var respond util.ErrorResponder = s.rejectRequest
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), util.ErrorResponderKey, respond)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// idempotencyStore returns the store set in the config
+// or the in-memory store shared by all the routes
+func (s *Server) idempotencyStore() middleware.IdempotencyStore {
+	if s.config.IdempotencyStore != nil {
+		return s.config.IdempotencyStore
+	}
+	if s.idempotency == nil {
+		s.idempotency = middleware.NewMemoryIdempotencyStore()
+	}
+	return s.idempotency
+}
+
+// responseCache returns the cache shared by all the GET routes
+func (s *Server) responseCache() *middleware.Cache {
+	s.cacheOnce.Do(func() {
+		store := s.config.CacheStore
+		if store == nil {
+			store = middleware.NewMemoryCacheStore(s.config.CacheSize)
+		}
+		s.cache = middleware.NewCache(store)
+	})
+	return s.cache
+}
+
+// cacheOptions returns the caching options of the route. The
+// route policy takes precedence over the config. Reports
+// false when the route disabled caching
+func (s *Server) cacheOptions(route util.Route) (middleware.CacheOptions, bool) {
+	opts := middleware.CacheOptions{
+		TTL: s.config.CacheTTL,
+	}
+	if route.Cache != nil {
+		if route.Cache.TTL < 0 {
+			return opts, false
+		}
+		if route.Cache.TTL > 0 {
+			opts.TTL = route.Cache.TTL
+		}
+		opts.VaryHeaders = route.Cache.VaryHeaders
+		opts.Shared = route.Cache.Shared
+	}
+	return opts, true
+}
+
+// InvalidateCache drops the stored responses
+// of the paths e.g. /users/1 after an update
+func (s *Server) InvalidateCache(ctx context.Context, paths ...string) error {
+	return s.responseCache().Invalidate(ctx, paths...)
+}
+
+// InvalidateCachedRoutes drops the stored responses of the named routes
+func (s *Server) InvalidateCachedRoutes(ctx context.Context, names ...string) error {
+	return s.responseCache().InvalidateRoutes(ctx, names...)
+}
+
 func (s *Server) mountRoutes() *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
 
-	if s.config.StaticDir != "" {
-		staticAbsPath, err := filepath.Abs(s.config.StaticDir)
-		if err != nil {
-			logger.Errorf("Unable to read absolute path to static directory err=%v", err.Error())
+	if s.templates == nil {
+		if err := s.loadTemplates(); err != nil {
+			logger.Errorf("Unable to load templates err=%v", err.Error())
+		}
+	}
+
+	if s.locales == nil {
+		if err := s.loadLocales(); err != nil {
+			logger.Errorf("Unable to load locales err=%v", err.Error())
+		}
+	}
+
+	if s.container != nil {
+		if err := s.container.Build(); err != nil {
+			logger.Errorf("Unable to build dependencies err=%v", err.Error())
 		}
+	}
+
+	// Listen and Handler fail on invalid networks before mounting
+	// the routes. Without networks no request sees the causes
+	if s.debugNetworks == nil {
+		if err := s.loadDebugNetworks(); err != nil {
+			logger.Errorf("Unable to parse debug networks err=%v", err.Error())
+		}
+	}
+
+	if s.config.StaticFS != nil || s.config.StaticDir != "" {
+		s.mountStatic(router)
+	}
 
-		// serve static files at /static path
-		fs := http.FileServer(http.Dir(staticAbsPath))
-		router.PathPrefix(staticPathPrefix).Handler(http.StripPrefix(staticPathPrefix, fs))
+	if s.config.SPAFallback && s.static != nil {
+		router.NotFoundHandler = middleware.RequestID(s.spaFallback(s.config.SPAExclude))
 	}
 
 	router.Use(middleware.RequestID)
 
 	// mount the health enpoint. useful for Kubernetes integration among other things
-    router.Name("health").Path("/health").HandlerFunc(healthCheckHandler).Methods(http.MethodGet)
+	router.Name("health").Path("/health").HandlerFunc(s.healthCheckHandler).Methods(http.MethodGet)
 
-    subrouter := router.PathPrefix("/").Subrouter().StrictSlash(true)
+	subrouter := router.PathPrefix("/").Subrouter().StrictSlash(true)
 
 	logger.Debug("Mounting middlewares")
 	for _, mw := range s.middlewares {
 		subrouter.Use(mw)
 	}
 
+	middlewares := s.routeMiddlewares()
 	for _, route := range s.routers {
+		if route.WebSocket != nil {
+			logger.Debug("Mounting WebSocket route", "name", route.Name, "path", route.Path)
+			subrouter.Name(route.Name).Path(route.Path).HandlerFunc(s.handleWebSocket(route.WebSocket)).Methods(http.MethodGet)
+			continue
+		}
+
 		logger.Debug("Mounting route", "name", route.Name, "path", route.Path, "method", route.Method)
-		subrouter.Name(route.Name).Path(route.Path).HandlerFunc(s.handleHTTP(route.Handler)).Methods(route.Method)
+		var handler http.Handler = s.handleHTTP(route.Handler)
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			if mw := middlewares[i]; mw.applies(route) {
+				handler = mw.wrap(route)(handler)
+			}
+		}
+		subrouter.Name(route.Name).Path(route.Path).Handler(handler).Methods(route.Method)
 	}
 
 	return router
 }
 
-func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-    w.Header().Set("Content-Type", "application/json")
-    w.WriteHeader(http.StatusOK)
-    w.Write([]byte("ok"))
+// mountStatic serves the static files at the configured prefix
+func (s *Server) mountStatic(router *mux.Router) {
+	static := newStaticHandler(s.config)
+
+	if s.config.StaticFS != nil {
+		static.setFS(s.config.StaticFS)
+	} else {
+		staticAbsPath, err := filepath.Abs(s.currentConfig().StaticDir)
+		if err != nil {
+			logger.Errorf("Unable to read absolute path to static directory err=%v", err.Error())
+			return
+		}
+		static.setDir(staticAbsPath)
+	}
+
+	s.static = static
+	router.PathPrefix(static.prefix).Handler(http.StripPrefix(static.prefix, static))
+}
+
+// AssetURL returns the URL of a static file with a fingerprint
+// of its content e.g. /static/css/main.0123456789.css for css/main.css.
+// Fingerprinted URLs are cached by clients indefinitely, so a new
+// URL is used whenever the file changes
+func (s *Server) AssetURL(name string) string {
+	if s.static == nil {
+		return path.Join(defaultStaticPrefix, name)
+	}
+	return s.static.assetURL(name)
+}
+
+// maxBodyBytes returns the maximum size of the request body for the route
+func (s *Server) maxBodyBytes(route util.Route) int64 {
+	if route.MaxBodyBytes != 0 {
+		return route.MaxBodyBytes
+	}
+	if s.config.MaxBodyBytes != 0 {
+		return s.config.MaxBodyBytes
+	}
+	return middleware.DefaultMaxBodyBytes
 }
@@ -1,17 +1,28 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/riyadhalnur/godi/v2/pkg/di"
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
 	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+	"github.com/riyadhalnur/godi/v2/pkg/traffic"
 )
 
 func TestRouteMount(t *testing.T) {
@@ -30,10 +41,10 @@ func TestRouteMount(t *testing.T) {
 
 	testRoutes := []util.Route{
 		util.Route{
-			"test",
-			"/test",
-			http.MethodGet,
-			testHandler,
+			Name:    "test",
+			Path:    "/test",
+			Method:  http.MethodGet,
+			Handler: testHandler,
 		},
 	}
 
@@ -61,6 +72,25 @@ func TestHealthCheck(t *testing.T) {
 
 	assert.Equal(t, "ok", rr.Body.String())
 	assert.Equal(t, http.StatusOK, rr.Code)
+
+	t.Run("failing check", func(t *testing.T) {
+		srv.AddHealthChecks(healthCheckFunc(func(ctx context.Context) error {
+			return godierr.UnavailableError(errors.New("connection refused"))
+		}))
+		router := srv.mountRoutes()
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, "unavailable", rr.Body.String())
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	})
+}
+
+type healthCheckFunc func(ctx context.Context) error
+
+func (f healthCheckFunc) CheckHealth(ctx context.Context) error {
+	return f(ctx)
 }
 
 func TestServerResponse(t *testing.T) {
@@ -76,10 +106,10 @@ func TestServerResponse(t *testing.T) {
 
 		testRoutes := []util.Route{
 			util.Route{
-				"test",
-				endpoint,
-				http.MethodGet,
-				testHandler,
+				Name:    "test",
+				Path:    endpoint,
+				Method:  http.MethodGet,
+				Handler: testHandler,
 			},
 		}
 
@@ -100,7 +130,147 @@ func TestServerResponse(t *testing.T) {
 		body, _ := ioutil.ReadAll(rr.Body)
 
 		assert.Equal(t, http.StatusInternalServerError, rr.Code)
-		assert.JSONEq(t, `{"code":500}`, string(body))
+		assert.JSONEq(t, `{"code":500}`, withoutErrorID(t, body))
+	})
+
+	t.Run("wrapped errors", func(t *testing.T) {
+		cases := []struct {
+			name string
+			err  error
+			body string
+		}{
+			{
+				"godierr wrapped by fmt",
+				fmt.Errorf("loading user: %w", godierr.InvalidArgsError("id").WithField("userId", "1")),
+				`{"code":400,"type":"INVALID_ARGUMENT","message":"invalid argument(s) passed in: id"}`,
+			},
+			{
+				"error wrapped by godierr",
+				godierr.Wrap(errors.New("connection refused"), "loading user"),
+				`{"code":500,"type":"INTERNAL","message":"internal server error"}`,
+			},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				srv := Server{
+					config: &Config{},
+				}
+				srv.AddRoutes(util.Route{
+					Name:   "test",
+					Path:   endpoint,
+					Method: http.MethodGet,
+					Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+						return nil, c.err
+					},
+				})
+				router := srv.mountRoutes()
+
+				req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+				if err != nil {
+					assert.Nil(t, err)
+				}
+
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, req)
+
+				assert.JSONEq(t, c.body, withoutErrorID(t, rr.Body.Bytes()))
+			})
+		}
+	})
+
+	t.Run("localized errors", func(t *testing.T) {
+		srv := Server{
+			config: &Config{
+				Locales: fstest.MapFS{
+					"en.json": {Data: []byte(`{"NOT_FOUND": "resource(s) not found: {resources}", "INTERNAL": "internal server error"}`)},
+					"bn.json": {Data: []byte(`{"NOT_FOUND": "খুঁজে পাওয়া যায়নি: {resources}"}`)},
+				},
+			},
+		}
+		srv.AddRoutes(
+			util.Route{
+				Name:   "not found",
+				Path:   endpoint,
+				Method: http.MethodGet,
+				Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+					return nil, godierr.NotFoundError("user")
+				},
+			},
+			util.Route{
+				Name:   "internal",
+				Path:   "/internal",
+				Method: http.MethodGet,
+				Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+					return nil, godierr.InternalError(errors.New("connection refused"))
+				},
+			},
+		)
+		router := srv.mountRoutes()
+
+		cases := []struct {
+			name           string
+			path           string
+			acceptLanguage string
+			lang           string
+			body           string
+		}{
+			{"regional variant", endpoint, "bn-BD,bn;q=0.9,en;q=0.8", "bn", `{"code":404,"type":"NOT_FOUND","message":"খুঁজে পাওয়া যায়নি: user"}`},
+			{"unknown language", endpoint, "fr-FR", "en", `{"code":404,"type":"NOT_FOUND","message":"resource(s) not found: user"}`},
+			{"missing translation", "/internal", "bn", "en", `{"code":500,"type":"INTERNAL","message":"internal server error"}`},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				req, err := http.NewRequest(http.MethodGet, c.path, nil)
+				if err != nil {
+					assert.Nil(t, err)
+				}
+				req.Header.Set("Accept-Language", c.acceptLanguage)
+
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, req)
+
+				assert.Equal(t, c.lang, rr.Header().Get("Content-Language"))
+				assert.Contains(t, rr.Header().Values("Vary"), "Accept-Language")
+				assert.JSONEq(t, c.body, withoutErrorID(t, rr.Body.Bytes()))
+			})
+		}
+	})
+
+	t.Run("localized middleware errors", func(t *testing.T) {
+		srv := Server{
+			config: &Config{
+				MaxBodyBytes: 4,
+				Locales: fstest.MapFS{
+					"en.json": {Data: []byte(`{"PAYLOAD_TOO_LARGE": "request body too large, limit: {limit} bytes"}`)},
+					"bn.json": {Data: []byte(`{"PAYLOAD_TOO_LARGE": "অনুরোধ খুব বড়: {limit} বাইট"}`)},
+				},
+			},
+		}
+		srv.AddRoutes(util.Route{
+			Name:   "create",
+			Path:   endpoint,
+			Method: http.MethodPost,
+			Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+				return &util.Response{StatusCode: http.StatusCreated}, nil
+			},
+		})
+		handler := srv.handler()
+
+		req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBufferString(`{"name":"godi"}`))
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		req.Header.Set("Accept-Language", "bn")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, "bn", rr.Header().Get("Content-Language"))
+		assert.Contains(t, rr.Header().Values("Vary"), "Accept")
+		assert.JSONEq(t, `{"code":413,"type":"PAYLOAD_TOO_LARGE","message":"অনুরোধ খুব বড়: 4 বাইট"}`, rr.Body.String())
 	})
 
 	t.Run("request error", func(t *testing.T) {
@@ -113,10 +283,10 @@ func TestServerResponse(t *testing.T) {
 
 		testRoutes := []util.Route{
 			util.Route{
-				"test",
-				endpoint,
-				http.MethodGet,
-				testHandler,
+				Name:    "test",
+				Path:    endpoint,
+				Method:  http.MethodGet,
+				Handler: testHandler,
 			},
 		}
 
@@ -150,10 +320,10 @@ func TestServerResponse(t *testing.T) {
 
 		testRoutes := []util.Route{
 			util.Route{
-				"test",
-				endpoint,
-				http.MethodGet,
-				testHandler,
+				Name:    "test",
+				Path:    endpoint,
+				Method:  http.MethodGet,
+				Handler: testHandler,
 			},
 		}
 
@@ -196,10 +366,10 @@ func TestServerResponse(t *testing.T) {
 
 		testRoutes := []util.Route{
 			util.Route{
-				"test",
-				endpoint,
-				http.MethodGet,
-				testHandler,
+				Name:    "test",
+				Path:    endpoint,
+				Method:  http.MethodGet,
+				Handler: testHandler,
 			},
 		}
 
@@ -234,6 +404,641 @@ func TestServerResponse(t *testing.T) {
 	})
 }
 
+func TestStreamingResponse(t *testing.T) {
+	testHandler := func(ctx context.Context, req *util.Request) (*util.Response, error) {
+		return util.SSEResponse(req, util.SSEOptions{}, func(ctx context.Context, stream *util.EventStream) error {
+			for i := 1; ; i++ {
+				if err := stream.Send(util.Event{ID: strconv.Itoa(i), Data: "tick"}); err != nil {
+					return err
+				}
+
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(20 * time.Millisecond):
+				}
+			}
+		}), nil
+	}
+
+	srv := Server{
+		config: &Config{
+			WriteTimeout: 100 * time.Millisecond,
+		},
+	}
+	srv.AddRoutes(util.Route{
+		Name:    "events",
+		Path:    "/events",
+		Method:  http.MethodGet,
+		Handler: testHandler,
+	})
+	router := srv.mountRoutes()
+
+	r := httptest.NewServer(router)
+	defer r.Close()
+
+	start := time.Now()
+	res, err := http.Get(r.URL + "/events")
+	assert.Nil(t, err)
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	assert.Nil(t, err)
+
+	assert.Less(t, int64(time.Since(start)), int64(100*time.Millisecond))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), "id: 1\ndata: tick\n\n")
+}
+
+func TestStreamingContext(t *testing.T) {
+	srv := Server{
+		config: &Config{
+			HandlerTimeout: 10 * time.Millisecond,
+			Features:       map[string]bool{"beta": true},
+		},
+	}
+	assert.Nil(t, srv.Container().Supply("postgres://orders"))
+	srv.AddRoutes(util.Route{
+		Name:   "events",
+		Path:   "/events",
+		Method: http.MethodGet,
+		Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			return util.StreamResponse(http.StatusOK, func(ctx context.Context, w io.Writer) error {
+				// outlives the handler timeout
+				time.Sleep(30 * time.Millisecond)
+
+				var dsn string
+				err := di.ScopeFrom(ctx).Resolve(&dsn)
+				_, werr := fmt.Fprintf(w, "beta=%t dsn=%s err=%v done=%v", util.FeatureEnabled(ctx, "beta"), dsn, err, ctx.Err())
+				return werr
+			}), nil
+		},
+	})
+	router := srv.mountRoutes()
+
+	req, err := http.NewRequest(http.MethodGet, "/events", nil)
+	if err != nil {
+		assert.Nil(t, err)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "beta=true dsn=postgres://orders err=<nil> done=<nil>", rr.Body.String())
+}
+
+func TestContentNegotiation(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name"`
+	}
+
+	const (
+		endpoint string = "/user"
+	)
+
+	srv := Server{
+		config: &Config{},
+	}
+	srv.AddRoutes(util.Route{
+		Name:   "user",
+		Path:   endpoint,
+		Method: http.MethodGet,
+		Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			if req.URL.Query().Get("fail") != "" {
+				return nil, godierr.InvalidArgsError("fail")
+			}
+
+			return &util.Response{
+				StatusCode: http.StatusOK,
+				Value:      user{Name: "godi"},
+			}, nil
+		},
+	})
+	router := srv.mountRoutes()
+
+	cases := []struct {
+		name        string
+		path        string
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"json by default", endpoint, "", http.StatusOK, "application/json", `{"name":"godi"}`},
+		{"xml", endpoint, "application/xml", http.StatusOK, "application/xml", `<user><name>godi</name></user>`},
+		{"not acceptable", endpoint, "image/png", http.StatusNotAcceptable, "application/json", `"type":"NOT_ACCEPTABLE"`},
+		{"xml error", endpoint + "?fail=1", "application/xml", http.StatusBadRequest, "application/xml", `<type>INVALID_ARGUMENT</type>`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, c.path, nil)
+			if err != nil {
+				assert.Nil(t, err)
+			}
+			req.Header.Set("Accept", c.accept)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, c.status, rr.Code)
+			assert.Equal(t, c.contentType, rr.Header().Get("Content-Type"))
+			assert.Contains(t, rr.Body.String(), c.body)
+		})
+	}
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	testHandler := func(ctx context.Context, req *util.Request) (*util.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		return &util.Response{
+			StatusCode: http.StatusOK,
+			Body:       string(body),
+		}, nil
+	}
+
+	srv := Server{
+		config: &Config{
+			MaxBodyBytes: 16,
+		},
+	}
+	srv.AddRoutes(
+		util.Route{
+			Name:    "small",
+			Path:    "/small",
+			Method:  http.MethodPost,
+			Handler: testHandler,
+		},
+		util.Route{
+			Name:         "large",
+			Path:         "/large",
+			Method:       http.MethodPost,
+			Handler:      testHandler,
+			MaxBodyBytes: 1024,
+		},
+	)
+	router := srv.mountRoutes()
+
+	cases := []struct {
+		path   string
+		status int
+	}{
+		{"/small", http.StatusRequestEntityTooLarge},
+		{"/large", http.StatusOK},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			// chunked, so the limit is hit while the handler reads the body
+			body := bytes.Repeat([]byte("a"), 64)
+			req, err := http.NewRequest(http.MethodPost, c.path, ioutil.NopCloser(bytes.NewReader(body)))
+			if err != nil {
+				assert.Nil(t, err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, c.status, rr.Code)
+			if c.status != http.StatusOK {
+				assert.Contains(t, rr.Body.String(), godierr.PayloadTooLargeType)
+			}
+		})
+	}
+}
+
+func TestIdempotentRoutes(t *testing.T) {
+	calls := 0
+	srv := Server{
+		config: &Config{
+			Idempotency: true,
+		},
+	}
+	srv.AddRoutes(util.Route{
+		Name:   "create order",
+		Path:   "/orders",
+		Method: http.MethodPost,
+		Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			calls++
+			return &util.Response{
+				StatusCode: http.StatusCreated,
+				Body:       fmt.Sprintf("order %d", calls),
+			}, nil
+		},
+	})
+	router := srv.mountRoutes()
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodPost, "/orders", bytes.NewBufferString(`{"item":"book"}`))
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		req.Header.Set("Idempotency-Key", "order-1")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "order 1", rr.Body.String())
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestRecordedRoutes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.jsonl")
+	srv := Server{
+		config: &Config{
+			RecordFile:         path,
+			RecordRedactFields: []string{"card"},
+		},
+	}
+	srv.AddRoutes(util.Route{
+		Name:   "create order",
+		Path:   "/orders",
+		Method: http.MethodPost,
+		Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			return &util.Response{
+				StatusCode: http.StatusCreated,
+				Value:      map[string]string{"item": "book"},
+			}, nil
+		},
+	})
+	assert.Nil(t, srv.openRecording())
+	router := srv.mountRoutes()
+
+	req, err := http.NewRequest(http.MethodPost, "/orders", bytes.NewBufferString(`{"item":"book","card":"4242"}`))
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	srv.closeRecording()
+
+	f, err := os.Open(path)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	e, err := traffic.NewReader(f).Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "create order", e.Route)
+	assert.Equal(t, `{"card":"[REDACTED]","item":"book"}`, string(e.Request.Body.Data))
+	assert.Equal(t, http.StatusCreated, e.Response.Status)
+	assert.JSONEq(t, `{"item":"book"}`, string(e.Response.Body.Data))
+}
+
+func TestResponseCache(t *testing.T) {
+	calls := map[string]int{}
+	handler := func(ctx context.Context, req *util.Request) (*util.Response, error) {
+		calls[req.URL.Path]++
+		return &util.Response{
+			StatusCode: http.StatusOK,
+			Body:       fmt.Sprintf(`{"id":"%s"}`, req.PathParameters["id"]),
+		}, nil
+	}
+
+	srv := Server{
+		config: &Config{
+			Cache:    true,
+			CacheTTL: time.Minute,
+		},
+	}
+	srv.AddRoutes(
+		util.Route{
+			Name:    "user",
+			Path:    "/users/{id}",
+			Method:  http.MethodGet,
+			Handler: handler,
+		},
+		util.Route{
+			Name:    "feed",
+			Path:    "/feed/{id}",
+			Method:  http.MethodGet,
+			Handler: handler,
+			Cache:   &util.CachePolicy{TTL: -1},
+		},
+	)
+	router := srv.mountRoutes()
+
+	send := func(path, etag string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := send("/users/1", "")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.NotEmpty(t, first.Header().Get("ETag"))
+
+	rr := send("/users/1", first.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Equal(t, 1, calls["/users/1"])
+
+	assert.Nil(t, srv.InvalidateCache(context.Background(), "/users/1"))
+	send("/users/1", "")
+	assert.Equal(t, 2, calls["/users/1"])
+
+	send("/feed/1", "")
+	rr = send("/feed/1", "")
+	assert.Equal(t, 2, calls["/feed/1"])
+	assert.Empty(t, rr.Header().Get("ETag"))
+}
+
+func TestResponseCacheCORS(t *testing.T) {
+	srv := Server{
+		config: &Config{
+			CORSOrigins: []string{"*"},
+			Cache:       true,
+			CacheTTL:    time.Minute,
+		},
+	}
+	srv.AddRoutes(util.Route{
+		Name:   "user",
+		Path:   "/users/{id}",
+		Method: http.MethodGet,
+		Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			return &util.Response{StatusCode: http.StatusOK, Body: `{"id":"1"}`}, nil
+		},
+	})
+	handler := srv.handler()
+
+	for _, c := range []struct {
+		origin string
+		status string
+	}{
+		{"https://a.example", "MISS"},
+		{"https://b.example", "HIT"},
+	} {
+		t.Run(c.origin, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/users/1", nil)
+			if err != nil {
+				assert.Nil(t, err)
+			}
+			req.Header.Set("Origin", c.origin)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, c.status, rr.Header().Get("X-Cache"))
+			assert.Equal(t, c.origin, rr.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, []string{"Origin", "Accept"}, rr.Header().Values("Vary"))
+		})
+	}
+}
+
+type ordersRepo struct {
+	dsn    string
+	closed bool
+}
+
+func (r *ordersRepo) Close() error {
+	r.closed = true
+	return nil
+}
+
+type ordersHandler struct {
+	Repo *ordersRepo `inject:""`
+}
+
+func (h *ordersHandler) List(ctx context.Context, req *util.Request) (*util.Response, error) {
+	return &util.Response{
+		StatusCode: http.StatusOK,
+		Body:       h.Repo.dsn,
+	}, nil
+}
+
+func TestDependencyInjection(t *testing.T) {
+	var repos []*ordersRepo
+	srv := Server{
+		config: &Config{},
+	}
+	assert.Nil(t, srv.Container().Supply("postgres://orders"))
+	assert.Nil(t, srv.Container().Provide(di.PerRequest, func(dsn string) *ordersRepo {
+		repo := &ordersRepo{dsn: dsn}
+		repos = append(repos, repo)
+		return repo
+	}))
+	srv.AddRoutes(util.Route{
+		Name:    "orders",
+		Path:    "/orders",
+		Method:  http.MethodGet,
+		Handler: srv.Container().Handler(func(h *ordersHandler) util.APIHandlerFunc { return h.List }),
+	})
+	router := srv.mountRoutes()
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodGet, "/orders", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "postgres://orders", rr.Body.String())
+	}
+
+	assert.Len(t, repos, 2)
+	assert.True(t, repos[0].closed)
+	assert.True(t, repos[1].closed)
+}
+
+type slowRepo struct {
+	proceed chan struct{}
+	closed  chan struct{}
+}
+
+func (r *slowRepo) Close() error {
+	close(r.closed)
+	return nil
+}
+
+type slowHandler struct {
+	Repo *slowRepo `inject:""`
+}
+
+func TestDependencyInjectionTimeout(t *testing.T) {
+	repo := &slowRepo{proceed: make(chan struct{}), closed: make(chan struct{})}
+	usedAfterClose := make(chan bool, 1)
+
+	srv := Server{
+		config: &Config{
+			HandlerTimeout: 10 * time.Millisecond,
+		},
+	}
+	assert.Nil(t, srv.Container().Provide(di.PerRequest, func() *slowRepo {
+		return repo
+	}))
+	srv.AddRoutes(util.Route{
+		Name:   "slow",
+		Path:   "/slow",
+		Method: http.MethodGet,
+		Handler: srv.Container().Handler(func(h *slowHandler) util.APIHandlerFunc {
+			return func(ctx context.Context, req *util.Request) (*util.Response, error) {
+				<-h.Repo.proceed
+				select {
+				case <-h.Repo.closed:
+					usedAfterClose <- true
+				default:
+					usedAfterClose <- false
+				}
+				return &util.Response{StatusCode: http.StatusOK}, nil
+			}
+		}),
+	})
+	router := srv.mountRoutes()
+
+	req, err := http.NewRequest(http.MethodGet, "/slow", nil)
+	if err != nil {
+		assert.Nil(t, err)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+
+	close(repo.proceed)
+	assert.False(t, <-usedAfterClose)
+
+	select {
+	case <-repo.closed:
+	case <-time.After(time.Second):
+		assert.Fail(t, "request dependencies not disposed once the handler returned")
+	}
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	const (
+		endpoint string = "/test"
+	)
+
+	t.Run("handler exceeding deadline", func(t *testing.T) {
+		testHandler := func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			time.Sleep(100 * time.Millisecond)
+			return &util.Response{
+				StatusCode: http.StatusOK,
+				Body:       "too late",
+			}, nil
+		}
+
+		srv := Server{
+			config: &Config{
+				HandlerTimeout: 10 * time.Millisecond,
+			},
+		}
+		srv.AddRoutes(util.Route{
+			Name:    "test",
+			Path:    endpoint,
+			Method:  http.MethodGet,
+			Handler: testHandler,
+		})
+		router := srv.mountRoutes()
+
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+		assert.JSONEq(t, `{"code":504,"type":"TIMEOUT","message":"request timed out"}`, withoutErrorID(t, rr.Body.Bytes()))
+	})
+
+	t.Run("handler returning context error", func(t *testing.T) {
+		testHandler := func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+
+		srv := Server{
+			config: &Config{
+				HandlerTimeout: 10 * time.Millisecond,
+			},
+		}
+		srv.AddRoutes(util.Route{
+			Name:    "test",
+			Path:    endpoint,
+			Method:  http.MethodGet,
+			Handler: testHandler,
+		})
+		router := srv.mountRoutes()
+
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+	})
+
+	t.Run("handler within deadline", func(t *testing.T) {
+		testHandler := func(ctx context.Context, req *util.Request) (*util.Response, error) {
+			_, ok := ctx.Deadline()
+			assert.True(t, ok)
+
+			return &util.Response{
+				StatusCode: http.StatusOK,
+				Body:       "ok",
+			}, nil
+		}
+
+		srv := Server{
+			config: &Config{
+				HandlerTimeout: time.Second,
+			},
+		}
+		srv.AddRoutes(util.Route{
+			Name:    "test",
+			Path:    endpoint,
+			Method:  http.MethodGet,
+			Handler: testHandler,
+		})
+		router := srv.mountRoutes()
+
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "ok", rr.Body.String())
+	})
+
+	t.Run("cancelled request", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		ctx, cancelTimeout := context.WithTimeout(ctx, time.Second)
+		defer cancelTimeout()
+
+		err := contextError(ctx)
+		assert.Contains(t, err.Error(), "service unavailable")
+	})
+}
+
 func TestServerRequiredArgs(t *testing.T) {
 	srv := Server{
 		config: &Config{},
@@ -252,4 +1057,43 @@ func TestServerRequiredArgs(t *testing.T) {
 	err = srv.Listen()
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "missing required argument(s): port")
+
+	srv = Server{
+		config: &Config{
+			Port:        "3001",
+			Timeout:     30,
+			SPAFallback: true,
+		},
+	}
+
+	err = srv.Listen()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "missing required argument(s): static directory")
+
+	srv = Server{
+		config: &Config{
+			Port:          "3001",
+			Timeout:       30,
+			DebugNetworks: []string{"localhost"},
+		},
+	}
+
+	err = srv.Listen()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `invalid argument(s) passed in: debug networks "localhost"`)
+}
+
+// withoutErrorID returns the error response without its ID,
+// checking server errors have one
+func withoutErrorID(t *testing.T, body []byte) string {
+	var res map[string]interface{}
+	assert.Nil(t, json.Unmarshal(body, &res))
+
+	if code, _ := res["code"].(float64); code >= http.StatusInternalServerError {
+		assert.NotEmpty(t, res["id"])
+	}
+	delete(res, "id")
+
+	b, _ := json.Marshal(res)
+	return string(b)
 }
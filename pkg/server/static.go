@@ -0,0 +1,401 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+const (
+	defaultStaticPrefix string = "/static"
+
+	// number of hex characters of the content
+	// digest used to fingerprint asset URLs
+	fingerprintLength int = 10
+
+	immutableCacheControl string = "public, max-age=31536000, immutable"
+	defaultCacheControl   string = "no-cache"
+)
+
+// DefaultStaticCacheControl is the Cache-Control policy of static files
+// per extension. Files with other extensions are revalidated on every
+// request using their ETag. Fingerprinted asset URLs are always cached
+// for a year as their content never changes
+var DefaultStaticCacheControl = map[string]string{
+	".html":  "no-cache",
+	".css":   "public, max-age=86400",
+	".js":    "public, max-age=86400",
+	".json":  "no-cache",
+	".png":   "public, max-age=604800",
+	".jpg":   "public, max-age=604800",
+	".jpeg":  "public, max-age=604800",
+	".gif":   "public, max-age=604800",
+	".svg":   "public, max-age=604800",
+	".ico":   "public, max-age=604800",
+	".webp":  "public, max-age=604800",
+	".woff":  "public, max-age=2592000",
+	".woff2": "public, max-age=2592000",
+	".ttf":   "public, max-age=2592000",
+}
+
+// precompressed variants of static files looked up
+// next to the original, in order of preference
+var precompressed = []struct {
+	encoding  string
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// staticHandler serves files from a directory or an fs.FS.
+// The directory can be swapped while the server is running
+type staticHandler struct {
+	prefix       string
+	listing      bool
+	cacheControl map[string]string
+
+	files atomic.Value
+}
+
+// staticFiles is the file system being served along with
+// the digests of its files, so swapping it resets the digests
+type staticFiles struct {
+	fsys    fs.FS
+	digests sync.Map
+}
+
+type fileDigest struct {
+	modTime time.Time
+	size    int64
+	digest  string
+}
+
+func newStaticHandler(cfg *Config) *staticHandler {
+	prefix := "/" + strings.Trim(cfg.StaticPrefix, "/")
+	if prefix == "/" {
+		prefix = defaultStaticPrefix
+	}
+
+	cacheControl := make(map[string]string, len(DefaultStaticCacheControl)+len(cfg.StaticCacheControl))
+	for ext, policy := range DefaultStaticCacheControl {
+		cacheControl[ext] = policy
+	}
+	for ext, policy := range cfg.StaticCacheControl {
+		cacheControl[strings.ToLower(ext)] = policy
+	}
+
+	return &staticHandler{
+		prefix:       prefix,
+		listing:      cfg.StaticListing,
+		cacheControl: cacheControl,
+	}
+}
+
+func (h *staticHandler) setDir(dir string) {
+	h.setFS(os.DirFS(dir))
+}
+
+func (h *staticHandler) setFS(fsys fs.FS) {
+	h.files.Store(&staticFiles{fsys: fsys})
+}
+
+// assetURL returns the fingerprinted URL of the file. Falls back
+// to the plain URL when the file cannot be read
+func (h *staticHandler) assetURL(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	files := h.files.Load().(*staticFiles)
+
+	info, err := fs.Stat(files.fsys, name)
+	if err != nil || info.IsDir() {
+		return h.prefix + "/" + name
+	}
+
+	digest, err := files.digest(name, info)
+	if err != nil {
+		return h.prefix + "/" + name
+	}
+
+	ext := path.Ext(name)
+	return h.prefix + "/" + strings.TrimSuffix(name, ext) + "." + digest[:fingerprintLength] + ext
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// the prefix is stripped, anything else means
+	// the path only starts with the prefix e.g. /staticfoo
+	if r.URL.Path != "" && !strings.HasPrefix(r.URL.Path, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	files := h.files.Load().(*staticFiles)
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+
+	immutable := false
+	info, err := fs.Stat(files.fsys, name)
+	if err != nil {
+		original, ok := files.unfingerprint(name)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		name, immutable = original.name, original.current
+		info = original.info
+	}
+
+	if info.IsDir() {
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			target := h.prefix + "/"
+			if name != "." {
+				target += name + "/"
+			}
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+
+		index := path.Join(name, "index.html")
+		if indexInfo, err := fs.Stat(files.fsys, index); err == nil && !indexInfo.IsDir() {
+			h.serveFile(w, r, files, index, indexInfo, false)
+			return
+		}
+
+		if h.listing {
+			http.FileServer(http.FS(files.fsys)).ServeHTTP(w, r)
+			return
+		}
+
+		http.NotFound(w, r)
+		return
+	}
+
+	h.serveFile(w, r, files, name, info, immutable)
+}
+
+// serveFile serves the file, or a precompressed variant of it the
+// client accepts, with a strong ETag and the Cache-Control policy
+// of its extension. Conditional and range requests are handled
+// by http.ServeContent
+func (h *staticHandler) serveFile(w http.ResponseWriter, r *http.Request, files *staticFiles, name string, info fs.FileInfo, immutable bool) {
+	header := w.Header()
+	ext := strings.ToLower(path.Ext(name))
+	contentType := mime.TypeByExtension(ext)
+
+	served, servedInfo, encoding := name, info, ""
+	for _, variant := range precompressed {
+		variantInfo, err := fs.Stat(files.fsys, name+variant.extension)
+		if err != nil || variantInfo.IsDir() {
+			continue
+		}
+
+		// the variant exists, so the response depends on Accept-Encoding
+		header.Add("Vary", "Accept-Encoding")
+
+		// the content type of unknown extensions is sniffed
+		// which does not work on compressed content
+		if encoding == "" && contentType != "" && acceptsEncoding(r.Header.Get("Accept-Encoding"), variant.encoding) {
+			served, servedInfo, encoding = name+variant.extension, variantInfo, variant.encoding
+		}
+	}
+
+	digest, err := files.digest(served, servedInfo)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	f, err := files.fsys.Open(served)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := ioutil.ReadAll(f)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(b)
+	}
+
+	cacheControl, ok := h.cacheControl[ext]
+	if !ok {
+		cacheControl = defaultCacheControl
+	}
+	if immutable {
+		cacheControl = immutableCacheControl
+	}
+
+	header.Set("ETag", `"`+digest+`"`)
+	header.Set("Cache-Control", cacheControl)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+	}
+
+	http.ServeContent(w, r, name, servedInfo.ModTime(), content)
+}
+
+type fingerprinted struct {
+	name    string
+	info    fs.FileInfo
+	current bool
+}
+
+// unfingerprint resolves a fingerprinted name e.g. css/main.0123456789.css
+// to the original file. current reports whether the fingerprint matches
+// the content of the file. Stale fingerprints are still served, but not
+// cached as immutable, so clients holding an old URL during a deploy
+// get the latest content
+func (f *staticFiles) unfingerprint(name string) (fingerprinted, bool) {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	fingerprint := strings.TrimPrefix(path.Ext(stem), ".")
+	if len(fingerprint) != fingerprintLength {
+		return fingerprinted{}, false
+	}
+	if _, err := hex.DecodeString(fingerprint); err != nil {
+		return fingerprinted{}, false
+	}
+
+	original := strings.TrimSuffix(stem, "."+fingerprint) + ext
+	info, err := fs.Stat(f.fsys, original)
+	if err != nil || info.IsDir() {
+		return fingerprinted{}, false
+	}
+
+	digest, err := f.digest(original, info)
+	if err != nil {
+		return fingerprinted{}, false
+	}
+
+	return fingerprinted{
+		name:    original,
+		info:    info,
+		current: digest[:fingerprintLength] == fingerprint,
+	}, true
+}
+
+// digest returns the hex encoded SHA-256 of the content of the file.
+// Digests are cached until the modification time or size of the file change
+func (f *staticFiles) digest(name string, info fs.FileInfo) (string, error) {
+	if cached, ok := f.digests.Load(name); ok {
+		d := cached.(fileDigest)
+		if d.modTime.Equal(info.ModTime()) && d.size == info.Size() {
+			return d.digest, nil
+		}
+	}
+
+	file, err := f.fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	d := fileDigest{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		digest:  hex.EncodeToString(hash.Sum(nil)),
+	}
+	f.digests.Store(name, d)
+
+	return d.digest, nil
+}
+
+// acceptsEncoding reports whether the Accept-Encoding
+// header allows the content coding
+func acceptsEncoding(acceptEncoding, coding string) bool {
+	accepted := false
+	for _, qv := range util.ParseQualityList(acceptEncoding) {
+		switch qv.Value {
+		case coding:
+			// an explicit entry takes precedence over *
+			return qv.Q > 0
+		case "*":
+			accepted = qv.Q > 0
+		}
+	}
+	return accepted
+}
+
+// spaFallback serves the index.html of the static files for
+// GET requests that accept HTML and do not match a route, so
+// client-side routes of single-page applications can be loaded
+// directly. Requests under any of the excluded prefixes and
+// anything else get a 404
+func (s *Server) spaFallback(exclude []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead || !acceptsHTML(r.Header.Get("Accept")) {
+			http.NotFound(w, r)
+			return
+		}
+
+		for _, prefix := range exclude {
+			if pathHasPrefix(r.URL.Path, prefix) {
+				http.NotFound(w, r)
+				return
+			}
+		}
+
+		index := r.Clone(r.Context())
+		index.URL.Path = "/"
+		index.URL.RawPath = ""
+		s.static.ServeHTTP(w, index)
+	})
+}
+
+// acceptsHTML reports whether the Accept header explicitly accepts HTML.
+// Wildcards are ignored as API clients usually send */*
+func acceptsHTML(accept string) bool {
+	for _, qv := range util.ParseQualityList(accept) {
+		if (qv.Value == "text/html" || qv.Value == "application/xhtml+xml") && qv.Q > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// pathHasPrefix reports whether the path is the prefix
+// or is under it e.g. /api and /api/users for /api
+func pathHasPrefix(p, prefix string) bool {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		return true
+	}
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
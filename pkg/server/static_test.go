@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+	"github.com/riyadhalnur/godi/v2/static"
+)
+
+func TestStaticFiles(t *testing.T) {
+	files := fstest.MapFS{
+		"index.html":      {Data: []byte("<h1>home</h1>")},
+		"css/main.css":    {Data: []byte("body { margin: 0; }")},
+		"js/app.js":       {Data: []byte("console.log('plain')")},
+		"js/app.js.br":    {Data: []byte("brotli")},
+		"js/app.js.gz":    {Data: []byte("gzipped")},
+		"docs/readme.txt": {Data: []byte("readme")},
+		"data.bin":        {Data: []byte{0, 1, 2}},
+	}
+
+	newServer := func(cfg *Config) (*Server, http.Handler) {
+		cfg.StaticFS = files
+		srv := &Server{config: cfg}
+		return srv, srv.mountRoutes()
+	}
+
+	serve := func(handler http.Handler, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	_, router := newServer(&Config{})
+
+	t.Run("serves files with validators and cache policy", func(t *testing.T) {
+		rr := serve(router, http.MethodGet, "/static/css/main.css", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "body { margin: 0; }", rr.Body.String())
+		assert.Contains(t, rr.Header().Get("Content-Type"), "text/css")
+		assert.Equal(t, "public, max-age=86400", rr.Header().Get("Cache-Control"))
+		assert.Regexp(t, `^"[0-9a-f]{64}"$`, rr.Header().Get("ETag"))
+
+		rr = serve(router, http.MethodGet, "/static/css/main.css", map[string]string{
+			"If-None-Match": rr.Header().Get("ETag"),
+		})
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+	})
+
+	t.Run("unknown extensions are revalidated", func(t *testing.T) {
+		rr := serve(router, http.MethodGet, "/static/data.bin", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("serves index of directories", func(t *testing.T) {
+		rr := serve(router, http.MethodGet, "/static/", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "<h1>home</h1>", rr.Body.String())
+	})
+
+	t.Run("redirects directories to trailing slash", func(t *testing.T) {
+		rr := serve(router, http.MethodGet, "/static/docs?page=1", nil)
+
+		assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+		assert.Equal(t, "/static/docs/?page=1", rr.Header().Get("Location"))
+	})
+
+	t.Run("does not list directories by default", func(t *testing.T) {
+		rr := serve(router, http.MethodGet, "/static/docs/", nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("lists directories when enabled", func(t *testing.T) {
+		_, router := newServer(&Config{StaticListing: true})
+		rr := serve(router, http.MethodGet, "/static/docs/", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "readme.txt")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		rr := serve(router, http.MethodGet, "/static/missing.css", nil)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		rr = serve(router, http.MethodGet, "/staticfoo", nil)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rr := serve(router, http.MethodPost, "/static/css/main.css", nil)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+		assert.Equal(t, "GET, HEAD", rr.Header().Get("Allow"))
+	})
+
+	t.Run("serves precompressed variants", func(t *testing.T) {
+		cases := []struct {
+			acceptEncoding string
+			encoding       string
+			body           string
+		}{
+			{"gzip, br", "br", "brotli"},
+			{"gzip", "gzip", "gzipped"},
+			{"br;q=0, gzip", "gzip", "gzipped"},
+			{"", "", "console.log('plain')"},
+		}
+
+		for _, c := range cases {
+			rr := serve(router, http.MethodGet, "/static/js/app.js", map[string]string{
+				"Accept-Encoding": c.acceptEncoding,
+			})
+
+			assert.Equal(t, http.StatusOK, rr.Code, c.acceptEncoding)
+			assert.Equal(t, c.encoding, rr.Header().Get("Content-Encoding"), c.acceptEncoding)
+			assert.Equal(t, c.body, rr.Body.String(), c.acceptEncoding)
+			assert.Contains(t, rr.Header().Get("Content-Type"), "javascript", c.acceptEncoding)
+			assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"), c.acceptEncoding)
+		}
+	})
+
+	t.Run("serves fingerprinted assets", func(t *testing.T) {
+		srv, router := newServer(&Config{})
+
+		url := srv.AssetURL("css/main.css")
+		assert.Regexp(t, `^/static/css/main\.[0-9a-f]{10}\.css$`, url)
+
+		rr := serve(router, http.MethodGet, url, nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "body { margin: 0; }", rr.Body.String())
+		assert.Equal(t, "public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+
+		// stale fingerprints get the latest content without being cached
+		rr = serve(router, http.MethodGet, "/static/css/main.0123456789.css", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "public, max-age=86400", rr.Header().Get("Cache-Control"))
+
+		assert.Equal(t, "/static/missing.css", srv.AssetURL("missing.css"))
+	})
+
+	t.Run("configurable prefix and cache policy", func(t *testing.T) {
+		srv, router := newServer(&Config{
+			StaticPrefix: "/assets/",
+			StaticCacheControl: map[string]string{
+				".CSS": "public, max-age=60",
+			},
+		})
+
+		rr := serve(router, http.MethodGet, "/assets/css/main.css", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "public, max-age=60", rr.Header().Get("Cache-Control"))
+		assert.True(t, strings.HasPrefix(srv.AssetURL("css/main.css"), "/assets/css/main."))
+
+		rr = serve(router, http.MethodGet, "/static/css/main.css", nil)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("serves embedded files", func(t *testing.T) {
+		srv := &Server{config: &Config{StaticFS: static.Files}}
+		rr := serve(srv.mountRoutes(), http.MethodGet, "/static/", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "<html")
+	})
+}
+
+func TestSPAFallback(t *testing.T) {
+	testHandler := func(ctx context.Context, req *util.Request) (*util.Response, error) {
+		return &util.Response{
+			StatusCode: http.StatusOK,
+			Body:       "api",
+		}, nil
+	}
+
+	srv := Server{
+		config: &Config{
+			StaticFS: fstest.MapFS{
+				"index.html":   {Data: []byte("<div id=\"root\"></div>")},
+				"css/main.css": {Data: []byte("body {}")},
+			},
+			SPAFallback: true,
+			SPAExclude:  []string{"/api"},
+		},
+	}
+	srv.AddRoutes(util.Route{
+		Name:    "users",
+		Path:    "/api/users",
+		Method:  http.MethodGet,
+		Handler: testHandler,
+	})
+	router := srv.mountRoutes()
+
+	const html = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		accept string
+		status int
+		body   string
+	}{
+		{"client-side route", http.MethodGet, "/dashboard/settings", html, http.StatusOK, "<div id=\"root\"></div>"},
+		{"root", http.MethodGet, "/", html, http.StatusOK, "<div id=\"root\"></div>"},
+		{"api route", http.MethodGet, "/api/users", html, http.StatusOK, "api"},
+		{"unknown api route", http.MethodGet, "/api/unknown", html, http.StatusNotFound, ""},
+		{"does not accept html", http.MethodGet, "/dashboard", "application/json", http.StatusNotFound, ""},
+		{"accepts anything", http.MethodGet, "/dashboard", "*/*", http.StatusNotFound, ""},
+		{"html not acceptable", http.MethodGet, "/dashboard", "text/html;q=0", http.StatusNotFound, ""},
+		{"not a GET", http.MethodPost, "/dashboard", html, http.StatusNotFound, ""},
+		{"missing static file", http.MethodGet, "/static/missing.css", html, http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req, err := http.NewRequest(c.method, c.path, nil)
+			if err != nil {
+				assert.Nil(t, err)
+			}
+			req.Header.Set("Accept", c.accept)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, c.status, rr.Code)
+			if c.body != "" {
+				assert.Equal(t, c.body, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("fallback responses are revalidated", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/dashboard", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		req.Header.Set("Accept", html)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+		assert.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+		assert.NotEmpty(t, rr.Header().Get("X-Request-ID"))
+	})
+}
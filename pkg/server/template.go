@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+// loadTemplates parses the templates in TemplateFS or TemplateDir, if set.
+// Templates can use asset to get the fingerprinted URL of static files
+// e.g. {{asset "css/main.css"}} on top of the functions added using AddTemplateFuncs
+func (s *Server) loadTemplates() error {
+	var fsys fs.FS
+	switch {
+	case s.config.TemplateFS != nil:
+		fsys = s.config.TemplateFS
+	case s.config.TemplateDir != "":
+		dir, err := filepath.Abs(s.config.TemplateDir)
+		if err != nil {
+			return err
+		}
+		fsys = os.DirFS(dir)
+	default:
+		return nil
+	}
+
+	funcs := template.FuncMap{
+		"asset": s.AssetURL,
+	}
+	for name, fn := range s.templateFuncs {
+		funcs[name] = fn
+	}
+
+	templates, err := util.NewTemplates(fsys, util.TemplateOptions{
+		DefaultLayout: s.config.TemplateLayout,
+		Funcs:         funcs,
+		Reload:        s.config.TemplateReload,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.templates = templates
+	return nil
+}
+
+// renderView renders the view of the response into its body
+func (s *Server) renderView(res *util.Response) error {
+	if s.templates == nil {
+		return errors.New("templates are not configured")
+	}
+
+	var buf bytes.Buffer
+	if err := s.templates.Render(&buf, res.View); err != nil {
+		return err
+	}
+
+	if res.Headers == nil {
+		res.Headers = map[string]string{}
+	}
+	if _, ok := res.Headers["Content-Type"]; !ok {
+		res.Headers["Content-Type"] = "text/html; charset=utf-8"
+	}
+	res.Body = buf.String()
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+func TestRenderViews(t *testing.T) {
+	srv := Server{
+		config: &Config{
+			StaticFS: fstest.MapFS{
+				"css/main.css": {Data: []byte("body {}")},
+			},
+			TemplateFS: fstest.MapFS{
+				"layouts/main.html": {Data: []byte(`<link href="{{asset "css/main.css"}}">{{template "content" .}}`)},
+				"home.html":         {Data: []byte(`{{define "content"}}<h1>{{.Title | shout}}</h1>{{end}}`)},
+			},
+			TemplateLayout: "main",
+		},
+	}
+	srv.AddTemplateFuncs(template.FuncMap{
+		"shout": func(s string) string {
+			return strings.ToUpper(s) + "!"
+		},
+	})
+	srv.AddRoutes(
+		util.Route{
+			Name:   "home",
+			Path:   "/",
+			Method: http.MethodGet,
+			Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+				return util.ViewResponse(http.StatusOK, "home", map[string]string{"Title": "godi"}), nil
+			},
+		},
+		util.Route{
+			Name:   "missing",
+			Path:   "/missing",
+			Method: http.MethodGet,
+			Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+				return util.ViewResponse(http.StatusOK, "missing", nil), nil
+			},
+		},
+	)
+	router := srv.mountRoutes()
+
+	t.Run("renders the view", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Regexp(t, `^<link href="/static/css/main\.[0-9a-f]{10}\.css"><h1>GODI!</h1>$`, rr.Body.String())
+	})
+
+	t.Run("rendering errors", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/missing", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
@@ -0,0 +1,222 @@
+package util
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// JSONEncoder encodes values as JSON
+type JSONEncoder struct{}
+
+// ContentType returns application/json
+func (JSONEncoder) ContentType() string {
+	return "application/json"
+}
+
+// CanEncode reports true for any value
+func (JSONEncoder) CanEncode(v interface{}) bool {
+	return true
+}
+
+// Encode writes the value as JSON
+func (JSONEncoder) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// XMLEncoder encodes values as XML
+type XMLEncoder struct{}
+
+// ContentType returns application/xml
+func (XMLEncoder) ContentType() string {
+	return "application/xml"
+}
+
+// CanEncode reports false for maps which
+// cannot be represented in XML
+func (XMLEncoder) CanEncode(v interface{}) bool {
+	return v != nil && indirectType(v).Kind() != reflect.Map
+}
+
+// Encode writes the value as XML
+func (XMLEncoder) Encode(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// MessagePackEncoder encodes values as MessagePack.
+// Struct fields are named after their json tags
+type MessagePackEncoder struct{}
+
+// ContentType returns application/msgpack
+func (MessagePackEncoder) ContentType() string {
+	return "application/msgpack"
+}
+
+// CanEncode reports true for any value
+func (MessagePackEncoder) CanEncode(v interface{}) bool {
+	return true
+}
+
+// Encode writes the value as MessagePack
+func (MessagePackEncoder) Encode(w io.Writer, v interface{}) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(v)
+}
+
+// CBOREncoder encodes values as CBOR.
+// Struct fields are named after their cbor or json tags
+type CBOREncoder struct{}
+
+// ContentType returns application/cbor
+func (CBOREncoder) ContentType() string {
+	return "application/cbor"
+}
+
+// CanEncode reports true for any value
+func (CBOREncoder) CanEncode(v interface{}) bool {
+	return true
+}
+
+// Encode writes the value as CBOR
+func (CBOREncoder) Encode(w io.Writer, v interface{}) error {
+	return cbor.NewEncoder(w).Encode(v)
+}
+
+// ProtobufEncoder encodes protocol buffer messages
+type ProtobufEncoder struct{}
+
+// ContentType returns application/x-protobuf
+func (ProtobufEncoder) ContentType() string {
+	return "application/x-protobuf"
+}
+
+// CanEncode reports whether the value is a proto.Message
+func (ProtobufEncoder) CanEncode(v interface{}) bool {
+	_, ok := v.(proto.Message)
+	return ok
+}
+
+// Encode writes the message in the protocol buffer wire format
+func (ProtobufEncoder) Encode(w io.Writer, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot encode %T as protobuf", v)
+	}
+
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// CSVEncoder encodes tabular values as CSV. Supports [][]string
+// and slices of structs, where the header is formed from the
+// json tags or the names of the exported fields
+type CSVEncoder struct{}
+
+// ContentType returns text/csv
+func (CSVEncoder) ContentType() string {
+	return "text/csv"
+}
+
+// CanEncode reports whether the value is tabular
+func (CSVEncoder) CanEncode(v interface{}) bool {
+	if _, ok := v.([][]string); ok {
+		return true
+	}
+
+	t := indirectType(v)
+	if t == nil || t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return false
+	}
+
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem.Kind() == reflect.Struct
+}
+
+// Encode writes the value as CSV
+func (e CSVEncoder) Encode(w io.Writer, v interface{}) error {
+	if !e.CanEncode(v) {
+		return fmt.Errorf("cannot encode %T as csv", v)
+	}
+
+	cw := csv.NewWriter(w)
+	if records, ok := v.([][]string); ok {
+		return writeCSV(cw, records)
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	elem := rv.Type().Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+
+	var (
+		header []string
+		fields []int
+	)
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+
+	records := [][]string{header}
+	for i := 0; i < rv.Len(); i++ {
+		item := reflect.Indirect(rv.Index(i))
+
+		record := make([]string, len(fields))
+		if item.IsValid() {
+			for j, field := range fields {
+				record[j] = fmt.Sprint(item.Field(field).Interface())
+			}
+		}
+		records = append(records, record)
+	}
+
+	return writeCSV(cw, records)
+}
+
+func writeCSV(cw *csv.Writer, records [][]string) error {
+	if err := cw.WriteAll(records); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func indirectType(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestEncoders(t *testing.T) {
+	user := testUser{ID: 1, Name: "godi"}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := JSONEncoder{}.Encode(&buf, user)
+
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"id":1,"name":"godi"}`, buf.String())
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := XMLEncoder{}.Encode(&buf, user)
+
+		assert.Nil(t, err)
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<testUser><ID>1</ID><Name>godi</Name></testUser>`, buf.String())
+		assert.False(t, XMLEncoder{}.CanEncode(map[string]string{}))
+	})
+
+	t.Run("msgpack", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := MessagePackEncoder{}.Encode(&buf, user)
+		assert.Nil(t, err)
+
+		decoded := map[string]interface{}{}
+		err = msgpack.Unmarshal(buf.Bytes(), &decoded)
+		assert.Nil(t, err)
+		assert.Equal(t, "godi", decoded["name"])
+	})
+
+	t.Run("cbor", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := CBOREncoder{}.Encode(&buf, user)
+		assert.Nil(t, err)
+
+		decoded := map[string]interface{}{}
+		err = cbor.Unmarshal(buf.Bytes(), &decoded)
+		assert.Nil(t, err)
+		assert.Equal(t, "godi", decoded["name"])
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		assert.False(t, ProtobufEncoder{}.CanEncode(user))
+		assert.True(t, ProtobufEncoder{}.CanEncode(wrapperspb.String("godi")))
+
+		var buf bytes.Buffer
+		err := ProtobufEncoder{}.Encode(&buf, wrapperspb.String("godi"))
+		assert.Nil(t, err)
+
+		decoded := &wrapperspb.StringValue{}
+		err = proto.Unmarshal(buf.Bytes(), decoded)
+		assert.Nil(t, err)
+		assert.Equal(t, "godi", decoded.GetValue())
+
+		err = ProtobufEncoder{}.Encode(&buf, user)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		assert.False(t, CSVEncoder{}.CanEncode(user))
+		assert.False(t, CSVEncoder{}.CanEncode(nil))
+		assert.True(t, CSVEncoder{}.CanEncode([]*testUser{}))
+
+		var buf bytes.Buffer
+		err := CSVEncoder{}.Encode(&buf, []*testUser{&user, {ID: 2, Name: "pillow, soft"}})
+		assert.Nil(t, err)
+		assert.Equal(t, "id,name\n1,godi\n2,\"pillow, soft\"\n", buf.String())
+
+		buf.Reset()
+		err = CSVEncoder{}.Encode(&buf, [][]string{{"a", "b"}, {"1", "2"}})
+		assert.Nil(t, err)
+		assert.Equal(t, "a,b\n1,2\n", buf.String())
+	})
+}
@@ -0,0 +1,221 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+)
+
+// Encoder encodes values for a single media type
+type Encoder interface {
+	// ContentType returns the media type produced e.g. application/json
+	ContentType() string
+	// CanEncode reports whether the value can be encoded
+	CanEncode(v interface{}) bool
+	// Encode writes the encoded value to w
+	Encode(w io.Writer, v interface{}) error
+}
+
+// Encoders is a registry of encoders used
+// for content negotiation. Safe for concurrent use
+type Encoders struct {
+	mu       sync.RWMutex
+	encoders []Encoder
+}
+
+// NewEncoders returns a registry with the passed in encoders.
+// The order of the encoders is the order of preference
+// when a client accepts more than one equally
+func NewEncoders(encoders ...Encoder) *Encoders {
+	e := &Encoders{}
+	e.Register(encoders...)
+	return e
+}
+
+// DefaultEncoders returns a registry with all
+// the built-in encoders, preferring JSON
+func DefaultEncoders() *Encoders {
+	return NewEncoders(
+		JSONEncoder{},
+		XMLEncoder{},
+		MessagePackEncoder{},
+		CBOREncoder{},
+		ProtobufEncoder{},
+		CSVEncoder{},
+	)
+}
+
+// Register adds the encoders to the registry. An encoder replaces
+// any encoder already registered for the same content type
+func (e *Encoders) Register(encoders ...Encoder) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, encoder := range encoders {
+		replaced := false
+		for i, existing := range e.encoders {
+			if existing.ContentType() == encoder.ContentType() {
+				e.encoders[i] = encoder
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			e.encoders = append(e.encoders, encoder)
+		}
+	}
+}
+
+// ContentTypes returns the content types of the registered encoders
+func (e *Encoders) ContentTypes() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	types := make([]string, 0, len(e.encoders))
+	for _, encoder := range e.encoders {
+		types = append(types, encoder.ContentType())
+	}
+	return types
+}
+
+// Negotiate returns the encoder for the media type the client prefers,
+// based on the Accept header, out of the ones able to encode the value
+func (e *Encoders) Negotiate(accept string, v interface{}) (Encoder, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	ranges := parseAccept(accept)
+
+	var (
+		best    Encoder
+		bestQ   float64
+		matched bool
+	)
+	for _, encoder := range e.encoders {
+		if !encoder.CanEncode(v) {
+			continue
+		}
+
+		q := quality(ranges, encoder.ContentType())
+		if q > bestQ {
+			best, bestQ, matched = encoder, q, true
+		}
+	}
+
+	return best, matched
+}
+
+// EncodeValue encodes response.Value into the body of the response
+// using the encoder negotiated from the Accept header. Returns a
+// not acceptable error when none of the encoders match
+func EncodeValue(accept string, encoders *Encoders, response *Response) error {
+	encoder, ok := encoders.Negotiate(accept, response.Value)
+	if !ok {
+		return godierr.NotAcceptableError(encoders.ContentTypes()...)
+	}
+
+	var buf bytes.Buffer
+	if err := encoder.Encode(&buf, response.Value); err != nil {
+		return err
+	}
+
+	if response.Headers == nil {
+		response.Headers = map[string]string{}
+	}
+	response.Headers["Content-Type"] = encoder.ContentType()
+	response.Headers["Vary"] = "Accept"
+	response.Body = buf.String()
+
+	return nil
+}
+
+// RespondError returns an error response encoded in the media type
+// the client prefers. Falls back to JSON when none of the encoders match
+func RespondError(w http.ResponseWriter, accept string, encoders *Encoders, err *ErrorResponse) {
+	encoder, ok := encoders.Negotiate(accept, err)
+	if !ok {
+		ErrorJSON(w, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if encodeErr := encoder.Encode(&buf, err); encodeErr != nil {
+		ErrorJSON(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", encoder.ContentType())
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(err.Code)
+	w.Write(buf.Bytes())
+}
+
+// IsJSON reports whether the content type is JSON
+// e.g. application/json or application/problem+json
+func IsJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept parses the media ranges of an Accept header,
+// most specific first. An empty header accepts anything
+func parseAccept(accept string) []mediaRange {
+	if strings.TrimSpace(accept) == "" {
+		return []mediaRange{{mediaType: "*/*", q: 1}}
+	}
+
+	var ranges []mediaRange
+	for _, qv := range ParseQualityList(accept) {
+		ranges = append(ranges, mediaRange{mediaType: qv.Value, q: qv.Q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return specificity(ranges[i].mediaType) > specificity(ranges[j].mediaType)
+	})
+	return ranges
+}
+
+// quality returns the q value of the most
+// specific range matching the media type
+func quality(ranges []mediaRange, mediaType string) float64 {
+	for _, mr := range ranges {
+		if matchesMediaType(mr.mediaType, mediaType) {
+			return mr.q
+		}
+	}
+	return 0
+}
+
+func matchesMediaType(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*"))
+	}
+	return false
+}
+
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
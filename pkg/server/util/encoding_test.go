@@ -0,0 +1,143 @@
+package util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+)
+
+type testUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestNegotiate(t *testing.T) {
+	encoders := DefaultEncoders()
+	user := testUser{ID: 1, Name: "godi"}
+
+	cases := []struct {
+		name     string
+		accept   string
+		value    interface{}
+		expected string
+	}{
+		{"no accept header", "", user, "application/json"},
+		{"any", "*/*", user, "application/json"},
+		{"exact", "application/xml", user, "application/xml"},
+		{"quality", "application/json;q=0.5, application/cbor", user, "application/cbor"},
+		{"subtype wildcard", "text/*", []testUser{user}, "text/csv"},
+		{"excluded", "application/json;q=0, */*", user, "application/xml"},
+		{"case insensitive", "Application/MsgPack", user, "application/msgpack"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			encoder, ok := encoders.Negotiate(c.accept, c.value)
+			assert.True(t, ok)
+			assert.Equal(t, c.expected, encoder.ContentType())
+		})
+	}
+
+	t.Run("no match", func(t *testing.T) {
+		_, ok := encoders.Negotiate("text/csv", user)
+		assert.False(t, ok)
+
+		_, ok = encoders.Negotiate("image/png", user)
+		assert.False(t, ok)
+	})
+}
+
+func TestRegisterEncoders(t *testing.T) {
+	encoders := NewEncoders(JSONEncoder{})
+	encoders.Register(XMLEncoder{}, JSONEncoder{})
+
+	assert.Equal(t, []string{"application/json", "application/xml"}, encoders.ContentTypes())
+}
+
+func TestEncodeValue(t *testing.T) {
+	t.Run("encodes in negotiated media type", func(t *testing.T) {
+		res := &Response{
+			StatusCode: http.StatusOK,
+			Value:      testUser{ID: 1, Name: "godi"},
+		}
+
+		err := EncodeValue("application/xml", DefaultEncoders(), res)
+		assert.Nil(t, err)
+
+		assert.Equal(t, "application/xml", res.Headers["Content-Type"])
+		assert.Equal(t, "Accept", res.Headers["Vary"])
+		assert.Contains(t, res.Body, "<testUser><ID>1</ID><Name>godi</Name></testUser>")
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		res := &Response{
+			StatusCode: http.StatusOK,
+			Value:      testUser{ID: 1, Name: "godi"},
+		}
+
+		err := EncodeValue("image/png", DefaultEncoders(), res)
+		assert.NotNil(t, err)
+
+		godiErr, ok := err.(*godierr.Error)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusNotAcceptable, godiErr.Code())
+		assert.Equal(t, godierr.NotAcceptableType, godiErr.Type())
+	})
+}
+
+func TestRespondError(t *testing.T) {
+	errResp := &ErrorResponse{
+		Code:    http.StatusBadRequest,
+		Type:    godierr.InvalidArgType,
+		Message: "invalid",
+	}
+
+	t.Run("negotiated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		RespondError(w, "application/xml", DefaultEncoders(), errResp)
+
+		resp := w.Result()
+		body, _ := ioutil.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "application/xml", resp.Header.Get("Content-Type"))
+		assert.Contains(t, string(body), "<error><code>400</code><type>INVALID_ARGUMENT</type><message>invalid</message></error>")
+	})
+
+	t.Run("falls back to JSON", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		RespondError(w, "text/csv", DefaultEncoders(), errResp)
+
+		resp := w.Result()
+		body, _ := ioutil.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.JSONEq(t, `{"code":400,"type":"INVALID_ARGUMENT","message":"invalid"}`, string(body))
+	})
+}
+
+func TestIsJSON(t *testing.T) {
+	cases := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"application/problem+json", true},
+		{"application/xml", false},
+		{"text/plain", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.contentType, func(t *testing.T) {
+			assert.Equal(t, c.expected, IsJSON(c.contentType))
+		})
+	}
+}
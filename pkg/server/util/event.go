@@ -1,6 +1,7 @@
 package util
 
 import (
+	"encoding/xml"
 	"net/http"
 )
 
@@ -8,10 +9,15 @@ import (
 // Code - the error code
 // Type - type of error
 // Message - full description of error
+// ID - identifies the error in the logs. Set for server errors
+// Causes - the chain of errors that caused the error. Only set in debug mode
 type ErrorResponse struct {
-	Code    int    `json:"code,omitempty"`
-	Type    string `json:"type,omitempty"`
-	Message string `json:"message,omitempty"`
+	XMLName xml.Name `json:"-" xml:"error"`
+	Code    int      `json:"code,omitempty" xml:"code,omitempty"`
+	Type    string   `json:"type,omitempty" xml:"type,omitempty"`
+	Message string   `json:"message,omitempty" xml:"message,omitempty"`
+	ID      string   `json:"id,omitempty" xml:"id,omitempty"`
+	Causes  []string `json:"causes,omitempty" xml:"cause,omitempty"`
 }
 
 // Request struct passed in to http handlers
@@ -29,8 +35,14 @@ type Request struct {
 // StatusCode - any valid http status code
 // Headers - Customer headers to be attached to the response headers
 // Body - the body to be returned as JSON string
+// Stream - writes the body incrementally instead of Body, if set
+// Value - encoded into Body in the media type the client accepts, if set
+// View - rendered into Body as HTML using the server templates, if set
 type Response struct {
 	StatusCode int               `json:"statusCode,omitempty"`
 	Headers    map[string]string `json:"headers,omitempty"`
 	Body       string            `json:"body,omitempty"`
+	Stream     StreamFunc        `json:"-"`
+	Value      interface{}       `json:"-"`
+	View       *View             `json:"-"`
 }
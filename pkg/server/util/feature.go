@@ -0,0 +1,15 @@
+package util
+
+import (
+	"context"
+)
+
+// FeatureEnabled reports whether the feature flag is turned on
+// for the request the context belongs to
+func FeatureEnabled(ctx context.Context, name string) bool {
+	features, ok := ctx.Value(FeaturesKey).(map[string]bool)
+	if !ok {
+		return false
+	}
+	return features[name]
+}
@@ -0,0 +1,20 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatureEnabled(t *testing.T) {
+	ctx := context.WithValue(context.Background(), FeaturesKey, map[string]bool{
+		"on":  true,
+		"off": false,
+	})
+
+	assert.True(t, FeatureEnabled(ctx, "on"))
+	assert.False(t, FeatureEnabled(ctx, "off"))
+	assert.False(t, FeatureEnabled(ctx, "missing"))
+	assert.False(t, FeatureEnabled(context.Background(), "on"))
+}
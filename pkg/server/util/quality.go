@@ -0,0 +1,42 @@
+package util
+
+import (
+	"strconv"
+	"strings"
+)
+
+// QualityValue is an entry of a header listing values with
+// their relative quality e.g. Accept or Accept-Encoding
+type QualityValue struct {
+	Value string
+	Q     float64
+}
+
+// ParseQualityList parses the entries of a header listing values
+// with an optional quality e.g. "br, gzip;q=0.8". Values are trimmed
+// and lower-cased. The quality defaults to 1 when not set or invalid.
+// Entries with a quality of 0, which refuse the value, are kept
+func ParseQualityList(header string) []QualityValue {
+	var values []QualityValue
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		qv := QualityValue{
+			Value: strings.ToLower(strings.TrimSpace(params[0])),
+			Q:     1,
+		}
+		if qv.Value == "" {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					qv.Q = q
+				}
+			}
+		}
+		values = append(values, qv)
+	}
+	return values
+}
@@ -0,0 +1,28 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQualityList(t *testing.T) {
+	cases := []struct {
+		name     string
+		header   string
+		expected []QualityValue
+	}{
+		{"empty", "", nil},
+		{"default quality", "gzip", []QualityValue{{"gzip", 1}}},
+		{"qualities", "br;q=1.0, GZIP ; q=0.8,*;q=0", []QualityValue{{"br", 1}, {"gzip", 0.8}, {"*", 0}}},
+		{"other params", "text/html;level=1;q=0.5", []QualityValue{{"text/html", 0.5}}},
+		{"invalid quality", "en;q=high", []QualityValue{{"en", 1}}},
+		{"empty entries", " , en-US ,", []QualityValue{{"en-us", 1}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, ParseQualityList(c.header))
+		})
+	}
+}
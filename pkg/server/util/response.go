@@ -3,22 +3,73 @@ package util
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
 )
 
+// ErrorResponder writes err as the response to r
+type ErrorResponder func(w http.ResponseWriter, r *http.Request, err *godierr.Error)
+
 // RespondJSON returns fully formed JSON responses
 // for http requests
 func RespondJSON(w http.ResponseWriter, response *Response) {
 	w.Header().Set("Content-Type", "application/json")
+	setHeaders(w.Header(), response.Headers)
+
+	w.WriteHeader(response.StatusCode)
+	w.Write([]byte(response.Body))
+}
 
-	if len(response.Headers) != 0 {
-		for k, v := range response.Headers {
-			// set any custom headers passed in
-			w.Header().Set(k, v)
+// setHeaders sets any custom headers passed in. Vary is merged with
+// the values set by the middlewares e.g. Accept-Encoding or Origin
+func setHeaders(header http.Header, headers map[string]string) {
+	for k, v := range headers {
+		if http.CanonicalHeaderKey(k) == "Vary" {
+			AddVary(header, strings.Split(v, ",")...)
+			continue
 		}
+		header.Set(k, v)
 	}
+}
 
-	w.WriteHeader(response.StatusCode)
-	w.Write([]byte(response.Body))
+// AddVary adds the header names to Vary unless they are there already
+func AddVary(header http.Header, names ...string) {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || varies(header, name) {
+			continue
+		}
+		header.Add("Vary", name)
+	}
+}
+
+func varies(header http.Header, name string) bool {
+	for _, value := range header.Values("Vary") {
+		for _, existing := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RespondErr writes err as the response to r with the ErrorResponder of
+// the request context, which the server sets to negotiate the media type
+// and localize the message as for the errors of the handlers.
+// Falls back to a JSON error response
+func RespondErr(w http.ResponseWriter, r *http.Request, err *godierr.Error) {
+	if respond, ok := r.Context().Value(ErrorResponderKey).(ErrorResponder); ok {
+		respond(w, r, err)
+		return
+	}
+
+	ErrorJSON(w, &ErrorResponse{
+		Code:    err.Code(),
+		Type:    err.Type(),
+		Message: err.Message(),
+	})
 }
 
 // ErrorJSON returns a common JSON formed error response
@@ -1,12 +1,15 @@
 package util
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,6 +52,48 @@ func TestRespondJSON(t *testing.T) {
 		assert.Equal(t, csvHeader, resp.Header.Get("Content-Type"))
 		assert.Equal(t, `"id,name"`, string(body))
 	})
+
+	t.Run("merges vary", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		w.Header().Add("Vary", "Accept-Encoding")
+		w.Header().Add("Vary", "Origin")
+
+		RespondJSON(w, &Response{
+			StatusCode: http.StatusOK,
+			Headers: map[string]string{
+				"Vary": "Accept, origin",
+			},
+		})
+
+		assert.Equal(t, []string{"Accept-Encoding", "Origin", "Accept"}, w.Result().Header.Values("Vary"))
+	})
+}
+
+func TestRespondErr(t *testing.T) {
+	t.Run("json by default", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		RespondErr(w, httptest.NewRequest(http.MethodGet, "/", nil), godierr.ConflictError("order"))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"code":409,"type":"CONFLICT","message":"conflict with the current state of resource(s): order"}`, w.Body.String())
+	})
+
+	t.Run("responder of the request", func(t *testing.T) {
+		var respond ErrorResponder = func(w http.ResponseWriter, r *http.Request, err *godierr.Error) {
+			w.WriteHeader(err.Code())
+			w.Write([]byte(err.Type()))
+		}
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(context.WithValue(r.Context(), ErrorResponderKey, respond))
+		w := httptest.NewRecorder()
+
+		RespondErr(w, r, godierr.ConflictError("order"))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, "CONFLICT", w.Body.String())
+	})
 }
 
 func TestErrorJSON(t *testing.T) {
@@ -2,6 +2,7 @@ package util
 
 import (
 	"context"
+	"time"
 )
 
 // APIHandlerFunc is the signature API controllers must implement
@@ -9,10 +10,61 @@ import (
 type APIHandlerFunc func(ctx context.Context, req *Request) (res *Response, err error)
 
 // Route defines the properties of an API route
-// to mount on the server
+// to mount on the server. Routes with WebSocket set
+// are upgraded to WebSocket connections on GET
+// requests instead of calling Handler.
+// MaxBodyBytes overrides the maximum size of
+// the request body set in the server config.
+// Cache configures the caching of the responses
+// of GET routes when caching is enabled.
+// Doc documents the route in the OpenAPI spec
 type Route struct {
-	Name    string
-	Path    string
-	Method  string
-	Handler APIHandlerFunc
+	Name         string
+	Path         string
+	Method       string
+	Handler      APIHandlerFunc
+	WebSocket    *WebSocket
+	MaxBodyBytes int64
+	Cache        *CachePolicy
+	Doc          *RouteDoc
+}
+
+// CachePolicy configures the caching of the responses of a route
+// TTL - how long full responses are stored. Overrides the TTL set in
+// the server config. A negative TTL disables caching and ETags for the route
+// VaryHeaders - request headers the response depends on. Defaults to Accept
+// Shared - store a single response for all the principals instead of
+// one per principal, read from PrincipalKey. Only for responses that do
+// not depend on who makes the request
+type CachePolicy struct {
+	TTL         time.Duration
+	VaryHeaders []string
+	Shared      bool
+}
+
+// RouteDoc documents a route in the OpenAPI spec
+// Summary, Description, Tags - describe the operation
+// Query - the query parameters the route accepts
+// Request - a value of the type of the request body e.g. CreateUser{}
+// Response - a value of the type of the response body
+// Status - status code of successful responses. Defaults to 200
+// Errors - godierr types the route fails with e.g. godierr.NotFoundType
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Query       []QueryParam
+	Request     interface{}
+	Response    interface{}
+	Status      int
+	Errors      []string
+}
+
+// QueryParam documents a query parameter
+// Type - the JSON schema type e.g. integer. Defaults to string
+type QueryParam struct {
+	Name        string
+	Description string
+	Required    bool
+	Type        string
 }
@@ -0,0 +1,160 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is a single Server-Sent Event
+// ID - sent back by clients in the Last-Event-ID header when reconnecting
+// Event - the event type. Defaults to "message" on the client when not set
+// Data - the event payload. Can span multiple lines
+// Retry - reconnection delay for the client, if set
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// SSEOptions configures a Server-Sent Events response
+// Retry - reconnection delay sent to the client when the stream opens
+// Heartbeat - interval between keep-alive comments. Disabled when not set
+type SSEOptions struct {
+	Retry     time.Duration
+	Heartbeat time.Duration
+}
+
+// SSEFunc sends events to the client until it returns
+// or the context is done
+type SSEFunc func(ctx context.Context, stream *EventStream) error
+
+// EventStream sends Server-Sent Events to a client.
+// Safe for concurrent use
+type EventStream struct {
+	// LastEventID is the ID of the last event the client received
+	// before reconnecting. Empty on the first connection
+	LastEventID string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Send writes the event to the client
+func (s *EventStream) Send(e Event) error {
+	var b strings.Builder
+
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", sanitizeField(e.ID))
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", sanitizeField(e.Event))
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry.Milliseconds())
+	}
+	// a bare \r ends a line too, which would start a new field
+	data := strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(e.Data)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// Comment writes a comment line that clients ignore.
+// Useful to keep idle connections open
+func (s *EventStream) Comment(text string) error {
+	return s.write(fmt.Sprintf(": %s\n\n", sanitizeField(text)))
+}
+
+func (s *EventStream) write(str string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := io.WriteString(s.w, str)
+	return err
+}
+
+// SSEResponse returns a response that streams Server-Sent Events
+// produced by fn. Clients reconnecting with a Last-Event-ID header
+// can be resumed from EventStream.LastEventID. The stream ends without
+// an error when the client disconnects or the server's write timeout
+// is near so that clients reconnect and resume
+func SSEResponse(req *Request, opts SSEOptions, fn SSEFunc) *Response {
+	lastEventID := ""
+	if req != nil && req.Request != nil {
+		lastEventID = req.Header.Get("Last-Event-ID")
+	}
+
+	res := StreamResponse(200, func(ctx context.Context, w io.Writer) error {
+		stream := &EventStream{
+			LastEventID: lastEventID,
+			w:           w,
+		}
+
+		if opts.Retry > 0 {
+			if err := stream.write(fmt.Sprintf("retry: %d\n\n", opts.Retry.Milliseconds())); err != nil {
+				return nil
+			}
+		}
+
+		streamCtx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		defer func() {
+			// stop the heartbeat before handing the writer back
+			cancel()
+			wg.Wait()
+		}()
+
+		if opts.Heartbeat > 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				heartbeat(streamCtx, stream, opts.Heartbeat)
+			}()
+		}
+
+		err := fn(streamCtx, stream)
+		if ctx.Err() != nil {
+			// the client went away or the stream is being
+			// closed before the write timeout
+			return nil
+		}
+		return err
+	})
+
+	res.Headers["Content-Type"] = "text/event-stream"
+	res.Headers["Cache-Control"] = "no-cache"
+	res.Headers["Connection"] = "keep-alive"
+	res.Headers["X-Accel-Buffering"] = "no"
+
+	return res
+}
+
+func heartbeat(ctx context.Context, stream *EventStream, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := stream.Comment("heartbeat"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sanitizeField removes line breaks that would
+// otherwise end the field early
+func sanitizeField(str string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(str)
+}
@@ -0,0 +1,113 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSEResponse(t *testing.T) {
+	t.Run("sends events", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		res := SSEResponse(nil, SSEOptions{Retry: 3 * time.Second}, func(ctx context.Context, stream *EventStream) error {
+			stream.Send(Event{
+				ID:    "1",
+				Event: "greeting",
+				Data:  "hello\nworld",
+			})
+			return stream.Send(Event{ID: "2", Data: "bye"})
+		})
+
+		err := RespondStream(context.Background(), w, res)
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+		assert.Equal(t, "retry: 3000\n\n"+
+			"id: 1\nevent: greeting\ndata: hello\ndata: world\n\n"+
+			"id: 2\ndata: bye\n\n", w.Body.String())
+	})
+
+	t.Run("resumes from last event id", func(t *testing.T) {
+		r, err := http.NewRequest(http.MethodGet, "/events", nil)
+		if err != nil {
+			assert.Nil(t, err)
+		}
+		r.Header.Set("Last-Event-ID", "41")
+
+		w := httptest.NewRecorder()
+		res := SSEResponse(&Request{Request: r}, SSEOptions{}, func(ctx context.Context, stream *EventStream) error {
+			assert.Equal(t, "41", stream.LastEventID)
+			return stream.Send(Event{ID: "42", Data: "next"})
+		})
+
+		err = RespondStream(context.Background(), w, res)
+		assert.Nil(t, err)
+		assert.Equal(t, "id: 42\ndata: next\n\n", w.Body.String())
+	})
+
+	t.Run("heartbeat", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		res := SSEResponse(nil, SSEOptions{Heartbeat: 5 * time.Millisecond}, func(ctx context.Context, stream *EventStream) error {
+			time.Sleep(30 * time.Millisecond)
+			return nil
+		})
+
+		err := RespondStream(context.Background(), w, res)
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(w.Body.String(), ": heartbeat\n\n"))
+	})
+
+	t.Run("client disconnect ends stream", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		res := SSEResponse(nil, SSEOptions{}, func(ctx context.Context, stream *EventStream) error {
+			cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		err := RespondStream(ctx, w, res)
+		assert.Nil(t, err)
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		res := SSEResponse(nil, SSEOptions{}, func(ctx context.Context, stream *EventStream) error {
+			return errors.New("no more events")
+		})
+
+		err := RespondStream(context.Background(), w, res)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestEventStreamSanitizesFields(t *testing.T) {
+	w := httptest.NewRecorder()
+	stream := &EventStream{w: w}
+
+	stream.Send(Event{ID: "1\n2", Event: "a\r\nb", Data: "x"})
+	stream.Comment("keep\nalive")
+
+	assert.Equal(t, "id: 12\nevent: ab\ndata: x\n\n: keepalive\n\n", w.Body.String())
+
+	t.Run("carriage returns", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		stream := &EventStream{w: w}
+
+		stream.Send(Event{ID: "1\rretry: 0", Event: "a\rid: x", Data: "a\rid: x\r\nb\nc"})
+
+		assert.Equal(t, "id: 1retry: 0\nevent: aid: x\ndata: a\ndata: id: x\ndata: b\ndata: c\n\n", w.Body.String())
+	})
+}
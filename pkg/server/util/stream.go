@@ -0,0 +1,82 @@
+package util
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// StreamFunc writes the body of a response incrementally.
+// Every write is flushed to the client straight away.
+// The context is done when the client disconnects or
+// the server is about to hit its write timeout
+type StreamFunc func(ctx context.Context, w io.Writer) error
+
+// ReaderResponse returns a response that copies
+// everything read from r to the client.
+// r is closed once done if it implements io.Closer
+func ReaderResponse(statusCode int, r io.Reader) *Response {
+	return &Response{
+		StatusCode: statusCode,
+		Headers:    map[string]string{},
+		Stream: func(ctx context.Context, w io.Writer) error {
+			if closer, ok := r.(io.Closer); ok {
+				defer closer.Close()
+			}
+
+			_, err := io.Copy(w, r)
+			return err
+		},
+	}
+}
+
+// StreamResponse returns a response that calls
+// fn to write the body to the client
+func StreamResponse(statusCode int, fn StreamFunc) *Response {
+	return &Response{
+		StatusCode: statusCode,
+		Headers:    map[string]string{},
+		Stream:     fn,
+	}
+}
+
+// RespondStream writes the headers of the response
+// and then streams the body using response.Stream
+func RespondStream(ctx context.Context, w http.ResponseWriter, response *Response) error {
+	setHeaders(w.Header(), response.Headers)
+
+	w.WriteHeader(response.StatusCode)
+
+	fw := &flushWriter{
+		ctx: ctx,
+		w:   w,
+	}
+	fw.flusher, _ = w.(http.Flusher)
+	fw.flush()
+
+	return response.Stream(ctx, fw)
+}
+
+// flushWriter flushes every write to the client
+// and stops writing once the context is done
+type flushWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	if err := fw.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := fw.w.Write(p)
+	fw.flush()
+	return n, err
+}
+
+func (fw *flushWriter) flush() {
+	if fw.flusher != nil {
+		fw.flusher.Flush()
+	}
+}
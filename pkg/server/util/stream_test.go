@@ -0,0 +1,66 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondStream(t *testing.T) {
+	t.Run("reader response", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		res := ReaderResponse(http.StatusOK, ioutil.NopCloser(strings.NewReader("id,name\n1,godi\n")))
+		res.Headers["Content-Type"] = "text/csv"
+
+		err := RespondStream(context.Background(), w, res)
+		assert.Nil(t, err)
+
+		resp := w.Result()
+		body, _ := ioutil.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "id,name\n1,godi\n", string(body))
+		assert.True(t, w.Flushed)
+	})
+
+	t.Run("write callback", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		err := RespondStream(context.Background(), w, StreamResponse(http.StatusAccepted, func(ctx context.Context, w io.Writer) error {
+			for _, chunk := range []string{"a", "b", "c"} {
+				if _, err := io.WriteString(w, chunk); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "abc", w.Body.String())
+	})
+
+	t.Run("stops writing when context is done", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		err := RespondStream(ctx, w, StreamResponse(http.StatusOK, func(ctx context.Context, w io.Writer) error {
+			io.WriteString(w, "before")
+			cancel()
+			_, err := io.WriteString(w, "after")
+			return err
+		}))
+
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, "before", w.Body.String())
+	})
+}
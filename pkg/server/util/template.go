@@ -0,0 +1,193 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultTemplateExtension string = ".html"
+	defaultLayoutsDir        string = "layouts"
+	defaultPartialsDir       string = "partials"
+)
+
+// View is a page rendered from the templates
+// Name - the page template e.g. users/show for users/show.html
+// Layout - the layout the page is rendered in e.g. main for layouts/main.html.
+// Defaults to the layout set in TemplateOptions. The page is rendered on its own when empty
+// Data - passed in to the templates
+type View struct {
+	Name   string
+	Layout string
+	Data   interface{}
+}
+
+// ViewResponse returns a response rendering the page in the default layout
+func ViewResponse(status int, name string, data interface{}) *Response {
+	return &Response{
+		StatusCode: status,
+		View: &View{
+			Name: name,
+			Data: data,
+		},
+	}
+}
+
+// TemplateOptions configures the loading of templates
+// Extension - extension of template files. Defaults to .html
+// Layouts - directory of the layouts. Defaults to layouts
+// Partials - directory of the partials, available to every page and layout. Defaults to partials
+// DefaultLayout - layout pages are rendered in unless the view sets one
+// Funcs - functions available to the templates
+// Reload - parse the templates on every render so changes show up without a restart
+type TemplateOptions struct {
+	Extension     string
+	Layouts       string
+	Partials      string
+	DefaultLayout string
+	Funcs         template.FuncMap
+	Reload        bool
+}
+
+// Templates renders pages using html/template. Every file outside the
+// layouts and partials directories is a page named after its path without
+// the extension. Layouts render the page using {{template "content" .}}
+// where the page defines {{define "content"}}...{{end}}. Partials are
+// available to every page and layout by their path e.g. {{template "partials/nav" .}}
+type Templates struct {
+	fsys fs.FS
+	opts TemplateOptions
+
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+// NewTemplates parses the templates in the file system
+func NewTemplates(fsys fs.FS, opts TemplateOptions) (*Templates, error) {
+	if opts.Extension == "" {
+		opts.Extension = defaultTemplateExtension
+	}
+	if opts.Layouts == "" {
+		opts.Layouts = defaultLayoutsDir
+	}
+	if opts.Partials == "" {
+		opts.Partials = defaultPartialsDir
+	}
+
+	t := &Templates{
+		fsys: fsys,
+		opts: opts,
+	}
+
+	if err := t.parse(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Render writes the view to w. Nothing is written when rendering fails
+func (t *Templates) Render(w io.Writer, view *View) error {
+	if t.opts.Reload {
+		if err := t.parse(); err != nil {
+			return err
+		}
+	}
+
+	t.mu.RLock()
+	page, ok := t.pages[view.Name]
+	t.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("template %s not found", view.Name)
+	}
+
+	layout := view.Layout
+	if layout == "" {
+		layout = t.opts.DefaultLayout
+	}
+
+	name := view.Name
+	if layout != "" {
+		name = path.Join(t.opts.Layouts, layout)
+		if page.Lookup(name) == nil {
+			return fmt.Errorf("layout %s not found", layout)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := page.ExecuteTemplate(&buf, name, view.Data); err != nil {
+		return err
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// parse parses all the templates. Each page gets its own set
+// including the layouts and partials, so the blocks defined
+// by pages do not clash with each other
+func (t *Templates) parse() error {
+	var shared, pages []string
+	err := fs.WalkDir(t.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != t.opts.Extension {
+			return nil
+		}
+
+		if inDir(p, t.opts.Layouts) || inDir(p, t.opts.Partials) {
+			shared = append(shared, p)
+		} else {
+			pages = append(pages, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	base := template.New("").Funcs(t.opts.Funcs)
+	for _, file := range shared {
+		if err := t.parseFile(base, file); err != nil {
+			return err
+		}
+	}
+
+	parsed := make(map[string]*template.Template, len(pages))
+	for _, file := range pages {
+		page, err := base.Clone()
+		if err != nil {
+			return err
+		}
+		if err := t.parseFile(page, file); err != nil {
+			return err
+		}
+		parsed[strings.TrimSuffix(file, t.opts.Extension)] = page
+	}
+
+	t.mu.Lock()
+	t.pages = parsed
+	t.mu.Unlock()
+
+	return nil
+}
+
+func (t *Templates) parseFile(set *template.Template, file string) error {
+	b, err := fs.ReadFile(t.fsys, file)
+	if err != nil {
+		return err
+	}
+
+	_, err = set.New(strings.TrimSuffix(file, t.opts.Extension)).Parse(string(b))
+	return err
+}
+
+func inDir(p, dir string) bool {
+	return strings.HasPrefix(p, strings.Trim(dir, "/")+"/")
+}
@@ -0,0 +1,125 @@
+package util
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplates(t *testing.T) {
+	files := fstest.MapFS{
+		"layouts/main.html":  {Data: []byte(`<main>{{template "partials/nav" .}}{{template "content" .}}</main>`)},
+		"layouts/bare.html":  {Data: []byte(`<div>{{template "content" .}}</div>`)},
+		"partials/nav.html":  {Data: []byte(`<nav>{{.Title | upper}}</nav>`)},
+		"home.html":          {Data: []byte(`{{define "content"}}<h1>{{.Title}}</h1>{{end}}`)},
+		"users/show.html":    {Data: []byte(`{{define "content"}}<p>{{.Name}}</p>{{end}}`)},
+		"fragment.html":      {Data: []byte(`<span>{{.}}</span>`)},
+		"broken.html":        {Data: []byte(`{{define "content"}}{{.Missing.Field}}{{end}}`)},
+		"layouts/readme.txt": {Data: []byte(`not a template`)},
+	}
+
+	templates, err := NewTemplates(files, TemplateOptions{
+		DefaultLayout: "main",
+		Funcs: template.FuncMap{
+			"upper": strings.ToUpper,
+		},
+	})
+	assert.Nil(t, err)
+
+	render := func(view *View) (string, error) {
+		var buf bytes.Buffer
+		err := templates.Render(&buf, view)
+		return buf.String(), err
+	}
+
+	t.Run("renders pages in the default layout", func(t *testing.T) {
+		out, err := render(&View{Name: "home", Data: map[string]string{"Title": "godi"}})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "<main><nav>GODI</nav><h1>godi</h1></main>", out)
+	})
+
+	t.Run("pages define their own blocks", func(t *testing.T) {
+		out, err := render(&View{Name: "users/show", Data: map[string]string{"Title": "user", "Name": "<b>riyadh</b>"}})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "<main><nav>USER</nav><p>&lt;b&gt;riyadh&lt;/b&gt;</p></main>", out)
+	})
+
+	t.Run("renders pages in another layout", func(t *testing.T) {
+		out, err := render(&View{Name: "users/show", Layout: "bare", Data: map[string]string{"Name": "riyadh"}})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "<div><p>riyadh</p></div>", out)
+	})
+
+	t.Run("renders pages without a layout", func(t *testing.T) {
+		noLayout, err := NewTemplates(files, TemplateOptions{
+			Funcs: template.FuncMap{
+				"upper": strings.ToUpper,
+			},
+		})
+		assert.Nil(t, err)
+
+		var buf bytes.Buffer
+		assert.Nil(t, noLayout.Render(&buf, &View{Name: "fragment", Data: "hi"}))
+		assert.Equal(t, "<span>hi</span>", buf.String())
+	})
+
+	t.Run("missing templates", func(t *testing.T) {
+		_, err := render(&View{Name: "missing"})
+		assert.EqualError(t, err, "template missing not found")
+
+		_, err = render(&View{Name: "home", Layout: "missing"})
+		assert.EqualError(t, err, "layout missing not found")
+	})
+
+	t.Run("nothing is written when rendering fails", func(t *testing.T) {
+		out, err := render(&View{Name: "broken", Data: struct{ Title string }{"godi"}})
+
+		assert.NotNil(t, err)
+		assert.Empty(t, out)
+	})
+
+	t.Run("invalid templates", func(t *testing.T) {
+		_, err := NewTemplates(fstest.MapFS{
+			"home.html": {Data: []byte(`{{if}}`)},
+		}, TemplateOptions{})
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestTemplatesReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	page := filepath.Join(dir, "home.html")
+	assert.Nil(t, ioutil.WriteFile(page, []byte("v1"), 0644))
+
+	for _, reload := range []bool{false, true} {
+		assert.Nil(t, ioutil.WriteFile(page, []byte("v1"), 0644))
+
+		templates, err := NewTemplates(os.DirFS(dir), TemplateOptions{Reload: reload})
+		assert.Nil(t, err)
+
+		assert.Nil(t, ioutil.WriteFile(page, []byte("v2"), 0644))
+
+		var buf bytes.Buffer
+		assert.Nil(t, templates.Render(&buf, &View{Name: "home"}))
+
+		if reload {
+			assert.Equal(t, "v2", buf.String())
+		} else {
+			assert.Equal(t, "v1", buf.String())
+		}
+	}
+}
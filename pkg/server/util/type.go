@@ -11,4 +11,13 @@ const (
 	// RequestIDKey key for unique request ID attached to
 	// all incoming http requests
 	RequestIDKey contextKey = "RequestID"
+	// FeaturesKey key for the feature flags enabled
+	// when the request was received
+	FeaturesKey contextKey = "Features"
+	// PrincipalKey key for the ID of the authenticated principal
+	// making the request e.g. a user ID. Set by authentication middlewares
+	PrincipalKey contextKey = "Principal"
+	// ErrorResponderKey key for the ErrorResponder
+	// the middlewares reject requests with
+	ErrorResponderKey contextKey = "ErrorResponder"
 )
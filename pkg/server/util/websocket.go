@@ -0,0 +1,79 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// MessageType is the type of a WebSocket message
+type MessageType int
+
+const (
+	// TextMessage denotes a UTF-8 encoded text message
+	TextMessage MessageType = 1
+	// BinaryMessage denotes a binary message
+	BinaryMessage MessageType = 2
+)
+
+const (
+	// CloseNormalClosure close code for connections that
+	// were closed after fulfilling their purpose
+	CloseNormalClosure int = 1000
+	// CloseGoingAway close code for connections closed
+	// because the server is shutting down
+	CloseGoingAway int = 1001
+	// ClosePolicyViolation close code for connections closed
+	// because a message violated the endpoint's policy
+	ClosePolicyViolation int = 1008
+	// CloseInternalError close code for connections closed
+	// because the handler returned an error
+	CloseInternalError int = 1011
+)
+
+// ErrConnectionClosed is returned when sending or receiving
+// messages on a WebSocket connection that is closed
+var ErrConnectionClosed = errors.New("websocket connection closed")
+
+// Message is a single WebSocket message
+type Message struct {
+	Type MessageType
+	Data []byte
+}
+
+// WebSocketConn is a message oriented WebSocket connection
+// passed in to WebSocket handlers. Safe for concurrent use
+type WebSocketConn interface {
+	// Receive blocks until the next message arrives, the connection
+	// is closed or the context is done
+	Receive(ctx context.Context) (Message, error)
+	// Send queues the message to be written to the client. Blocks while
+	// the send queue is full until the connection is closed or the context is done
+	Send(ctx context.Context, msg Message) error
+	// Close sends a close message with the code and reason to the client
+	// once all queued messages are written, and then closes the connection
+	Close(code int, reason string)
+}
+
+// WebSocketHandlerFunc is the signature WebSocket controllers must implement.
+// The connection is closed once the handler returns. The context is done
+// when the connection is closed by either side or the server shuts down
+type WebSocketHandlerFunc func(ctx context.Context, req *Request, conn WebSocketConn) error
+
+// WebSocket defines the properties of a WebSocket endpoint
+//
+// Handler (required) - called once the request is upgraded
+// MaxMessageSize - maximum size of incoming messages in bytes. Defaults to 32KB
+// PingInterval - interval between pings sent to the client. Connections are closed
+// when no pong is received within twice the interval. Defaults to 30 seconds
+// SendQueueSize - number of outgoing messages buffered per connection. Defaults to 16
+// CheckOrigin - reports whether the origin of the request is allowed.
+// Only same origin requests are allowed when not set
+type WebSocket struct {
+	Handler        WebSocketHandlerFunc
+	MaxMessageSize int64
+	PingInterval   time.Duration
+	SendQueueSize  int
+	CheckOrigin    func(r *http.Request) bool
+}
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+const (
+	defaultMaxMessageSize int64         = 32 << 10
+	defaultPingInterval   time.Duration = 30 * time.Second
+	defaultSendQueueSize  int           = 16
+
+	// maximum time allowed to write a message to the client
+	wsWriteWait time.Duration = 10 * time.Second
+)
+
+func (s *Server) handleWebSocket(ws *util.WebSocket) http.HandlerFunc {
+	maxMessageSize := ws.MaxMessageSize
+	if maxMessageSize <= 0 {
+		maxMessageSize = defaultMaxMessageSize
+	}
+
+	pingInterval := ws.PingInterval
+	if pingInterval <= 0 {
+		pingInterval = defaultPingInterval
+	}
+
+	sendQueueSize := ws.SendQueueSize
+	if sendQueueSize <= 0 {
+		sendQueueSize = defaultSendQueueSize
+	}
+
+	upgrader := &websocket.Upgrader{
+		CheckOrigin: ws.CheckOrigin,
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := r.Context()
+		requestID := ctx.Value(util.RequestIDKey).(string)
+
+		req := &util.Request{
+			PathParameters: mux.Vars(r),
+			Request:        r,
+		}
+
+		logger.Info("Incoming WebSocket connection",
+			"path",
+			req.URL.Path,
+			"query",
+			req.URL.RawQuery,
+			"requestId",
+			requestID,
+			"ip",
+			req.RemoteAddr,
+		)
+
+		// the hijacked connection does not carry the headers set by middlewares
+		conn, err := upgrader.Upgrade(w, r, http.Header{
+			"X-Request-ID": []string{requestID},
+		})
+		if err != nil {
+			logger.Error("Unable to upgrade to WebSocket connection",
+				"error",
+				err.Error(),
+				"requestId",
+				requestID,
+			)
+			return
+		}
+
+		if features := s.currentConfig().Features; features != nil {
+			ctx = context.WithValue(ctx, util.FeaturesKey, features)
+		}
+
+		c := newWSConn(ctx, conn, maxMessageSize, pingInterval, sendQueueSize)
+		if !s.websockets.add(c) {
+			c.Close(util.CloseGoingAway, "server shutting down")
+			c.wait()
+			return
+		}
+		defer s.websockets.remove(c)
+
+		code := util.CloseNormalClosure
+		if err := ws.Handler(c.ctx, req, c); err != nil {
+			code = util.CloseInternalError
+			logger.Error("WebSocket handler returned an error",
+				"error",
+				err.Error(),
+				"requestId",
+				requestID,
+			)
+		}
+
+		c.Close(code, "")
+		c.wait()
+
+		logger.Info("Closed WebSocket connection",
+			"code",
+			c.code(),
+			"requestId",
+			requestID,
+			"latency",
+			time.Since(start).String(),
+		)
+	}
+}
+
+// wsConn implements util.WebSocketConn. A read pump and a
+// write pump own the reading and writing sides of the connection
+type wsConn struct {
+	conn         *websocket.Conn
+	pingInterval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	incoming chan util.Message
+	outgoing chan util.Message
+	pumps    sync.WaitGroup
+
+	mu        sync.Mutex
+	closeCode int
+	closeText string
+}
+
+func newWSConn(ctx context.Context, conn *websocket.Conn, maxMessageSize int64, pingInterval time.Duration, sendQueueSize int) *wsConn {
+	c := &wsConn{
+		conn:         conn,
+		pingInterval: pingInterval,
+		incoming:     make(chan util.Message),
+		outgoing:     make(chan util.Message, sendQueueSize),
+		closeCode:    util.CloseNormalClosure,
+	}
+	c.ctx, c.cancel = context.WithCancel(ctx)
+
+	conn.SetReadLimit(maxMessageSize)
+
+	c.pumps.Add(2)
+	go c.readPump()
+	go c.writePump()
+
+	return c
+}
+
+func (c *wsConn) Receive(ctx context.Context) (util.Message, error) {
+	select {
+	case msg, ok := <-c.incoming:
+		if !ok {
+			return util.Message{}, util.ErrConnectionClosed
+		}
+		return msg, nil
+	case <-ctx.Done():
+		return util.Message{}, c.contextError(ctx)
+	}
+}
+
+func (c *wsConn) Send(ctx context.Context, msg util.Message) error {
+	if c.ctx.Err() != nil {
+		return util.ErrConnectionClosed
+	}
+
+	select {
+	case c.outgoing <- msg:
+		return nil
+	case <-c.ctx.Done():
+		return util.ErrConnectionClosed
+	case <-ctx.Done():
+		return c.contextError(ctx)
+	}
+}
+
+// contextError prefers reporting the connection as closed
+// when the context passed in is derived from the connection's
+func (c *wsConn) contextError(ctx context.Context) error {
+	if c.ctx.Err() != nil {
+		return util.ErrConnectionClosed
+	}
+	return ctx.Err()
+}
+
+func (c *wsConn) Close(code int, reason string) {
+	c.mu.Lock()
+	if c.ctx.Err() == nil {
+		c.closeCode = code
+		c.closeText = reason
+	}
+	c.mu.Unlock()
+
+	c.cancel()
+}
+
+func (c *wsConn) code() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closeCode
+}
+
+// wait blocks until both pumps are done
+func (c *wsConn) wait() {
+	c.pumps.Wait()
+}
+
+func (c *wsConn) readPump() {
+	defer c.pumps.Done()
+	defer close(c.incoming)
+	defer c.cancel()
+
+	pongWait := 2 * c.pingInterval
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		t, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if closeErr, ok := err.(*websocket.CloseError); ok {
+				c.Close(closeErr.Code, "")
+			} else if err == websocket.ErrReadLimit {
+				c.Close(util.ClosePolicyViolation, "message too big")
+			}
+			return
+		}
+
+		select {
+		case c.incoming <- util.Message{Type: util.MessageType(t), Data: data}:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *wsConn) writePump() {
+	defer c.pumps.Done()
+	defer c.conn.Close()
+
+	ticker := time.NewTicker(c.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-c.outgoing:
+			if err := c.write(msg); err != nil {
+				c.cancel()
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				c.cancel()
+				return
+			}
+		case <-c.ctx.Done():
+			c.drain()
+
+			c.mu.Lock()
+			msg := websocket.FormatCloseMessage(c.closeCode, c.closeText)
+			c.mu.Unlock()
+
+			c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+			return
+		}
+	}
+}
+
+// drain writes the messages still queued before closing
+func (c *wsConn) drain() {
+	for {
+		select {
+		case msg := <-c.outgoing:
+			if err := c.write(msg); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (c *wsConn) write(msg util.Message) error {
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteMessage(int(msg.Type), msg.Data)
+}
+
+// wsHub keeps track of the open WebSocket connections
+// so they can be closed when the server shuts down
+type wsHub struct {
+	mu       sync.Mutex
+	conns    map[*wsConn]struct{}
+	closing  bool
+	handlers sync.WaitGroup
+}
+
+// add registers the connection. Returns false
+// when the server is already shutting down
+func (h *wsHub) add(c *wsConn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closing {
+		return false
+	}
+
+	if h.conns == nil {
+		h.conns = make(map[*wsConn]struct{})
+	}
+	h.conns[c] = struct{}{}
+	h.handlers.Add(1)
+	return true
+}
+
+func (h *wsHub) remove(c *wsConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.conns, c)
+	h.handlers.Done()
+}
+
+// closeAll tells every open connection that
+// the server is going away
+func (h *wsHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closing = true
+	for c := range h.conns {
+		c.Close(util.CloseGoingAway, "server shutting down")
+	}
+}
+
+// wait blocks until the handlers of all connections
+// returned or the context is done
+func (h *wsHub) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.handlers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
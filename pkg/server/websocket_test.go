@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+func newWebSocketServer(ws *util.WebSocket) (*Server, *httptest.Server) {
+	srv := &Server{
+		config: &Config{},
+	}
+	srv.AddRoutes(util.Route{
+		Name:      "ws",
+		Path:      "/ws",
+		WebSocket: ws,
+	})
+
+	return srv, httptest.NewServer(srv.mountRoutes())
+}
+
+func dialWebSocket(t *testing.T, r *httptest.Server) (*websocket.Conn, *http.Response) {
+	url := "ws" + strings.TrimPrefix(r.URL, "http") + "/ws"
+	conn, res, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.Nil(t, err)
+
+	return conn, res
+}
+
+func TestWebSocket(t *testing.T) {
+	t.Run("echo", func(t *testing.T) {
+		_, r := newWebSocketServer(&util.WebSocket{
+			Handler: func(ctx context.Context, req *util.Request, conn util.WebSocketConn) error {
+				for {
+					msg, err := conn.Receive(ctx)
+					if err != nil {
+						return nil
+					}
+
+					if err := conn.Send(ctx, msg); err != nil {
+						return err
+					}
+				}
+			},
+		})
+		defer r.Close()
+
+		conn, res := dialWebSocket(t, r)
+		defer conn.Close()
+
+		assert.NotEmpty(t, res.Header.Get("X-Request-ID"))
+
+		err := conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+		assert.Nil(t, err)
+
+		mt, data, err := conn.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, websocket.TextMessage, mt)
+		assert.Equal(t, "hello", string(data))
+	})
+
+	t.Run("handler error closes connection", func(t *testing.T) {
+		_, r := newWebSocketServer(&util.WebSocket{
+			Handler: func(ctx context.Context, req *util.Request, conn util.WebSocketConn) error {
+				conn.Send(ctx, util.Message{Type: util.TextMessage, Data: []byte("bye")})
+				return errors.New("something went wrong")
+			},
+		})
+		defer r.Close()
+
+		conn, _ := dialWebSocket(t, r)
+		defer conn.Close()
+
+		_, data, err := conn.ReadMessage()
+		assert.Nil(t, err)
+		assert.Equal(t, "bye", string(data))
+
+		_, _, err = conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseInternalServerErr))
+	})
+
+	t.Run("max message size", func(t *testing.T) {
+		_, r := newWebSocketServer(&util.WebSocket{
+			MaxMessageSize: 4,
+			Handler: func(ctx context.Context, req *util.Request, conn util.WebSocketConn) error {
+				_, err := conn.Receive(ctx)
+				assert.Equal(t, util.ErrConnectionClosed, err)
+				return nil
+			},
+		})
+		defer r.Close()
+
+		conn, _ := dialWebSocket(t, r)
+		defer conn.Close()
+
+		err := conn.WriteMessage(websocket.BinaryMessage, []byte("too big"))
+		assert.Nil(t, err)
+
+		_, _, err = conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig, websocket.ClosePolicyViolation))
+	})
+
+	t.Run("keepalive pings", func(t *testing.T) {
+		_, r := newWebSocketServer(&util.WebSocket{
+			PingInterval: 10 * time.Millisecond,
+			Handler: func(ctx context.Context, req *util.Request, conn util.WebSocketConn) error {
+				<-ctx.Done()
+				return nil
+			},
+		})
+		defer r.Close()
+
+		conn, _ := dialWebSocket(t, r)
+		defer conn.Close()
+
+		pinged := make(chan struct{}, 1)
+		conn.SetPingHandler(func(data string) error {
+			select {
+			case pinged <- struct{}{}:
+			default:
+			}
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+		go conn.ReadMessage()
+
+		select {
+		case <-pinged:
+		case <-time.After(time.Second):
+			t.Fatal("no ping received")
+		}
+	})
+
+	t.Run("rejects other origins", func(t *testing.T) {
+		_, r := newWebSocketServer(&util.WebSocket{
+			Handler: func(ctx context.Context, req *util.Request, conn util.WebSocketConn) error {
+				return nil
+			},
+		})
+		defer r.Close()
+
+		url := "ws" + strings.TrimPrefix(r.URL, "http") + "/ws"
+		_, res, err := websocket.DefaultDialer.Dial(url, http.Header{
+			"Origin": []string{"https://evil.com"},
+		})
+		assert.NotNil(t, err)
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("graceful close on shutdown", func(t *testing.T) {
+		closed := make(chan struct{})
+		srv, r := newWebSocketServer(&util.WebSocket{
+			Handler: func(ctx context.Context, req *util.Request, conn util.WebSocketConn) error {
+				defer close(closed)
+				<-ctx.Done()
+				return nil
+			},
+		})
+		defer r.Close()
+
+		conn, _ := dialWebSocket(t, r)
+		defer conn.Close()
+
+		assert.Eventually(t, func() bool {
+			srv.websockets.mu.Lock()
+			defer srv.websockets.mu.Unlock()
+			return len(srv.websockets.conns) == 1
+		}, time.Second, 5*time.Millisecond)
+
+		srv.websockets.closeAll()
+
+		_, _, err := conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		assert.Nil(t, srv.websockets.wait(ctx))
+		<-closed
+
+		// new connections are turned away while shutting down
+		late, _ := dialWebSocket(t, r)
+		defer late.Close()
+
+		_, _, err = late.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+	})
+}
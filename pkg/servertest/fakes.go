@@ -0,0 +1,35 @@
+package servertest
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+	"github.com/riyadhalnur/godi/v2/pkg/middleware"
+)
+
+// FakeRequestIDs replaces the uuids of requests with sequential IDs
+// e.g. request-1, request-2, until the end of the test. Request IDs
+// are global, so tests using it must not run in parallel
+func FakeRequestIDs(t testing.TB) {
+	var count int64
+	restore := middleware.SetRequestIDGenerator(func() string {
+		return fmt.Sprintf("request-%d", atomic.AddInt64(&count, 1))
+	})
+	t.Cleanup(restore)
+}
+
+// CaptureLogs records the entries of the logger package, at any
+// level, instead of writing them until the end of the test. The
+// logger is global, so tests using it must not run in parallel
+func CaptureLogs(t testing.TB) *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.DebugLevel)
+	restore := logger.Replace(zap.New(core).Sugar())
+	t.Cleanup(restore)
+	return logs
+}
@@ -0,0 +1,48 @@
+package servertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// UpdateGoldenEnv is the environment variable which, when
+// true, writes the golden files instead of comparing them
+// e.g. UPDATE_GOLDEN=true go test ./...
+const UpdateGoldenEnv string = "UPDATE_GOLDEN"
+
+// Golden compares the content to the golden file testdata/<name>.golden
+// of the package under test. JSON is indented before the comparison,
+// so the files stay readable and their diffs small
+func Golden(t testing.TB, name string, content []byte) {
+	t.Helper()
+
+	var indented bytes.Buffer
+	if json.Indent(&indented, bytes.TrimSpace(content), "", "  ") == nil {
+		indented.WriteByte('\n')
+		content = indented.Bytes()
+	}
+
+	path := filepath.Join("testdata", name+".golden")
+	if update, _ := strconv.ParseBool(os.Getenv(UpdateGoldenEnv)); update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("servertest: %v", err)
+		}
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("servertest: %v", err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("servertest: reading the golden file, run the tests with %s=true to create it: %v", UpdateGoldenEnv, err)
+	}
+	assert.Equal(t, string(expected), string(content), "golden file %s", path)
+}
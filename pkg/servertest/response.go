@@ -0,0 +1,101 @@
+package servertest
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+// Response is a recorded response. Assertions report failures
+// to the test and return the response to chain them
+type Response struct {
+	*httptest.ResponseRecorder
+	t testing.TB
+}
+
+// AssertStatus asserts the status code of the response
+func (r *Response) AssertStatus(code int) *Response {
+	r.t.Helper()
+	assert.Equal(r.t, code, r.Code, "status code, body: %s", r.Body.String())
+	return r
+}
+
+// AssertHeader asserts a header of the response
+func (r *Response) AssertHeader(key, value string) *Response {
+	r.t.Helper()
+	assert.Equal(r.t, value, r.Header().Get(key), "header %s", key)
+	return r
+}
+
+// AssertBody asserts the body of the response
+func (r *Response) AssertBody(body string) *Response {
+	r.t.Helper()
+	assert.Equal(r.t, body, r.Body.String())
+	return r
+}
+
+// AssertJSON asserts the body is the same JSON as expected,
+// regardless of formatting and key order. Expected is either
+// a JSON string or a value to encode
+func (r *Response) AssertJSON(expected interface{}) *Response {
+	r.t.Helper()
+
+	var want string
+	switch v := expected.(type) {
+	case string:
+		want = v
+	case []byte:
+		want = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			r.t.Fatalf("servertest: encoding the expected body: %v", err)
+		}
+		want = string(b)
+	}
+
+	assert.JSONEq(r.t, want, r.Body.String())
+	return r
+}
+
+// AssertError asserts the response is an util.ErrorResponse
+// with the status code and type e.g. godierr.NotFoundType
+func (r *Response) AssertError(code int, errType string) *Response {
+	r.t.Helper()
+
+	res := r.ErrorResponse()
+	assert.Equal(r.t, code, r.Code, "status code")
+	assert.Equal(r.t, code, res.Code, "error code")
+	assert.Equal(r.t, errType, res.Type, "error type")
+	return r
+}
+
+// ErrorResponse decodes the util.ErrorResponse of the body
+func (r *Response) ErrorResponse() util.ErrorResponse {
+	r.t.Helper()
+
+	var res util.ErrorResponse
+	r.DecodeJSON(&res)
+	return res
+}
+
+// DecodeJSON decodes the JSON body into v
+func (r *Response) DecodeJSON(v interface{}) {
+	r.t.Helper()
+
+	if err := json.Unmarshal(r.Body.Bytes(), v); err != nil {
+		r.t.Fatalf("servertest: decoding the body %q: %v", r.Body.String(), err)
+	}
+}
+
+// AssertGolden asserts the body matches the golden
+// file testdata/<name>.golden. See Golden
+func (r *Response) AssertGolden(name string) *Response {
+	r.t.Helper()
+	Golden(r.t, name, r.Body.Bytes())
+	return r
+}
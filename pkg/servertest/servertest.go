@@ -0,0 +1,231 @@
+// Package servertest tests godi servers in-process. Requests are
+// served by the handler of the server without binding a port, and
+// responses come with assertions on their status, headers, JSON
+// bodies and util.ErrorResponse errors, e.g.
+//
+//	srv := servertest.New(t, server.NewServer(cfg))
+//	srv.Get("/users/1").Header("Accept", "application/json").Do().
+//		AssertStatus(http.StatusOK).
+//		AssertJSON(`{"id": 1, "name": "Jane"}`)
+package servertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/riyadhalnur/godi/v2/pkg/openapi"
+	"github.com/riyadhalnur/godi/v2/pkg/server"
+)
+
+// Server serves requests using the handler of a server
+type Server struct {
+	t         testing.TB
+	server    *server.Server
+	handler   http.Handler
+	validator *openapi.Validator
+}
+
+// New returns the in-process server of srv. Add the routes,
+// middlewares and dependencies of srv before calling New.
+// The test fails when the server does not load
+func New(t testing.TB, srv *server.Server) *Server {
+	t.Helper()
+
+	handler, err := srv.Handler()
+	if err != nil {
+		t.Fatalf("servertest: loading the server: %v", err)
+	}
+	// singletons of the container are closed with the test
+	t.Cleanup(func() {
+		srv.Container().Close()
+	})
+
+	return &Server{
+		t:       t,
+		server:  srv,
+		handler: handler,
+	}
+}
+
+// Server returns the server under test
+func (s *Server) Server() *server.Server {
+	return s.server
+}
+
+// ValidateContract fails the test whenever a request or a response
+// differs from its operation in the document. Uses the document
+// generated from the routes of the server when doc is nil
+func (s *Server) ValidateContract(doc *openapi.Document) *Server {
+	if doc == nil {
+		doc = s.server.OpenAPI(openapi.Info{})
+	}
+	s.validator = openapi.NewValidator(doc)
+	return s
+}
+
+// ServeHTTP serves the request, so the server
+// can be wrapped e.g. by httptest.NewServer
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// Do serves the request and records the response
+func (s *Server) Do(r *http.Request) *Response {
+	s.t.Helper()
+
+	var (
+		body       []byte
+		violations []openapi.Violation
+	)
+	if s.validator != nil {
+		if r.Body != nil {
+			var err error
+			if body, err = ioutil.ReadAll(r.Body); err != nil {
+				s.t.Fatalf("servertest: reading the request body: %v", err)
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		violations = s.validator.ValidateRequest(r, body)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, r)
+
+	if s.validator != nil {
+		violations = append(violations, s.validator.ValidateResponse(r, rec.Code, rec.Header(), rec.Body.Bytes())...)
+		for _, v := range violations {
+			s.t.Errorf("servertest: contract violation: %v", v)
+		}
+	}
+	return &Response{t: s.t, ResponseRecorder: rec}
+}
+
+// Get starts a GET request to the path
+func (s *Server) Get(path string) *Request {
+	return s.NewRequest(http.MethodGet, path)
+}
+
+// Post starts a POST request to the path
+func (s *Server) Post(path string) *Request {
+	return s.NewRequest(http.MethodPost, path)
+}
+
+// Put starts a PUT request to the path
+func (s *Server) Put(path string) *Request {
+	return s.NewRequest(http.MethodPut, path)
+}
+
+// Patch starts a PATCH request to the path
+func (s *Server) Patch(path string) *Request {
+	return s.NewRequest(http.MethodPatch, path)
+}
+
+// Delete starts a DELETE request to the path
+func (s *Server) Delete(path string) *Request {
+	return s.NewRequest(http.MethodDelete, path)
+}
+
+// NewRequest starts a request with the method to the path
+func (s *Server) NewRequest(method, path string) *Request {
+	return &Request{
+		server: s,
+		method: method,
+		path:   path,
+		header: http.Header{},
+		query:  url.Values{},
+		ctx:    context.Background(),
+	}
+}
+
+// Request builds a request to the server
+type Request struct {
+	server *Server
+	method string
+	path   string
+	header http.Header
+	query  url.Values
+	body   []byte
+	ctx    context.Context
+}
+
+// Header sets a header of the request
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// Query adds a query parameter to the URL of the request
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// Context sets the context of the request
+func (r *Request) Context(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// Body sets the body of the request and its content type
+func (r *Request) Body(contentType string, body []byte) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = body
+	return r
+}
+
+// JSON sets the body of the request to the JSON encoding of v.
+// Strings and byte slices are sent as is
+func (r *Request) JSON(v interface{}) *Request {
+	r.server.t.Helper()
+
+	var body []byte
+	switch v := v.(type) {
+	case string:
+		body = []byte(v)
+	case []byte:
+		body = v
+	default:
+		var err error
+		if body, err = json.Marshal(v); err != nil {
+			r.server.t.Fatalf("servertest: encoding the body: %v", err)
+		}
+	}
+	return r.Body("application/json", body)
+}
+
+// Build returns the request
+func (r *Request) Build() *http.Request {
+	r.server.t.Helper()
+
+	target := r.path
+	if len(r.query) > 0 {
+		target += "?" + r.query.Encode()
+	}
+
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+
+	req, err := http.NewRequestWithContext(r.ctx, r.method, target, body)
+	if err != nil {
+		r.server.t.Fatalf("servertest: building the request: %v", err)
+	}
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	return req
+}
+
+// Do serves the request and records the response
+func (r *Request) Do() *Response {
+	r.server.t.Helper()
+	return r.server.Do(r.Build())
+}
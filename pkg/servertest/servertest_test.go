@@ -0,0 +1,163 @@
+package servertest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/riyadhalnur/godi/v2/pkg/godierr"
+	"github.com/riyadhalnur/godi/v2/pkg/logger"
+	"github.com/riyadhalnur/godi/v2/pkg/server"
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeT records the failures of the assertions under test
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Name() string                              { return "fake" }
+func (t *fakeT) Helper()                                   {}
+func (t *fakeT) Cleanup(func())                            {}
+func (t *fakeT) Errorf(format string, args ...interface{}) { t.failed = true }
+func (t *fakeT) Fatalf(format string, args ...interface{}) { t.failed = true }
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func newTestServer(t *testing.T) *Server {
+	srv := server.NewServer(&server.Config{Port: "3000", Timeout: 30})
+	srv.AddRoutes(
+		util.Route{
+			Name:   "getUser",
+			Path:   "/users/{id}",
+			Method: http.MethodGet,
+			Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+				if req.PathParameters["id"] != "1" {
+					return nil, godierr.NotFoundError("user")
+				}
+				logger.Info("Found user", "id", req.PathParameters["id"], "requestId", ctx.Value(util.RequestIDKey))
+				return &util.Response{
+					StatusCode: http.StatusOK,
+					Value:      user{ID: "1", Name: req.URL.Query().Get("name")},
+				}, nil
+			},
+		},
+		util.Route{
+			Name:   "createUser",
+			Path:   "/users",
+			Method: http.MethodPost,
+			Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+				var u user
+				if err := json.NewDecoder(req.Body).Decode(&u); err != nil {
+					return nil, godierr.InvalidArgsError("body")
+				}
+				return &util.Response{
+					StatusCode: http.StatusCreated,
+					Headers:    map[string]string{"Location": "/users/" + u.ID},
+					Value:      u,
+				}, nil
+			},
+		},
+	)
+	return New(t, srv)
+}
+
+func TestServer(t *testing.T) {
+	FakeRequestIDs(t)
+	logs := CaptureLogs(t)
+	srv := newTestServer(t)
+
+	t.Run("json", func(t *testing.T) {
+		res := srv.Get("/users/1").
+			Query("name", "Jane").
+			Header("Accept", "application/json").
+			Do().
+			AssertStatus(http.StatusOK).
+			AssertHeader("X-Request-ID", "request-1").
+			AssertJSON(`{"name": "Jane", "id": "1"}`).
+			AssertJSON(user{ID: "1", Name: "Jane"}).
+			AssertGolden("user")
+
+		var u user
+		res.DecodeJSON(&u)
+		assert.Equal(t, "Jane", u.Name)
+
+		entries := logs.FilterMessage("Found user").All()
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "request-1", entries[0].ContextMap()["requestId"])
+	})
+
+	t.Run("request body", func(t *testing.T) {
+		srv.Post("/users").
+			JSON(user{ID: "2", Name: "John"}).
+			Do().
+			AssertStatus(http.StatusCreated).
+			AssertHeader("Location", "/users/2").
+			AssertJSON(`{"id": "2", "name": "John"}`)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		res := srv.Get("/users/2").Do().AssertError(http.StatusNotFound, godierr.NotFoundType)
+		assert.Equal(t, "request-3", res.Header().Get("X-Request-ID"))
+
+		srv.Post("/users").JSON("{").Do().AssertError(http.StatusBadRequest, godierr.InvalidArgType)
+	})
+
+	t.Run("failed assertions", func(t *testing.T) {
+		for _, assertion := range []func(*Response){
+			func(res *Response) { res.AssertStatus(http.StatusTeapot) },
+			func(res *Response) { res.AssertHeader("X-Request-ID", "request-1") },
+			func(res *Response) { res.AssertJSON(`{}`) },
+			func(res *Response) { res.AssertError(http.StatusNotFound, godierr.NotFoundType) },
+		} {
+			fake := &fakeT{}
+			assertion(&Response{t: fake, ResponseRecorder: srv.Get("/users/1").Do().ResponseRecorder})
+			assert.True(t, fake.failed)
+		}
+	})
+}
+
+func TestServerLoadFailure(t *testing.T) {
+	fake := &fakeT{}
+	srv := server.NewServer(&server.Config{Port: "3000", Timeout: 30, DebugNetworks: []string{"not a network"}})
+
+	New(fake, srv)
+	assert.True(t, fake.failed)
+}
+
+func TestValidateContract(t *testing.T) {
+	newServer := func(t testing.TB, value interface{}) *Server {
+		srv := server.NewServer(&server.Config{Port: "3000", Timeout: 30})
+		srv.AddRoutes(util.Route{
+			Name:   "getUser",
+			Path:   "/users/{id}",
+			Method: http.MethodGet,
+			Handler: func(ctx context.Context, req *util.Request) (*util.Response, error) {
+				return &util.Response{StatusCode: http.StatusOK, Value: value}, nil
+			},
+			Doc: &util.RouteDoc{Response: user{}},
+		})
+		return New(t, srv).ValidateContract(nil)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		newServer(t, user{ID: "1", Name: "Jane"}).Get("/users/1").Do().AssertStatus(http.StatusOK)
+	})
+
+	t.Run("violations", func(t *testing.T) {
+		fake := &fakeT{}
+		newServer(fake, map[string]int{"id": 1}).Get("/users/1").Do().AssertStatus(http.StatusOK)
+		assert.True(t, fake.failed)
+
+		fake = &fakeT{}
+		newServer(fake, user{ID: "1"}).Get("/posts").Do()
+		assert.True(t, fake.failed)
+	})
+}
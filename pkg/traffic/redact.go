@@ -0,0 +1,177 @@
+package traffic
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the values of redacted headers, query parameters and fields
+const Redacted string = "[REDACTED]"
+
+// DefaultRedactHeaders are the headers always redacted
+var DefaultRedactHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie", "X-Api-Key"}
+
+// Redactor masks credentials and personal data in exchanges before
+// they are written. Fields are matched by name, ignoring case, in
+// the query of requests, in form bodies and at any depth of JSON bodies
+type Redactor struct {
+	headers map[string]bool
+	fields  map[string]bool
+}
+
+// NewRedactor returns a new instance of Redactor masking
+// the headers, on top of DefaultRedactHeaders, and the fields
+// e.g. NewRedactor(nil, []string{"password", "email"})
+func NewRedactor(headers, fields []string) *Redactor {
+	r := &Redactor{
+		headers: map[string]bool{},
+		fields:  map[string]bool{},
+	}
+	for _, h := range append(append([]string{}, DefaultRedactHeaders...), headers...) {
+		r.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	for _, f := range fields {
+		r.fields[strings.ToLower(f)] = true
+	}
+	return r
+}
+
+// Redact masks the headers and fields of the request and the response
+func (r *Redactor) Redact(e *Exchange) {
+	r.redactHeader(e.Request.Header)
+	r.redactHeader(e.Response.Header)
+	e.Request.URL = r.redactURL(e.Request.URL)
+	e.Request.Body.Data = r.redactBody(e.Request.Header, e.Request.Body.Data)
+	e.Response.Body.Data = r.redactBody(e.Response.Header, e.Response.Body.Data)
+}
+
+func (r *Redactor) redactHeader(header http.Header) {
+	for name, values := range header {
+		if !r.headers[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		for i := range values {
+			values[i] = Redacted
+		}
+	}
+}
+
+func (r *Redactor) redactURL(rawURL string) string {
+	if len(r.fields) == 0 {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+
+	query := u.Query()
+	if !r.redactQuery(query) {
+		return rawURL
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// redactQuery masks the fields of the query in place,
+// reporting whether any was found
+func (r *Redactor) redactQuery(query url.Values) bool {
+	redacted := false
+	for name, values := range query {
+		if !r.fields[strings.ToLower(name)] {
+			continue
+		}
+		for i := range values {
+			values[i] = Redacted
+		}
+		redacted = true
+	}
+	return redacted
+}
+
+// redactBody masks the fields of JSON bodies, whatever their content
+// type as clients do not always set it, and of form bodies. Other
+// bodies are kept as is
+func (r *Redactor) redactBody(header http.Header, body []byte) []byte {
+	if len(r.fields) == 0 || len(body) == 0 {
+		return body
+	}
+
+	if redacted, ok := r.redactJSON(body); ok {
+		return redacted
+	}
+	if isForm(header.Get("Content-Type")) {
+		return r.redactForm(body)
+	}
+	return body
+}
+
+// redactJSON masks the fields of the body, reporting
+// whether the body is a single JSON value
+func (r *Redactor) redactJSON(body []byte) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return body, false
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return body, false
+	}
+	if !r.redactValue(value) {
+		return body, true
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return body, true
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), true
+}
+
+// redactForm masks the fields of a URL encoded form body
+func (r *Redactor) redactForm(body []byte) []byte {
+	form, err := url.ParseQuery(string(body))
+	if err != nil || !r.redactQuery(form) {
+		return body
+	}
+	return []byte(form.Encode())
+}
+
+// redactValue masks the fields of the value in place,
+// reporting whether any was found
+func (r *Redactor) redactValue(value interface{}) bool {
+	redacted := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, field := range v {
+			if r.fields[strings.ToLower(name)] {
+				v[name] = Redacted
+				redacted = true
+				continue
+			}
+			if r.redactValue(field) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if r.redactValue(item) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+func isForm(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/x-www-form-urlencoded"
+}
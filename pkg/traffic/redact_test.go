@@ -0,0 +1,87 @@
+package traffic
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor([]string{"x-session"}, []string{"password", "Token"})
+
+	e := &Exchange{
+		Request: Request{
+			Method: http.MethodPost,
+			URL:    "/login?token=secret&next=%2Fhome",
+			Header: http.Header{
+				"Authorization": []string{"Bearer secret"},
+				"X-Session":     []string{"secret"},
+				"Content-Type":  []string{"application/json"},
+			},
+			Body: Body{Data: []byte(`{"user":{"name":"jane","password":"secret"},"devices":[{"token":"secret","id":12345678901234567890}]}`)},
+		},
+		Response: Response{
+			Status: http.StatusOK,
+			Header: http.Header{
+				"Set-Cookie":   []string{"session=secret", "theme=dark"},
+				"Content-Type": []string{"text/plain"},
+			},
+			Body: Body{Data: []byte(`password=secret`)},
+		},
+	}
+	r.Redact(e)
+
+	assert.Equal(t, "/login?next=%2Fhome&token=%5BREDACTED%5D", e.Request.URL)
+	assert.Equal(t, http.Header{
+		"Authorization": []string{Redacted},
+		"X-Session":     []string{Redacted},
+		"Content-Type":  []string{"application/json"},
+	}, e.Request.Header)
+	assert.Equal(t, `{"devices":[{"id":12345678901234567890,"token":"[REDACTED]"}],"user":{"name":"jane","password":"[REDACTED]"}}`, string(e.Request.Body.Data))
+	assert.Equal(t, []string{Redacted, Redacted}, e.Response.Header["Set-Cookie"])
+	// bodies that are neither JSON nor forms are kept as is
+	assert.Equal(t, `password=secret`, string(e.Response.Body.Data))
+
+	t.Run("bodies", func(t *testing.T) {
+		cases := []struct {
+			name        string
+			contentType string
+			body        string
+			expected    string
+		}{
+			{"JSON without content type", "", `{"password":"secret"}`, `{"password":"[REDACTED]"}`},
+			{"JSON as text", "text/plain", `[{"token":"secret"}]`, `[{"token":"[REDACTED]"}]`},
+			{"form", "application/x-www-form-urlencoded", `user=jane&password=secret&password=again`, `password=%5BREDACTED%5D&password=%5BREDACTED%5D&user=jane`},
+			{"form with charset", "application/x-www-form-urlencoded; charset=utf-8", `Token=secret`, `Token=%5BREDACTED%5D`},
+			{"form without fields", "application/x-www-form-urlencoded", `user=jane&next=%2Fhome`, `user=jane&next=%2Fhome`},
+			{"invalid form", "application/x-www-form-urlencoded", `password=%zz`, `password=%zz`},
+			{"text", "text/plain", `password: secret`, `password: secret`},
+		}
+
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				e := &Exchange{Request: Request{
+					Header: http.Header{"Content-Type": []string{c.contentType}},
+					Body:   Body{Data: []byte(c.body)},
+				}}
+				r.Redact(e)
+
+				assert.Equal(t, c.expected, string(e.Request.Body.Data))
+			})
+		}
+	})
+
+	t.Run("nothing to redact", func(t *testing.T) {
+		body := `{ "name": "jane" }`
+		e := &Exchange{Request: Request{
+			URL:    "/users?limit=10",
+			Header: http.Header{"Content-Type": []string{"application/json"}},
+			Body:   Body{Data: []byte(body)},
+		}}
+		r.Redact(e)
+
+		assert.Equal(t, "/users?limit=10", e.Request.URL)
+		assert.Equal(t, body, string(e.Request.Body.Data))
+	})
+}
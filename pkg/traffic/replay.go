@@ -0,0 +1,298 @@
+package traffic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/riyadhalnur/godi/v2/pkg/server/util"
+)
+
+// missing is shown for values present on one side only
+const missing string = "(missing)"
+
+// ErrBodyOmitted is returned when replaying a request
+// whose body was too large to be recorded
+var ErrBodyOmitted = errors.New("traffic: the request body was not recorded")
+
+// DefaultIgnoreHeaders are the response headers never compared,
+// as they differ between responses to the same request
+var DefaultIgnoreHeaders = []string{"Age", "Content-Length", "Date", "Etag", "Idempotent-Replayed", "Last-Modified", "X-Cache", "X-Request-Id"}
+
+// request headers set by the client when replaying
+var clientHeaders = []string{"Accept-Encoding", "Connection", "Content-Length", "Host", "Keep-Alive", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+// ReplayOptions configures a Replayer
+//
+// Target (required) - base URL of the server e.g. http://localhost:3001
+// Client - sends the requests. Defaults to a client timing out after 30s
+// Header - set on every request e.g. to replace redacted credentials
+// IgnoreHeaders - response headers not compared, on top of DefaultIgnoreHeaders
+// IgnoreFields - JSON fields not compared, matched by name ignoring case
+// at any depth e.g. id or createdAt
+type ReplayOptions struct {
+	Target        string
+	Client        *http.Client
+	Header        http.Header
+	IgnoreHeaders []string
+	IgnoreFields  []string
+}
+
+// Replayer re-sends recorded requests and diffs
+// the responses with the recorded ones
+type Replayer struct {
+	target        *url.URL
+	client        *http.Client
+	header        http.Header
+	ignoreHeaders map[string]bool
+	ignoreFields  map[string]bool
+}
+
+// Result is the outcome of replaying an exchange
+type Result struct {
+	Exchange *Exchange
+	Response Response
+	Duration time.Duration
+	Diffs    []Diff
+}
+
+// Diff is a difference between the recorded and the replayed response
+//
+// Field - what differs e.g. status, header.Content-Type, body.user.name
+// Recorded, Replayed - the values, as JSON for fields of JSON bodies
+type Diff struct {
+	Field    string
+	Recorded string
+	Replayed string
+}
+
+func (d Diff) String() string {
+	return fmt.Sprintf("%s: %s != %s", d.Field, d.Recorded, d.Replayed)
+}
+
+// NewReplayer returns a new instance of Replayer
+func NewReplayer(opts ReplayOptions) (*Replayer, error) {
+	target, err := url.Parse(opts.Target)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("traffic: invalid target %q", opts.Target)
+	}
+
+	r := &Replayer{
+		target:        target,
+		client:        opts.Client,
+		header:        opts.Header,
+		ignoreHeaders: map[string]bool{},
+		ignoreFields:  map[string]bool{},
+	}
+	if r.client == nil {
+		r.client = &http.Client{Timeout: 30 * time.Second}
+	}
+	for _, h := range append(append([]string{}, DefaultIgnoreHeaders...), opts.IgnoreHeaders...) {
+		r.ignoreHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+	for _, f := range opts.IgnoreFields {
+		r.ignoreFields[strings.ToLower(f)] = true
+	}
+	return r, nil
+}
+
+// Replay sends the request of the exchange to the target and diffs
+// the response with the recorded one. Redacted headers are not sent
+func (r *Replayer) Replay(ctx context.Context, e *Exchange) (*Result, error) {
+	if e.Request.Body.Omitted {
+		return nil, ErrBodyOmitted
+	}
+
+	ref, err := url.Parse(e.Request.URL)
+	if err != nil {
+		return nil, fmt.Errorf("traffic: invalid URL %q: %w", e.Request.URL, err)
+	}
+	target := *r.target
+	target.Path = strings.TrimSuffix(target.Path, "/") + ref.Path
+	target.RawPath = ""
+	target.RawQuery = ref.RawQuery
+
+	req, err := http.NewRequestWithContext(ctx, e.Request.Method, target.String(), bytes.NewReader(e.Request.Body.Data))
+	if err != nil {
+		return nil, fmt.Errorf("traffic: %w", err)
+	}
+	for name, values := range e.Request.Header {
+		for _, value := range values {
+			if value != Redacted {
+				req.Header.Add(name, value)
+			}
+		}
+	}
+	for _, name := range clientHeaders {
+		req.Header.Del(name)
+	}
+	for name, values := range r.header {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+
+	start := time.Now()
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("traffic: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("traffic: reading the response of %s %s: %w", e.Request.Method, e.Request.URL, err)
+	}
+
+	result := &Result{
+		Exchange: e,
+		Response: Response{Status: res.StatusCode, Header: res.Header, Body: Body{Data: body}},
+		Duration: time.Since(start),
+	}
+	result.Diffs = r.Diff(e.Response, result.Response)
+	return result, nil
+}
+
+// Diff returns the differences between the recorded and the replayed
+// response: the status, the headers not ignored and the body. JSON
+// bodies are compared field by field. Redacted values and omitted
+// bodies are not compared
+func (r *Replayer) Diff(recorded, replayed Response) []Diff {
+	diffs := []Diff{}
+	if recorded.Status != replayed.Status {
+		diffs = append(diffs, Diff{Field: "status", Recorded: fmt.Sprint(recorded.Status), Replayed: fmt.Sprint(replayed.Status)})
+	}
+
+	names := map[string]bool{}
+	for name := range recorded.Header {
+		names[http.CanonicalHeaderKey(name)] = true
+	}
+	for name := range replayed.Header {
+		names[http.CanonicalHeaderKey(name)] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		if !r.ignoreHeaders[name] {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		want, got := headerValue(recorded.Header, name), headerValue(replayed.Header, name)
+		if want != got && want != Redacted {
+			diffs = append(diffs, Diff{Field: "header." + name, Recorded: want, Replayed: got})
+		}
+	}
+
+	if recorded.Body.Omitted || replayed.Body.Omitted {
+		return diffs
+	}
+	if util.IsJSON(recorded.Header.Get("Content-Type")) && util.IsJSON(replayed.Header.Get("Content-Type")) {
+		want, wantErr := decodeJSON(recorded.Body.Data)
+		got, gotErr := decodeJSON(replayed.Body.Data)
+		if wantErr == nil && gotErr == nil {
+			r.diffValues("body", want, got, &diffs)
+			return diffs
+		}
+	}
+	if !bytes.Equal(recorded.Body.Data, replayed.Body.Data) {
+		diffs = append(diffs, Diff{Field: "body", Recorded: abbreviate(recorded.Body.Data), Replayed: abbreviate(replayed.Body.Data)})
+	}
+	return diffs
+}
+
+func (r *Replayer) diffValues(field string, want, got interface{}, diffs *[]Diff) {
+	if want == Redacted {
+		return
+	}
+
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			break
+		}
+		names := make([]string, 0, len(w)+len(g))
+		for name := range w {
+			names = append(names, name)
+		}
+		for name := range g {
+			if _, ok := w[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if r.ignoreFields[strings.ToLower(name)] {
+				continue
+			}
+			wv, wok := w[name]
+			gv, gok := g[name]
+			switch {
+			case !wok:
+				*diffs = append(*diffs, Diff{Field: field + "." + name, Recorded: missing, Replayed: encode(gv)})
+			case !gok:
+				*diffs = append(*diffs, Diff{Field: field + "." + name, Recorded: encode(wv), Replayed: missing})
+			default:
+				r.diffValues(field+"."+name, wv, gv, diffs)
+			}
+		}
+		return
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			break
+		}
+		if len(w) != len(g) {
+			*diffs = append(*diffs, Diff{Field: field + ".length", Recorded: fmt.Sprint(len(w)), Replayed: fmt.Sprint(len(g))})
+		}
+		for i := 0; i < len(w) && i < len(g); i++ {
+			r.diffValues(fmt.Sprintf("%s[%d]", field, i), w[i], g[i], diffs)
+		}
+		return
+	}
+
+	if wantJSON, gotJSON := encode(want), encode(got); wantJSON != gotJSON {
+		*diffs = append(*diffs, Diff{Field: field, Recorded: wantJSON, Replayed: gotJSON})
+	}
+}
+
+func headerValue(header http.Header, name string) string {
+	values := header.Values(name)
+	if len(values) == 0 {
+		return missing
+	}
+	return strings.Join(values, ", ")
+}
+
+func decodeJSON(body []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value interface{}
+	err := dec.Decode(&value)
+	return value, err
+}
+
+func encode(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// abbreviate returns the start of a body that is not JSON
+func abbreviate(body []byte) string {
+	const max = 200
+	if len(body) > max {
+		return fmt.Sprintf("%q... (%d bytes)", body[:max], len(body))
+	}
+	return fmt.Sprintf("%q", body)
+}
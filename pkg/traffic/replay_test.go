@@ -0,0 +1,152 @@
+package traffic
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplay(t *testing.T) {
+	var received *http.Request
+	var receivedBody string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, receivedBody = r, string(body)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-ID", "replayed")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":2,"name":"Jane","tags":["a"],"createdAt":"now","token":"new"}`))
+	}))
+	defer ts.Close()
+
+	replayer, err := NewReplayer(ReplayOptions{
+		Target:       ts.URL + "/api/",
+		Header:       http.Header{"Authorization": []string{"Bearer test"}},
+		IgnoreFields: []string{"createdAt"},
+	})
+	assert.Nil(t, err)
+
+	exchange := &Exchange{
+		Route: "createUser",
+		Request: Request{
+			Method: http.MethodPost,
+			URL:    "/users?notify=true",
+			Header: http.Header{
+				"Authorization":   []string{Redacted},
+				"Cookie":          []string{Redacted},
+				"Accept-Encoding": []string{"gzip"},
+				"Content-Type":    []string{"application/json"},
+			},
+			Body: Body{Data: []byte(`{"name":"Jane"}`)},
+		},
+		Response: Response{
+			Status: http.StatusCreated,
+			Header: http.Header{"Content-Type": []string{"application/json"}, "X-Request-Id": []string{"recorded"}},
+			Body:   Body{Data: []byte(`{"id":1,"name":"Jane","tags":["a","b"],"createdAt":"then","token":"[REDACTED]"}`)},
+		},
+	}
+
+	result, err := replayer.Replay(context.Background(), exchange)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.MethodPost, received.Method)
+	assert.Equal(t, "/api/users", received.URL.Path)
+	assert.Equal(t, "notify=true", received.URL.RawQuery)
+	assert.Equal(t, `{"name":"Jane"}`, receivedBody)
+	assert.Equal(t, "Bearer test", received.Header.Get("Authorization"))
+	assert.Empty(t, received.Header.Get("Cookie"))
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+
+	assert.Equal(t, http.StatusCreated, result.Response.Status)
+	assert.Equal(t, []Diff{
+		{Field: "body.id", Recorded: "1", Replayed: "2"},
+		{Field: "body.tags.length", Recorded: "2", Replayed: "1"},
+	}, result.Diffs)
+
+	t.Run("omitted body", func(t *testing.T) {
+		_, err := replayer.Replay(context.Background(), &Exchange{Request: Request{Method: http.MethodPost, URL: "/upload", Body: Body{Omitted: true}}})
+		assert.Equal(t, ErrBodyOmitted, err)
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		_, err := NewReplayer(ReplayOptions{Target: "localhost:3001"})
+		assert.EqualError(t, err, `traffic: invalid target "localhost:3001"`)
+	})
+}
+
+func TestDiff(t *testing.T) {
+	replayer, err := NewReplayer(ReplayOptions{Target: "http://localhost:3001", IgnoreHeaders: []string{"x-version"}})
+	assert.Nil(t, err)
+
+	jsonHeader := func(extra ...string) http.Header {
+		h := http.Header{"Content-Type": []string{"application/json"}}
+		for i := 0; i < len(extra); i += 2 {
+			h.Set(extra[i], extra[i+1])
+		}
+		return h
+	}
+
+	cases := []struct {
+		name     string
+		recorded Response
+		replayed Response
+		expected []Diff
+	}{
+		{
+			"equal",
+			Response{Status: 200, Header: jsonHeader("Date", "yesterday", "X-Version", "1"), Body: Body{Data: []byte(`{"a":1,"b":[true]}`)}},
+			Response{Status: 200, Header: jsonHeader("Date", "today", "X-Version", "2"), Body: Body{Data: []byte(`{ "b": [true], "a": 1 }`)}},
+			[]Diff{},
+		},
+		{
+			"status and headers",
+			Response{Status: 200, Header: jsonHeader("Cache-Control", "no-store", "Set-Cookie", Redacted)},
+			Response{Status: 500, Header: jsonHeader("Set-Cookie", "session=1", "Retry-After", "1")},
+			[]Diff{
+				{Field: "status", Recorded: "200", Replayed: "500"},
+				{Field: "header.Cache-Control", Recorded: "no-store", Replayed: "(missing)"},
+				{Field: "header.Retry-After", Recorded: "(missing)", Replayed: "1"},
+			},
+		},
+		{
+			"fields",
+			Response{Status: 200, Header: jsonHeader(), Body: Body{Data: []byte(`{"a":{"b":1.50,"c":"x"},"d":[1,{"e":null}]}`)}},
+			Response{Status: 200, Header: jsonHeader(), Body: Body{Data: []byte(`{"a":{"b":1.5,"f":"y"},"d":[1,{"e":"z"}]}`)}},
+			[]Diff{
+				{Field: "body.a.b", Recorded: "1.50", Replayed: "1.5"},
+				{Field: "body.a.c", Recorded: `"x"`, Replayed: "(missing)"},
+				{Field: "body.a.f", Recorded: "(missing)", Replayed: `"y"`},
+				{Field: "body.d[1].e", Recorded: "null", Replayed: `"z"`},
+			},
+		},
+		{
+			"types",
+			Response{Status: 200, Header: jsonHeader(), Body: Body{Data: []byte(`{"a":[1]}`)}},
+			Response{Status: 200, Header: jsonHeader(), Body: Body{Data: []byte(`{"a":{"0":1}}`)}},
+			[]Diff{{Field: "body.a", Recorded: "[1]", Replayed: `{"0":1}`}},
+		},
+		{
+			"text",
+			Response{Status: 200, Body: Body{Data: []byte("hello")}},
+			Response{Status: 200, Body: Body{Data: []byte("hello, world")}},
+			[]Diff{{Field: "body", Recorded: `"hello"`, Replayed: `"hello, world"`}},
+		},
+		{
+			"omitted",
+			Response{Status: 200, Body: Body{Omitted: true}},
+			Response{Status: 200, Body: Body{Data: []byte("large")}},
+			[]Diff{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, replayer.Diff(c.recorded, c.replayed))
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// Package traffic records HTTP exchanges to a line-delimited JSON file
+// and replays them against a server, diffing the responses with the
+// recorded ones e.g. to regression-test a refactor with production traffic
+package traffic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineBytes is the largest exchange read by a Reader
+const maxLineBytes int = 64 << 20
+
+// Exchange is a request and the response served for it
+//
+// Route - name of the route that served the request, if any
+// Duration - time taken to serve the request
+type Exchange struct {
+	Time     time.Time `json:"time"`
+	Route    string    `json:"route,omitempty"`
+	Duration Duration  `json:"duration"`
+	Request  Request   `json:"request"`
+	Response Response  `json:"response"`
+}
+
+// Request is a recorded request
+//
+// URL - path and query of the request e.g. /users?limit=10
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body"`
+}
+
+// Response is a recorded response
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body"`
+}
+
+// Body is a recorded body. Text bodies are kept as is and binary
+// ones base64 encoded. Bodies larger than the recording limit are
+// omitted, as they could not be redacted, and are not replayed
+type Body struct {
+	Data    []byte
+	Omitted bool
+}
+
+type jsonBody struct {
+	Text    string `json:"text,omitempty"`
+	Base64  string `json:"base64,omitempty"`
+	Omitted bool   `json:"omitted,omitempty"`
+}
+
+// MarshalJSON writes text bodies as strings and binary ones in base64
+func (b Body) MarshalJSON() ([]byte, error) {
+	jb := jsonBody{Omitted: b.Omitted}
+	if utf8.Valid(b.Data) {
+		jb.Text = string(b.Data)
+	} else {
+		jb.Base64 = base64.StdEncoding.EncodeToString(b.Data)
+	}
+
+	// bodies are kept readable e.g. HTML is not escaped
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(jb); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// UnmarshalJSON reads bodies written by MarshalJSON
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var jb jsonBody
+	if err := json.Unmarshal(data, &jb); err != nil {
+		return err
+	}
+
+	b.Omitted = jb.Omitted
+	b.Data = nil
+	if jb.Text != "" {
+		b.Data = []byte(jb.Text)
+	}
+	if jb.Base64 != "" {
+		decoded, err := base64.StdEncoding.DecodeString(jb.Base64)
+		if err != nil {
+			return err
+		}
+		b.Data = decoded
+	}
+	return nil
+}
+
+// Duration is written as a string e.g. "1.5ms"
+type Duration time.Duration
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON reads durations written as strings
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Writer writes exchanges as lines of JSON.
+// Safe for concurrent use
+type Writer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewWriter returns a new instance of Writer writing to w
+func NewWriter(w io.Writer) *Writer {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &Writer{enc: enc}
+}
+
+// Write writes the exchange on a line
+func (w *Writer) Write(e *Exchange) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enc.Encode(e)
+}
+
+// Reader reads the exchanges written by a Writer
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewReader returns a new instance of Reader reading from r
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	return &Reader{scanner: scanner}
+}
+
+// Next returns the next exchange. Returns io.EOF once all
+// the exchanges are read. Blank lines are skipped
+func (r *Reader) Next() (*Exchange, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var e Exchange
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, &LineError{Line: r.line, Err: err}
+		}
+		return &e, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// LineError is returned by Reader for lines that are not exchanges
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the decoding error
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
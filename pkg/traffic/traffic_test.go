@@ -0,0 +1,76 @@
+package traffic
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterReader(t *testing.T) {
+	exchanges := []*Exchange{
+		{
+			Time:     time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+			Route:    "createUser",
+			Duration: Duration(1500 * time.Microsecond),
+			Request: Request{
+				Method: http.MethodPost,
+				URL:    "/users?notify=true",
+				Header: http.Header{"Content-Type": []string{"application/json"}},
+				Body:   Body{Data: []byte(`{"name":"<Jane>"}`)},
+			},
+			Response: Response{
+				Status: http.StatusCreated,
+				Header: http.Header{"Content-Type": []string{"application/json"}},
+				Body:   Body{Data: []byte(`{"id":1}`)},
+			},
+		},
+		{
+			Time:     time.Date(2021, 6, 1, 12, 0, 1, 0, time.UTC),
+			Request:  Request{Method: http.MethodPut, URL: "/avatar", Body: Body{Omitted: true}},
+			Response: Response{Status: http.StatusOK, Body: Body{Data: []byte{0xff, 0xd8, 0xff}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, e := range exchanges {
+		assert.Nil(t, w.Write(e))
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"duration":"1.5ms"`)
+	assert.Contains(t, lines[0], `"body":{"text":"{\"name\":\"<Jane>\"}"}`)
+	assert.Contains(t, lines[1], `"body":{"omitted":true}`)
+	assert.Contains(t, lines[1], `"body":{"base64":"/9j/"}`)
+
+	// blank lines are skipped
+	r := NewReader(strings.NewReader(lines[0] + "\n\n" + lines[1] + "\n"))
+	for _, expected := range exchanges {
+		e, err := r.Next()
+		assert.Nil(t, err)
+		assert.Equal(t, expected.Time.Unix(), e.Time.Unix())
+		expected.Time = e.Time
+		assert.Equal(t, expected, e)
+	}
+	_, err := r.Next()
+	assert.Equal(t, io.EOF, err)
+
+	t.Run("invalid line", func(t *testing.T) {
+		r := NewReader(strings.NewReader(lines[0] + "\nnot json\n"))
+
+		_, err := r.Next()
+		assert.Nil(t, err)
+
+		_, err = r.Next()
+		var lineErr *LineError
+		assert.True(t, errors.As(err, &lineErr))
+		assert.Equal(t, 2, lineErr.Line)
+	})
+}
@@ -0,0 +1,10 @@
+// Package static contains the static files
+// served by the boilerplate, embedded in the binary
+package static
+
+import "embed"
+
+// Files holds the HTML page and the stylesheets
+//
+//go:embed index.html css
+var Files embed.FS